@@ -0,0 +1,39 @@
+package apperrors
+
+import (
+	"net/http"
+)
+
+// TooManyRequestsError is returned when a short term limit, e.g. rate limit or daily quota, is exceeded
+type TooManyRequestsError struct {
+	baseError
+}
+
+func NewTooManyRequestsError(resource string, err error) TooManyRequestsError {
+	return TooManyRequestsError{baseError{
+		Resource: resource,
+		Cause:    err,
+	}}
+}
+
+func (e TooManyRequestsError) HTTPCode() int { return http.StatusTooManyRequests }
+func (e TooManyRequestsError) HTTPResponse() map[string]any {
+	return e.httpResponse("TOO_MANY_REQUESTS")
+}
+
+// PaymentRequiredError is returned when a paid quota, e.g. monthly budget, is exhausted
+type PaymentRequiredError struct {
+	baseError
+}
+
+func NewPaymentRequiredError(resource string, err error) PaymentRequiredError {
+	return PaymentRequiredError{baseError{
+		Resource: resource,
+		Cause:    err,
+	}}
+}
+
+func (e PaymentRequiredError) HTTPCode() int { return http.StatusPaymentRequired }
+func (e PaymentRequiredError) HTTPResponse() map[string]any {
+	return e.httpResponse("PAYMENT_REQUIRED")
+}
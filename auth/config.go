@@ -1,6 +1,9 @@
 package auth
 
 import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
 	"strings"
 	"time"
 
@@ -10,13 +13,27 @@ import (
 
 // Config holds the configuration for auth service
 type Config struct {
-	SecretKey                   string          `validate:"required" log:"-"`
-	AccessTokenValiditySeconds  int             `validate:"required"`
-	RefreshTokenValiditySeconds int             `validate:"required"`
-	OTP                         otpConfig       `validate:"required"`
-	Twilio                      twilio.Config   `validate:"required"`
-	Fast2SMS                    fast2sms.Config `validate:"required"`
-	OAuthGoogle                 OAuthConfig
+	SecretKey                   string    `validate:"required" log:"-"`
+	AccessTokenValiditySeconds  int       `validate:"required"`
+	RefreshTokenValiditySeconds int       `validate:"required"`
+	OTP                         otpConfig `validate:"required"`
+	// sms providers, oauth and magic link are optional and only validated when configured
+	Twilio      twilio.Config   `validate:"omitempty"`
+	Fast2SMS    fast2sms.Config `validate:"omitempty"`
+	OAuthGoogle OAuthConfig     `validate:"omitempty"`
+	MagicLink   MagicLinkConfig `validate:"omitempty"`
+}
+
+// OTPConfig returns OTP with its SecretKey, if empty, derived from SecretKey so that otps
+// hashed by one instance verify on the others sharing the cache.
+func (c Config) OTPConfig() otpConfig {
+	otp := c.OTP
+	if otp.SecretKey == "" && c.SecretKey != "" {
+		mac := hmac.New(sha256.New, []byte(c.SecretKey))
+		mac.Write([]byte("auth:otp"))
+		otp.SecretKey = hex.EncodeToString(mac.Sum(nil))
+	}
+	return otp
 }
 
 func (c Config) accessTokenValidity() time.Duration {
@@ -27,15 +44,23 @@ func (c Config) refreshTokenValidity() time.Duration {
 	return time.Duration(c.RefreshTokenValiditySeconds) * time.Second
 }
 
+const defaultOTPMaxVerifyAttempts = 5
+
 // otpConfig holds the configuration for OTP service
 type otpConfig struct {
 	ValiditySeconds   int `validate:"required"`
-	MaxAttempts       int `validate:"required"`
+	MaxAttempts       int `validate:"required"` // max otps sent per target within validity
 	RetryAfterSeconds int `validate:"required"`
 	Length            int `validate:"required"`
-	Organisation      string
-	TestPhone         string
-	TestEmail         string
+	// MaxVerifyAttempts is the max incorrect otps per target before lockout, defaults to 5
+	MaxVerifyAttempts int
+	// LockoutSeconds is how long a target stays locked out, defaults to ValiditySeconds
+	LockoutSeconds int
+	// SecretKey is used to hash otps at rest, see Config.OTPConfig to default it
+	SecretKey    string `log:"-"`
+	Organisation string
+	TestPhone    string
+	TestEmail    string
 }
 
 func (c otpConfig) validity() time.Duration {
@@ -45,10 +70,43 @@ func (c otpConfig) retryAfter() time.Duration {
 	return time.Duration(c.RetryAfterSeconds) * time.Second
 }
 
+func (c otpConfig) maxVerifyAttempts() int {
+	if c.MaxVerifyAttempts <= 0 {
+		return defaultOTPMaxVerifyAttempts
+	}
+	return c.MaxVerifyAttempts
+}
+
+func (c otpConfig) lockout() time.Duration {
+	if c.LockoutSeconds <= 0 {
+		return c.validity()
+	}
+	return time.Duration(c.LockoutSeconds) * time.Second
+}
+
 func (c otpConfig) brandName() string {
 	return strings.TrimSpace(c.Organisation)
 }
 
+// MagicLinkConfig holds the configuration for magic link login over email
+type MagicLinkConfig struct {
+	// CallbackURL is the app url that receives the link, token is added as "token" query param
+	CallbackURL       string `validate:"required"`
+	SecretKey         string `validate:"required" log:"-"`
+	ValiditySeconds   int    `validate:"required"`
+	MaxAttempts       int    `validate:"required"`
+	RetryAfterSeconds int    `validate:"required"`
+	Organisation      string
+}
+
+func (c MagicLinkConfig) validity() time.Duration {
+	return time.Duration(c.ValiditySeconds) * time.Second
+}
+
+func (c MagicLinkConfig) retryAfter() time.Duration {
+	return time.Duration(c.RetryAfterSeconds) * time.Second
+}
+
 // OAuthConfig holds the configuration for OAuth providers (Google, Twitter, LinkedIn, etc.)
 type OAuthConfig struct {
 	ClientID     string `validate:"required"`
@@ -2,11 +2,13 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 
 	"github.com/gin-gonic/gin"
 	"github.com/krsoninikhil/go-rest-kit/apperrors"
+	"github.com/krsoninikhil/go-rest-kit/notifications"
 )
 
 // dependencies
@@ -24,6 +26,10 @@ type (
 	LocalSvc interface {
 		GetCountryInfo(ctx context.Context, locale string) (*CountryInfoSource, error)
 	}
+	MagicLinkSvcI interface {
+		Send(ctx context.Context, u SigupInfo) (*OTPStatus, error)
+		Verify(ctx context.Context, token string) (*SigupInfo, error)
+	}
 )
 
 type Controller struct {
@@ -31,6 +37,7 @@ type Controller struct {
 	otpSvc         OTPSvcI
 	oauthProviders map[string]OAuthProvider // Map of provider name to provider implementation
 	localeSvc      LocalSvc
+	magicLinkSvc   MagicLinkSvcI
 }
 
 func NewController(authSvc AuthService, otpSvc OTPSvcI, cacheClient cacheClient) *Controller {
@@ -56,13 +63,19 @@ func (c *Controller) WithOAuthProviders(providers ...OAuthProvider) *Controller
 	return c
 }
 
+// WithMagicLinkSvc enables magic link login on the controller
+func (c *Controller) WithMagicLinkSvc(svc MagicLinkSvcI) *Controller {
+	c.magicLinkSvc = svc
+	return c
+}
+
 func (a *Controller) SendOTP(c *gin.Context, r SendOTPRequest) (*SendOTPResponse, error) {
 	log.Printf("auth: sending otp request=%+v", r)
 	target, channel, err := r.resolveOTPInputs()
 	if err != nil {
 		return nil, err
 	}
-	res, err := a.otpSvc.Send(c, target, channel)
+	res, err := a.otpSvc.Send(notifications.WithLocale(c, r.Locale), target, channel)
 	if err != nil {
 		return nil, err
 	}
@@ -97,6 +110,44 @@ func (a *Controller) VerifyOTP(c *gin.Context, r VerifyOTPRequest) (*VerifyOTPRe
 	}, nil
 }
 
+func (a *Controller) SendMagicLink(c *gin.Context, r SendMagicLinkRequest) (*SendOTPResponse, error) {
+	if a.magicLinkSvc == nil {
+		return nil, apperrors.NewServerError(errors.New("magic link not configured"))
+	}
+	res, err := a.magicLinkSvc.Send(c, r.toSigupInfo())
+	if err != nil {
+		return nil, err
+	}
+	log.Printf("auth: magic link sent successfully")
+
+	return &SendOTPResponse{
+		RetryAfter:  res.RetryAfter,
+		AttemptLeft: res.AttemptLeft,
+	}, nil
+}
+
+func (a *Controller) VerifyMagicLink(c *gin.Context, r VerifyMagicLinkRequest) (*VerifyOTPResponse, error) {
+	if a.magicLinkSvc == nil {
+		return nil, apperrors.NewServerError(errors.New("magic link not configured"))
+	}
+	signupInfo, err := a.magicLinkSvc.Verify(c, r.Token)
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := a.authSvc.UpsertUser(c, *signupInfo)
+	if err != nil {
+		return nil, err
+	}
+
+	return &VerifyOTPResponse{
+		AccessToken:      res.AccessToken,
+		RefreshToken:     res.RefreshToken,
+		ExpiresIn:        res.ExpiresIn,
+		RefreshExpiresIn: res.RefreshExpiresIn,
+	}, nil
+}
+
 func (a *Controller) OAuthAuth(c *gin.Context, r OAuthAuthRequest) (*OAuthAuthResponse, error) {
 	// Get the appropriate OAuth provider
 	provider, exists := a.oauthProviders[r.Provider]
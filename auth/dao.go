@@ -6,6 +6,7 @@ import (
 	"fmt"
 
 	"github.com/krsoninikhil/go-rest-kit/apperrors"
+	"github.com/krsoninikhil/go-rest-kit/encrypt"
 	"github.com/krsoninikhil/go-rest-kit/sqldb"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
@@ -19,6 +20,13 @@ type UserModel interface {
 	ResourceName() string
 }
 
+// EmailIndexer is implemented by user models storing the email encrypted, e.g. as
+// encrypt.String, with its encrypt.BlindIndex in a unique column named by EmailIndexColumn.
+// The model sets the index, e.g. in a BeforeSave hook, and email lookups and upserts use it.
+type EmailIndexer interface {
+	EmailIndexColumn() string
+}
+
 type userDao[U UserModel] struct {
 	*sqldb.PGDB
 }
@@ -68,7 +76,11 @@ func (d *userDao[U]) GetByPhone(ctx context.Context, phone string) (int, error)
 
 func (d *userDao[U]) GetByEmail(ctx context.Context, email string) (int, error) {
 	var user U
-	err := d.PGDB.DB(ctx).Where("email = ?", email).First(&user).Error
+	column, value, err := emailLookup(user, email)
+	if err != nil {
+		return 0, apperrors.NewServerError(err)
+	}
+	err = d.PGDB.DB(ctx).Where(column+" = ?", value).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
 			return 0, apperrors.NewNotFoundError(user.ResourceName())
@@ -81,10 +93,14 @@ func (d *userDao[U]) GetByEmail(ctx context.Context, email string) (int, error)
 func (d *userDao[U]) UpsertByEmail(ctx context.Context, oauthInfo OAuthUserInfo) (int, error) {
 	var user U
 	user = user.SetOAuthInfo(oauthInfo).(U)
-	err := d.PGDB.DB(ctx).Clauses(
+	column, _, err := emailLookup(user, oauthInfo.Email)
+	if err != nil {
+		return 0, apperrors.NewServerError(err)
+	}
+	err = d.PGDB.DB(ctx).Clauses(
 		clause.Returning{Columns: []clause.Column{{Name: "id"}}},
 		clause.OnConflict{
-			Columns:   []clause.Column{{Name: "email"}},
+			Columns:   []clause.Column{{Name: column}},
 			DoNothing: true,
 		},
 	).Create(&user).Error
@@ -99,6 +115,16 @@ func (d *userDao[U]) UpsertByEmail(ctx context.Context, oauthInfo OAuthUserInfo)
 	return user.PK(), nil
 }
 
+// emailLookup returns the column and value to find a user by email
+func emailLookup(user UserModel, email string) (string, string, error) {
+	indexer, ok := user.(EmailIndexer)
+	if !ok {
+		return "email", email, nil
+	}
+	index, err := encrypt.BlindIndex(email)
+	return indexer.EmailIndexColumn(), index, err
+}
+
 func (d *userDao[U]) GetByUsername(ctx context.Context, username string) (int, error) {
 	var user U
 	err := d.PGDB.DB(ctx).Where("username = ?", username).First(&user).Error
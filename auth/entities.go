@@ -46,6 +46,16 @@ type (
 		RefreshExpiresIn int64  `json:"refresh_expires_in"`
 	}
 
+	SendMagicLinkRequest struct {
+		Email    string `json:"email" binding:"required,email"`
+		DialCode string `json:"dial_code"`
+		Country  string `json:"country"`
+		Locale   string `json:"locale"`
+	}
+	VerifyMagicLinkRequest struct {
+		Token string `json:"token" binding:"required"`
+	}
+
 	RefreshTokenRequest struct {
 		RefreshToken string `json:"refresh_token" binding:"required"`
 	}
@@ -86,6 +96,9 @@ type (
 	OTPStatus struct {
 		RetryAfter  int
 		AttemptLeft int
+		// MessageID is the provider's id of the sent message for correlating delivery events,
+		// empty for test targets and providers that don't return one
+		MessageID string `json:"-"`
 	}
 	Token struct {
 		AccessToken      string
@@ -137,6 +150,15 @@ func (v *VerifyOTPRequest) toSigupInfo() SigupInfo {
 	}
 }
 
+func (r SendMagicLinkRequest) toSigupInfo() SigupInfo {
+	return SigupInfo{
+		Email:    strings.TrimSpace(r.Email),
+		DialCode: r.DialCode,
+		Country:  r.Country,
+		Locale:   r.Locale,
+	}
+}
+
 func (r SendOTPRequest) OTPDestination() string {
 	target := strings.TrimSpace(r.Target)
 	if target != "" {
@@ -0,0 +1,216 @@
+package auth
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+	"github.com/krsoninikhil/go-rest-kit/cache"
+	"github.com/krsoninikhil/go-rest-kit/notifications"
+	"github.com/pkg/errors"
+)
+
+const magicLinkNonceSize = 32
+
+var errInvalidMagicLink = apperrors.NewInvalidParamsError("token", errors.New("invalid or expired link"))
+
+// magicLinkAttempt is stored per email to rate limit sends, TokenHash refers to
+// the last link sent so that it can be revoked when a new one is sent.
+type magicLinkAttempt struct {
+	sendAttempt
+	TokenHash string
+}
+
+type magicLinkMetaData struct {
+	SignupInfo SigupInfo
+	ExpiresAt  time.Time
+}
+
+type magicLinkSvc struct {
+	config        MagicLinkConfig
+	cache         cacheClient
+	emailProvider emailProvider
+	renderer      *notifications.Renderer
+}
+
+func NewMagicLinkSvc(config MagicLinkConfig, emailProvider emailProvider, cache cacheClient) magicLinkSvc {
+	return magicLinkSvc{
+		config:        config,
+		cache:         cache,
+		emailProvider: emailProvider,
+		renderer:      notifications.DefaultRenderer(),
+	}
+}
+
+// WithRenderer overrides the templates used for magic link emails (notifications.TemplateMagicLink)
+func (s magicLinkSvc) WithRenderer(renderer *notifications.Renderer) magicLinkSvc {
+	s.renderer = renderer
+	return s
+}
+
+// Send emails a signed single use login link to u.Email. Sending a new link revokes the previous one.
+func (s magicLinkSvc) Send(ctx context.Context, u SigupInfo) (*OTPStatus, error) {
+	if s.emailProvider == nil {
+		return nil, apperrors.NewServerError(errors.New("email provider not configured"))
+	}
+
+	attempt := 1
+	attemptKey := buildMagicLinkAttemptKey(u.Email)
+	lastMeta, err := s.cache.Get(attemptKey)
+	if err != nil {
+		if !errors.Is(err, cache.ErrKeyNotFound) {
+			return nil, errors.Wrap(err, "unable to get last magic link")
+		}
+	} else {
+		last, ok := lastMeta.(magicLinkAttempt)
+		if !ok {
+			return nil, apperrors.NewServerError(errors.New("invalid last magic link"))
+		}
+		attempt, err = last.next("magic_link", s.config.MaxAttempts, s.config.retryAfter())
+		if err != nil {
+			return nil, err
+		}
+		if err := s.cache.Delete(buildMagicLinkTokenKey(last.TokenHash)); err != nil {
+			return nil, errors.Wrap(err, "unable to revoke last magic link")
+		}
+	}
+
+	expiresAt := time.Now().Add(s.config.validity())
+	token, err := s.newToken(expiresAt)
+	if err != nil {
+		return nil, apperrors.NewServerError(err)
+	}
+	link, err := s.link(token)
+	if err != nil {
+		return nil, apperrors.NewServerError(err)
+	}
+
+	tokenHash := hashMagicLinkToken(token)
+	meta := magicLinkMetaData{SignupInfo: u, ExpiresAt: expiresAt}
+	if err := s.cache.Set(buildMagicLinkTokenKey(tokenHash), meta, s.config.validity()); err != nil {
+		return nil, errors.Wrap(err, "unable to set magic link")
+	}
+
+	// the attempt is recorded before sending so failed or slow sends count towards the limit
+	lastAttempt := magicLinkAttempt{
+		sendAttempt: sendAttempt{Attempt: attempt, SentAt: time.Now()},
+		TokenHash:   tokenHash,
+	}
+	if err := s.cache.Set(attemptKey, lastAttempt, s.config.validity()); err != nil {
+		return nil, errors.Wrap(err, "unable to set magic link attempt")
+	}
+
+	data := notifications.MagicLinkData{
+		Link:             link,
+		Brand:            strings.TrimSpace(s.config.Organisation),
+		ExpiresInMinutes: int(s.config.validity().Minutes()),
+	}
+	notifier := notifications.NewNotifier(s.renderer).WithEmailProvider(s.emailProvider)
+	messageID, err := notifier.SendEmail(notifications.WithLocale(ctx, u.Locale), u.Email, notifications.TemplateMagicLink, data)
+	if err != nil {
+		return nil, errors.Wrap(err, "unable to send magic link")
+	}
+
+	return &OTPStatus{
+		RetryAfter:  s.config.RetryAfterSeconds,
+		AttemptLeft: s.config.MaxAttempts - attempt,
+		MessageID:   messageID,
+	}, nil
+}
+
+// Verify validates and consumes the token, returning the signup info it was issued for.
+func (s magicLinkSvc) Verify(ctx context.Context, token string) (*SigupInfo, error) {
+	expiresAt, ok := s.parseToken(token)
+	if !ok || time.Now().After(expiresAt) {
+		return nil, errInvalidMagicLink
+	}
+
+	tokenKey := buildMagicLinkTokenKey(hashMagicLinkToken(token))
+	val, err := s.cache.Get(tokenKey)
+	if err != nil {
+		if errors.Is(err, cache.ErrKeyNotFound) {
+			return nil, errInvalidMagicLink
+		}
+		return nil, errors.Wrap(err, "unable to get magic link")
+	}
+	meta, ok := val.(magicLinkMetaData)
+	if !ok {
+		return nil, apperrors.NewServerError(errors.New("invalid magic link"))
+	}
+	if err := s.cache.Delete(tokenKey); err != nil {
+		return nil, errors.Wrap(err, "unable to consume magic link")
+	}
+	if time.Now().After(meta.ExpiresAt) {
+		return nil, errInvalidMagicLink
+	}
+	return &meta.SignupInfo, nil
+}
+
+// newToken returns base64url(nonce || expiry) + "." + base64url(hmac) so that
+// forged or expired tokens are rejected before looking up the cache.
+func (s magicLinkSvc) newToken(expiresAt time.Time) (string, error) {
+	payload := make([]byte, magicLinkNonceSize+8)
+	if _, err := rand.Read(payload[:magicLinkNonceSize]); err != nil {
+		return "", errors.Wrap(err, "unable to generate magic link token")
+	}
+	binary.BigEndian.PutUint64(payload[magicLinkNonceSize:], uint64(expiresAt.Unix()))
+	return encodeToken(payload) + "." + encodeToken(s.sign(payload)), nil
+}
+
+func (s magicLinkSvc) parseToken(token string) (time.Time, bool) {
+	encodedPayload, encodedSig, found := strings.Cut(token, ".")
+	if !found {
+		return time.Time{}, false
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil || len(payload) != magicLinkNonceSize+8 {
+		return time.Time{}, false
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !hmac.Equal(sig, s.sign(payload)) {
+		return time.Time{}, false
+	}
+	return time.Unix(int64(binary.BigEndian.Uint64(payload[magicLinkNonceSize:])), 0), true
+}
+
+func (s magicLinkSvc) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, []byte(s.config.SecretKey))
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (s magicLinkSvc) link(token string) (string, error) {
+	callback, err := url.Parse(s.config.CallbackURL)
+	if err != nil {
+		return "", errors.Wrap(err, "invalid magic link callback url")
+	}
+	query := callback.Query()
+	query.Set("token", token)
+	callback.RawQuery = query.Encode()
+	return callback.String(), nil
+}
+
+func encodeToken(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func hashMagicLinkToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func buildMagicLinkAttemptKey(email string) string {
+	return "magic_link:" + strings.ToLower(strings.TrimSpace(email))
+}
+
+func buildMagicLinkTokenKey(tokenHash string) string {
+	return "magic_link_token:" + tokenHash
+}
@@ -0,0 +1,117 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"regexp"
+	"testing"
+
+	"github.com/krsoninikhil/go-rest-kit/cache"
+)
+
+func newTestMagicLinkSvc(email *fakeEmailProvider) magicLinkSvc {
+	return NewMagicLinkSvc(MagicLinkConfig{
+		CallbackURL:       "https://app.example.com/login?src=email",
+		SecretKey:         "magic-secret",
+		ValiditySeconds:   600,
+		MaxAttempts:       3,
+		RetryAfterSeconds: 0,
+		Organisation:      "FaithLabs",
+	}, email, cache.NewInMemory())
+}
+
+func tokenFromEmail(t *testing.T, body string) string {
+	t.Helper()
+	link := regexp.MustCompile(`https://\S+`).FindString(body)
+	u, err := url.Parse(link)
+	if err != nil {
+		t.Fatalf("invalid link in email body %q: %v", body, err)
+	}
+	if u.Query().Get("src") != "email" {
+		t.Fatalf("expected callback query params to be preserved: %s", link)
+	}
+	return u.Query().Get("token")
+}
+
+func TestMagicLinkSvc_SendAndVerify(t *testing.T) {
+	email := &fakeEmailProvider{}
+	svc := newTestMagicLinkSvc(email)
+
+	res, err := svc.Send(context.Background(), SigupInfo{Email: "reader@example.com", Locale: "en"})
+	if err != nil {
+		t.Fatalf("send magic link failed: %v", err)
+	}
+	if res.AttemptLeft != 2 {
+		t.Fatalf("unexpected attempts left: %d", res.AttemptLeft)
+	}
+	if email.lastTo != "reader@example.com" || email.lastSubject != "Your FaithLabs sign-in link" {
+		t.Fatalf("unexpected email: to=%s subject=%s", email.lastTo, email.lastSubject)
+	}
+
+	token := tokenFromEmail(t, email.lastBody)
+	info, err := svc.Verify(context.Background(), token)
+	if err != nil {
+		t.Fatalf("verify magic link failed: %v", err)
+	}
+	if info.Email != "reader@example.com" || info.Locale != "en" {
+		t.Fatalf("unexpected signup info: %+v", info)
+	}
+	if _, err := svc.Verify(context.Background(), token); err == nil {
+		t.Fatalf("expected magic link to be single use")
+	}
+}
+
+func TestMagicLinkSvc_RejectsTamperedToken(t *testing.T) {
+	email := &fakeEmailProvider{}
+	svc := newTestMagicLinkSvc(email)
+
+	if _, err := svc.Send(context.Background(), SigupInfo{Email: "reader@example.com"}); err != nil {
+		t.Fatalf("send magic link failed: %v", err)
+	}
+	token := tokenFromEmail(t, email.lastBody)
+	tampered := "A" + token[1:]
+	if tampered == token {
+		tampered = "B" + token[1:]
+	}
+	if _, err := svc.Verify(context.Background(), tampered); err == nil {
+		t.Fatalf("expected tampered token to be rejected")
+	}
+}
+
+func TestMagicLinkSvc_ResendRevokesPreviousLink(t *testing.T) {
+	email := &fakeEmailProvider{}
+	svc := newTestMagicLinkSvc(email)
+
+	if _, err := svc.Send(context.Background(), SigupInfo{Email: "reader@example.com"}); err != nil {
+		t.Fatalf("send magic link failed: %v", err)
+	}
+	first := tokenFromEmail(t, email.lastBody)
+	if _, err := svc.Send(context.Background(), SigupInfo{Email: "reader@example.com"}); err != nil {
+		t.Fatalf("resend magic link failed: %v", err)
+	}
+	second := tokenFromEmail(t, email.lastBody)
+
+	if _, err := svc.Verify(context.Background(), first); err == nil {
+		t.Fatalf("expected previous link to be revoked")
+	}
+	if _, err := svc.Verify(context.Background(), second); err != nil {
+		t.Fatalf("verify latest magic link failed: %v", err)
+	}
+}
+
+func TestMagicLinkSvc_FailedSendsCountTowardsLimit(t *testing.T) {
+	email := &fakeEmailProvider{err: errors.New("provider down")}
+	svc := newTestMagicLinkSvc(email)
+	u := SigupInfo{Email: "reader@example.com", Locale: "en"}
+
+	for i := 0; i < 3; i++ {
+		if _, err := svc.Send(context.Background(), u); err == nil {
+			t.Fatalf("expected send %d to fail", i+1)
+		}
+	}
+	email.err = nil
+	if _, err := svc.Send(context.Background(), u); err == nil {
+		t.Fatalf("expected failed sends to exhaust the attempts")
+	}
+}
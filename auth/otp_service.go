@@ -2,13 +2,20 @@ package auth
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"fmt"
-	"math/rand"
+	"hash/fnv"
+	"math/big"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/krsoninikhil/go-rest-kit/apperrors"
 	"github.com/krsoninikhil/go-rest-kit/cache"
+	"github.com/krsoninikhil/go-rest-kit/notifications"
 	"github.com/pkg/errors"
 )
 
@@ -23,35 +30,75 @@ type (
 	cacheClient interface {
 		Set(key string, value any, ttl time.Duration) error
 		Get(key string) (any, error)
+		Delete(key string) error
 	}
 )
 
 const (
-	OTPChannelSMS         = "sms"
-	OTPChannelEmail       = "email"
-	otpExpiryHintTemplate = "Your %s OTP code is %s. It expires shortly."
-	otpSubjectTemplate    = "Your %s verification code"
+	OTPChannelSMS   = "sms"
+	OTPChannelEmail = "email"
 )
 
-type otpMetaData struct {
-	OTP     string
+// sendAttempt tracks messages sent to a target for rate limiting
+type sendAttempt struct {
 	Attempt int
 	SentAt  time.Time
 }
 
+// otpMetaData is stored in cache per target. OTPHash is an HMAC of the otp so
+// that a leaked cache entry doesn't reveal the code.
+type otpMetaData struct {
+	sendAttempt
+	OTPHash        string
+	VerifyAttempts int
+}
+
 type otpSvc struct {
 	config        otpConfig
 	cache         cacheClient
 	smsProvider   smsProvider
 	emailProvider emailProvider
+	renderer      *notifications.Renderer
+	hashKey       []byte
+	locks         *targetLocks
+}
+
+// targetLocks serializes verifies of a target within the process, the mutexes are striped by
+// target so memory use is bounded.
+type targetLocks [64]sync.Mutex
+
+func (l *targetLocks) lock(key string) (unlock func()) {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	m := &l[h.Sum32()%uint32(len(l))]
+	m.Lock()
+	return m.Unlock
 }
 
-func NewOTPSvc(config otpConfig, smsProvider smsProvider, cache cacheClient) otpSvc {
+var errOTPSecretKey = errors.New("auth: otp secret key not configured, pass Config.OTPConfig()")
+
+// NewOTPSvc creates the otp service, it fails if config.SecretKey used for hashing otps is
+// empty. Pass Config.OTPConfig() to default it to one derived from the auth secret key.
+func NewOTPSvc(config otpConfig, smsProvider smsProvider, cache cacheClient) (otpSvc, error) {
+	hashKey := []byte(config.SecretKey)
+	if len(hashKey) == 0 {
+		return otpSvc{}, errOTPSecretKey
+	}
 	return otpSvc{
 		config:      config,
 		cache:       cache,
 		smsProvider: smsProvider,
-	}
+		renderer:    notifications.DefaultRenderer(),
+		hashKey:     hashKey,
+		locks:       &targetLocks{},
+	}, nil
+}
+
+// WithRenderer overrides the templates used for otp messages (notifications.TemplateOTP).
+// Locale is read from ctx, see notifications.WithLocale.
+func (s otpSvc) WithRenderer(renderer *notifications.Renderer) otpSvc {
+	s.renderer = renderer
+	return s
 }
 
 func (s otpSvc) WithEmailProvider(provider emailProvider) otpSvc {
@@ -60,7 +107,14 @@ func (s otpSvc) WithEmailProvider(provider emailProvider) otpSvc {
 }
 
 func (s otpSvc) Send(ctx context.Context, target, channel string) (*OTPStatus, error) {
-	attempt := 1
+	if channel == "" {
+		channel = OTPChannelSMS
+	}
+	if err := s.checkLockout(channel, target); err != nil {
+		return nil, err
+	}
+
+	attempt, verifyAttempts := 1, 0
 	cacheKey := buildOTPKey(channel, target)
 	lastOTPMeta, err := s.cache.Get(cacheKey)
 	if err != nil {
@@ -73,29 +127,31 @@ func (s otpSvc) Send(ctx context.Context, target, channel string) (*OTPStatus, e
 			return nil, apperrors.NewServerError(errors.New("invalid last otp"))
 		}
 
-		if lastOTP.Attempt >= s.config.MaxAttempts {
-			return nil, apperrors.NewInvalidParamsError("otp", errors.New("max attempt reached"))
+		attempt, err = lastOTP.next("otp", s.config.MaxAttempts, s.config.retryAfter())
+		if err != nil {
+			return nil, err
 		}
-
-		if time.Since(lastOTP.SentAt) < s.config.retryAfter() {
-			return nil, apperrors.NewInvalidParamsError("otp", errors.New("retry too soon"))
-		}
-		attempt = lastOTP.Attempt + 1
+		// resending must not reset the incorrect guesses made so far
+		verifyAttempts = lastOTP.VerifyAttempts
 	}
 
-	otp := generateOTP(s.config.Length)
+	otp, err := generateOTP(s.config.Length)
+	if err != nil {
+		return nil, apperrors.NewServerError(err)
+	}
+	var messageID string
 	if channel == OTPChannelSMS && s.config.TestPhone == target {
 		otp = testOTP
 	} else if channel == OTPChannelEmail && s.config.TestEmail != "" && strings.EqualFold(s.config.TestEmail, target) {
 		otp = testOTP
-	} else if err := s.sendOTPByChannel(channel, target, otp); err != nil {
+	} else if messageID, err = s.sendOTPByChannel(ctx, channel, target, otp); err != nil {
 		return nil, err
 	}
 
 	otpMeta := otpMetaData{
-		OTP:     otp,
-		Attempt: attempt,
-		SentAt:  time.Now(),
+		sendAttempt:    sendAttempt{Attempt: attempt, SentAt: time.Now()},
+		OTPHash:        s.hashOTP(channel, target, otp),
+		VerifyAttempts: verifyAttempts,
 	}
 	if err := s.cache.Set(cacheKey, otpMeta, s.config.validity()); err != nil {
 		return nil, errors.Wrap(err, "unable to set otp")
@@ -104,11 +160,26 @@ func (s otpSvc) Send(ctx context.Context, target, channel string) (*OTPStatus, e
 	return &OTPStatus{
 		RetryAfter:  s.config.RetryAfterSeconds,
 		AttemptLeft: s.config.MaxAttempts - otpMeta.Attempt,
+		MessageID:   messageID,
 	}, nil
 }
 
+// Verify checks the otp for target. Each incorrect otp counts towards MaxVerifyAttempts,
+// after which the target is locked out. A correct otp is consumed and can't be reused.
+// Verifies of a target are serialized within the process, the cache has no atomic updates
+// so instances sharing it can still race and let a few extra guesses or a reuse through.
 func (s otpSvc) Verify(ctx context.Context, target, otp, channel string) error {
-	lastOTPMeta, err := s.cache.Get(buildOTPKey(channel, target))
+	if channel == "" {
+		channel = OTPChannelSMS
+	}
+	if err := s.checkLockout(channel, target); err != nil {
+		return err
+	}
+
+	cacheKey := buildOTPKey(channel, target)
+	// counting attempts and consuming the otp are read-modify-writes of the cached otp
+	defer s.locks.lock(cacheKey)()
+	lastOTPMeta, err := s.cache.Get(cacheKey)
 	if err != nil {
 		if errors.Is(err, cache.ErrKeyNotFound) {
 			return apperrors.NewInvalidParamsError("otp", errors.New("otp not sent or expired"))
@@ -121,39 +192,100 @@ func (s otpSvc) Verify(ctx context.Context, target, otp, channel string) error {
 		return apperrors.NewServerError(errors.New("invalid last otp"))
 	}
 
-	if time.Since(lastOTP.SentAt) >= s.config.validity() {
+	validFor := s.config.validity() - time.Since(lastOTP.SentAt)
+	if validFor <= 0 {
 		return apperrors.NewInvalidParamsError("otp", errors.New("otp expired"))
 	}
 
-	if lastOTP.OTP != otp {
+	if !hmac.Equal([]byte(lastOTP.OTPHash), []byte(s.hashOTP(channel, target, otp))) {
+		lastOTP.VerifyAttempts++
+		if lastOTP.VerifyAttempts >= s.config.maxVerifyAttempts() {
+			if err := s.lockout(channel, target); err != nil {
+				return err
+			}
+			return apperrors.NewInvalidParamsError("otp", errors.New("max verify attempts reached"))
+		}
+		if err := s.cache.Set(cacheKey, lastOTP, validFor); err != nil {
+			return errors.Wrap(err, "unable to update otp attempts")
+		}
 		return apperrors.NewInvalidParamsError("otp", errors.New("incorrect otp"))
 	}
 
+	if err := s.cache.Delete(cacheKey); err != nil {
+		return errors.Wrap(err, "unable to consume otp")
+	}
+	return nil
+}
+
+// next returns the attempt number for the next send or an error if the
+// target has exhausted maxAttempts or is retrying before retryAfter.
+func (a sendAttempt) next(resource string, maxAttempts int, retryAfter time.Duration) (int, error) {
+	if a.Attempt >= maxAttempts {
+		return 0, apperrors.NewInvalidParamsError(resource, errors.New("max attempt reached"))
+	}
+	if time.Since(a.SentAt) < retryAfter {
+		return 0, apperrors.NewInvalidParamsError(resource, errors.New("retry too soon"))
+	}
+	return a.Attempt + 1, nil
+}
+
+// lockout blocks sending and verifying otps for target and drops the pending otp.
+func (s otpSvc) lockout(channel, target string) error {
+	if err := s.cache.Set(buildOTPLockKey(channel, target), time.Now(), s.config.lockout()); err != nil {
+		return errors.Wrap(err, "unable to lock otp target")
+	}
+	if err := s.cache.Delete(buildOTPKey(channel, target)); err != nil {
+		return errors.Wrap(err, "unable to remove otp")
+	}
 	return nil
 }
 
-func (s otpSvc) sendOTPByChannel(channel, target, otp string) error {
-	brand := s.config.brandName()
-	message := otpMessage(otp, brand)
+func (s otpSvc) checkLockout(channel, target string) error {
+	_, err := s.cache.Get(buildOTPLockKey(channel, target))
+	if err == nil {
+		return apperrors.NewInvalidParamsError("otp", errors.New("too many incorrect attempts, try again later"))
+	}
+	if !errors.Is(err, cache.ErrKeyNotFound) {
+		return errors.Wrap(err, "unable to get otp lockout")
+	}
+	return nil
+}
+
+// hashOTP binds the otp to its target so that hashes can't be swapped between targets.
+func (s otpSvc) hashOTP(channel, target, otp string) string {
+	mac := hmac.New(sha256.New, s.hashKey)
+	mac.Write([]byte(buildOTPKey(channel, target) + ":" + otp))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (s otpSvc) sendOTPByChannel(ctx context.Context, channel, target, otp string) (string, error) {
+	notifier := notifications.NewNotifier(s.renderer)
+	data := notifications.OTPData{
+		Code:             otp,
+		Brand:            s.config.brandName(),
+		ExpiresInMinutes: int(s.config.validity().Minutes()),
+	}
 	switch channel {
 	case OTPChannelSMS:
 		if s.smsProvider == nil {
-			return apperrors.NewServerError(errors.New("sms provider not configured"))
+			return "", apperrors.NewServerError(errors.New("sms provider not configured"))
 		}
-		if err := s.smsProvider.SendSMS(target, message); err != nil {
-			return errors.Wrap(err, "unable to send otp")
+		messageID, err := notifier.WithSMSProvider(s.smsProvider).SendSMS(ctx, target, notifications.TemplateOTP, data)
+		if err != nil {
+			return "", errors.Wrap(err, "unable to send otp")
 		}
-		return nil
+		return messageID, nil
 	case OTPChannelEmail:
 		if s.emailProvider == nil {
-			return apperrors.NewServerError(errors.New("email provider not configured"))
+			return "", apperrors.NewServerError(errors.New("email provider not configured"))
 		}
-		if err := s.emailProvider.SendEmail(target, otpEmailSubject(brand), message); err != nil {
-			return errors.Wrap(err, "unable to send otp")
+		messageID, err := notifier.WithEmailProvider(s.emailProvider).SendEmail(ctx, target, notifications.TemplateOTP, data)
+		if err != nil {
+			return "", errors.Wrap(err, "unable to send otp")
 		}
-		return nil
+		return messageID, nil
 	default:
-		return apperrors.NewInvalidParamsError("channel", fmt.Errorf("unsupported channel: %s", channel))
+		return "", apperrors.NewInvalidParamsError("channel", fmt.Errorf("unsupported channel: %s", channel))
 	}
 }
 
@@ -161,24 +293,23 @@ func buildOTPKey(channel, target string) string {
 	return "otp:" + channel + ":" + strings.ToLower(strings.TrimSpace(target))
 }
 
-var r = rand.New(rand.NewSource(time.Now().UnixNano()))
+func buildOTPLockKey(channel, target string) string {
+	return "otp_lock:" + channel + ":" + strings.ToLower(strings.TrimSpace(target))
+}
+
+var otpDigits = big.NewInt(10)
 
-func generateOTP(length int) string {
-	digits := "0123456789"
+// generateOTP returns a numeric otp using crypto/rand, digits are uniformly distributed.
+func generateOTP(length int) (string, error) {
 	otp := make([]byte, length)
 	for i := range otp {
-		otp[i] = digits[r.Intn(len(digits))]
+		n, err := rand.Int(rand.Reader, otpDigits)
+		if err != nil {
+			return "", errors.Wrap(err, "unable to generate otp")
+		}
+		otp[i] = byte('0' + n.Int64())
 	}
-
-	return string(otp)
-}
-
-func otpMessage(otp, organisation string) string {
-	return fmt.Sprintf(otpExpiryHintTemplate, organisation, otp)
-}
-
-func otpEmailSubject(organisation string) string {
-	return fmt.Sprintf(otpSubjectTemplate, organisation)
+	return string(otp), nil
 }
 
 func validatePhone(phone string) error {
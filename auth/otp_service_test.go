@@ -2,7 +2,10 @@ package auth
 
 import (
 	"context"
+	"errors"
 	"regexp"
+	"sync"
+	"sync/atomic"
 	"testing"
 
 	"github.com/krsoninikhil/go-rest-kit/cache"
@@ -23,13 +26,46 @@ type fakeEmailProvider struct {
 	lastTo      string
 	lastSubject string
 	lastBody    string
+	err         error
 }
 
 func (f *fakeEmailProvider) SendEmail(to, subject, message string) error {
 	f.lastTo = to
 	f.lastSubject = subject
 	f.lastBody = message
-	return nil
+	return f.err
+}
+
+type fakeTrackedSMSProvider struct {
+	fakeSMSProvider
+}
+
+func (f *fakeTrackedSMSProvider) SendTrackedSMS(ctx context.Context, phone, message string) (string, error) {
+	return "SM123", f.SendSMS(phone, message)
+}
+
+func TestOTPSvc_SendReturnsMessageID(t *testing.T) {
+	svc := mustOTPSvc(t, otpConfig{
+		ValiditySeconds:   600,
+		MaxAttempts:       5,
+		RetryAfterSeconds: 30,
+		Length:            6,
+		SecretKey:         "otp-secret",
+	}, &fakeTrackedSMSProvider{}, cache.NewInMemory())
+
+	res, err := svc.Send(context.Background(), "+12345678901", OTPChannelSMS)
+	if err != nil || res.MessageID != "SM123" {
+		t.Fatalf("expected message id of the sms, got %+v %v", res, err)
+	}
+}
+
+func mustOTPSvc(t *testing.T, config otpConfig, sms smsProvider, store cacheClient) otpSvc {
+	t.Helper()
+	svc, err := NewOTPSvc(config, sms, store)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return svc
 }
 
 func Test_generateOTP(t *testing.T) {
@@ -44,8 +80,8 @@ func Test_generateOTP(t *testing.T) {
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			got := generateOTP(tt.length)
-			if len(got) != tt.length {
+			got, err := generateOTP(tt.length)
+			if err != nil || len(got) != tt.length {
 				t.Fatalf("unexpected otp length: got=%d want=%d", len(got), tt.length)
 			}
 		})
@@ -54,12 +90,13 @@ func Test_generateOTP(t *testing.T) {
 
 func TestOTPSvc_SMS_DefaultChannelCompatibility(t *testing.T) {
 	sms := &fakeSMSProvider{}
-	svc := NewOTPSvc(otpConfig{
+	svc := mustOTPSvc(t, otpConfig{
 		ValiditySeconds:   600,
 		MaxAttempts:       5,
 		RetryAfterSeconds: 30,
 		Length:            6,
 		Organisation:      "FaithLabs",
+		SecretKey:         "otp-secret",
 	}, sms, cache.NewInMemory())
 
 	res, err := svc.Send(context.Background(), "+12345678901", OTPChannelSMS)
@@ -87,12 +124,13 @@ func TestOTPSvc_SMS_DefaultChannelCompatibility(t *testing.T) {
 func TestOTPSvc_EmailChannel(t *testing.T) {
 	sms := &fakeSMSProvider{}
 	email := &fakeEmailProvider{}
-	svc := NewOTPSvc(otpConfig{
+	svc := mustOTPSvc(t, otpConfig{
 		ValiditySeconds:   600,
 		MaxAttempts:       5,
 		RetryAfterSeconds: 30,
 		Length:            6,
 		Organisation:      "FaithLabs",
+		SecretKey:         "otp-secret",
 	}, sms, cache.NewInMemory()).WithEmailProvider(email)
 
 	res, err := svc.Send(context.Background(), "reader@example.com", OTPChannelEmail)
@@ -125,12 +163,13 @@ func TestOTPSvc_EmailChannel(t *testing.T) {
 
 func TestOTPSvc_ChannelDefaultsToSMS(t *testing.T) {
 	sms := &fakeSMSProvider{}
-	svc := NewOTPSvc(otpConfig{
+	svc := mustOTPSvc(t, otpConfig{
 		ValiditySeconds:   600,
 		MaxAttempts:       5,
 		RetryAfterSeconds: 30,
 		Length:            6,
 		Organisation:      "FaithLabs",
+		SecretKey:         "otp-secret",
 	}, sms, cache.NewInMemory())
 
 	_, err := svc.Send(context.Background(), "+12345678901", "")
@@ -148,13 +187,14 @@ func TestOTPSvc_ChannelDefaultsToSMS(t *testing.T) {
 func TestOTPSvc_TestEmailSkipsSending(t *testing.T) {
 	sms := &fakeSMSProvider{}
 	email := &fakeEmailProvider{}
-	svc := NewOTPSvc(otpConfig{
+	svc := mustOTPSvc(t, otpConfig{
 		ValiditySeconds:   600,
 		MaxAttempts:       5,
 		RetryAfterSeconds: 30,
 		Length:            6,
 		Organisation:      "FaithLabs",
 		TestEmail:         "engineering@faithlabs.io",
+		SecretKey:         "otp-secret",
 	}, sms, cache.NewInMemory()).WithEmailProvider(email)
 
 	_, err := svc.Send(context.Background(), "engineering@faithlabs.io", OTPChannelEmail)
@@ -168,3 +208,132 @@ func TestOTPSvc_TestEmailSkipsSending(t *testing.T) {
 		t.Fatalf("verify test email otp failed: %v", err)
 	}
 }
+
+func TestOTPSvc_OTPIsConsumedOnVerify(t *testing.T) {
+	sms := &fakeSMSProvider{}
+	svc := mustOTPSvc(t, otpConfig{
+		ValiditySeconds:   600,
+		MaxAttempts:       5,
+		RetryAfterSeconds: 30,
+		Length:            6,
+		SecretKey:         "otp-secret",
+	}, sms, cache.NewInMemory())
+
+	if _, err := svc.Send(context.Background(), "+12345678901", OTPChannelSMS); err != nil {
+		t.Fatalf("send otp failed: %v", err)
+	}
+	otp := regexp.MustCompile(`\d{6}`).FindString(sms.lastBody)
+	if err := svc.Verify(context.Background(), "+12345678901", otp, OTPChannelSMS); err != nil {
+		t.Fatalf("verify otp failed: %v", err)
+	}
+	if err := svc.Verify(context.Background(), "+12345678901", otp, OTPChannelSMS); err == nil {
+		t.Fatalf("expected replayed otp to be rejected")
+	}
+}
+
+func TestOTPSvc_ConcurrentVerify(t *testing.T) {
+	sms := &fakeSMSProvider{}
+	svc := mustOTPSvc(t, otpConfig{
+		ValiditySeconds:   600,
+		MaxAttempts:       5,
+		RetryAfterSeconds: 30,
+		Length:            6,
+		SecretKey:         "otp-secret",
+	}, sms, cache.NewInMemory())
+
+	if _, err := svc.Send(context.Background(), "+12345678901", OTPChannelSMS); err != nil {
+		t.Fatalf("send otp failed: %v", err)
+	}
+	otp := regexp.MustCompile(`\d{6}`).FindString(sms.lastBody)
+	var (
+		wg        sync.WaitGroup
+		successes atomic.Int32
+	)
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if svc.Verify(context.Background(), "+12345678901", otp, OTPChannelSMS) == nil {
+				successes.Add(1)
+			}
+		}()
+	}
+	wg.Wait()
+	if successes.Load() != 1 {
+		t.Fatalf("expected otp to be consumed once, verified %d times", successes.Load())
+	}
+}
+
+func TestOTPSvc_LockoutAfterMaxVerifyAttempts(t *testing.T) {
+	sms := &fakeSMSProvider{}
+	svc := mustOTPSvc(t, otpConfig{
+		ValiditySeconds:   600,
+		MaxAttempts:       5,
+		RetryAfterSeconds: 30,
+		Length:            6,
+		MaxVerifyAttempts: 3,
+		SecretKey:         "otp-secret",
+	}, sms, cache.NewInMemory())
+
+	if _, err := svc.Send(context.Background(), "+12345678901", OTPChannelSMS); err != nil {
+		t.Fatalf("send otp failed: %v", err)
+	}
+	otp := regexp.MustCompile(`\d{6}`).FindString(sms.lastBody)
+	wrong := "000000"
+	if otp == wrong {
+		wrong = "111111"
+	}
+	for i := 0; i < 3; i++ {
+		if err := svc.Verify(context.Background(), "+12345678901", wrong, OTPChannelSMS); err == nil {
+			t.Fatalf("expected incorrect otp to fail on attempt %d", i+1)
+		}
+	}
+	if err := svc.Verify(context.Background(), "+12345678901", otp, OTPChannelSMS); err == nil {
+		t.Fatalf("expected correct otp to be rejected during lockout")
+	}
+	if _, err := svc.Send(context.Background(), "+12345678901", OTPChannelSMS); err == nil {
+		t.Fatalf("expected send to be rejected during lockout")
+	}
+}
+
+func TestOTPSvc_OTPNotStoredInPlaintext(t *testing.T) {
+	sms := &fakeSMSProvider{}
+	store := cache.NewInMemory()
+	svc := mustOTPSvc(t, otpConfig{
+		ValiditySeconds:   600,
+		MaxAttempts:       5,
+		RetryAfterSeconds: 30,
+		Length:            6,
+		SecretKey:         "otp-secret",
+	}, sms, store)
+
+	if _, err := svc.Send(context.Background(), "+12345678901", OTPChannelSMS); err != nil {
+		t.Fatalf("send otp failed: %v", err)
+	}
+	otp := regexp.MustCompile(`\d{6}`).FindString(sms.lastBody)
+	val, err := store.Get(buildOTPKey(OTPChannelSMS, "+12345678901"))
+	if err != nil {
+		t.Fatalf("otp metadata not cached: %v", err)
+	}
+	if meta := val.(otpMetaData); meta.OTPHash == "" || meta.OTPHash == otp {
+		t.Fatalf("expected otp to be stored hashed, got %q", meta.OTPHash)
+	}
+}
+
+func TestConfig_OTPConfig(t *testing.T) {
+	conf := Config{SecretKey: "secret", OTP: otpConfig{Length: 6}}
+	otp := conf.OTPConfig()
+	if otp.SecretKey == "" || otp.SecretKey == conf.SecretKey || otp.SecretKey != conf.OTPConfig().SecretKey {
+		t.Fatalf("expected a stable otp key derived from the secret key, got %q", otp.SecretKey)
+	}
+	if _, err := NewOTPSvc(conf.OTP, &fakeSMSProvider{}, cache.NewInMemory()); !errors.Is(err, errOTPSecretKey) {
+		t.Errorf("expected otp service without a secret key to fail, got %v", err)
+	}
+	if _, err := NewOTPSvc(otp, &fakeSMSProvider{}, cache.NewInMemory()); err != nil {
+		t.Errorf("expected derived otp key to be accepted, got %v", err)
+	}
+	conf.OTP.SecretKey = "otp-secret"
+	if otp := conf.OTPConfig(); otp.SecretKey != "otp-secret" {
+		t.Errorf("expected configured otp key to be kept, got %q", otp.SecretKey)
+	}
+}
@@ -1,12 +1,15 @@
 package cache
 
 import (
+	"sync"
 	"time"
 
 	"github.com/pkg/errors"
 )
 
+// InMemory is a process local cache, safe for concurrent use.
 type InMemory struct {
+	mu       sync.RWMutex
 	data     map[string]interface{}
 	validity map[string]time.Time
 }
@@ -19,12 +22,16 @@ func NewInMemory() *InMemory {
 }
 
 func (c *InMemory) Set(key string, value interface{}, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.data[key] = value
 	c.validity[key] = time.Now().Add(ttl)
 	return nil
 }
 
 func (c *InMemory) Get(key string) (interface{}, error) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	value, ok := c.data[key]
 	if !ok || time.Now().After(c.validity[key]) {
 		return nil, errors.WithStack(ErrKeyNotFound)
@@ -34,18 +41,24 @@ func (c *InMemory) Get(key string) (interface{}, error) {
 }
 
 func (c *InMemory) Delete(key string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	delete(c.data, key)
 	delete(c.validity, key)
 	return nil
 }
 
 func (c *InMemory) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
 	c.data = make(map[string]interface{})
 	c.validity = make(map[string]time.Time)
 	return nil
 }
 
 func (c *InMemory) Keys() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
 	keys := make([]string, 0, len(c.data))
 	for key := range c.data {
 		if time.Now().Before(c.validity[key]) {
@@ -0,0 +1,81 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"time"
+)
+
+const redacted = "[REDACTED]"
+
+// Dump returns the effective config as indented json with values of fields tagged `log:"-"`
+// redacted, for logging at startup.
+func Dump(target any) string {
+	data, err := json.MarshalIndent(Redacted(target), "", "  ")
+	if err != nil {
+		return err.Error()
+	}
+	return string(data)
+}
+
+// Redacted returns target as a map by config keys with `log:"-"` fields redacted
+func Redacted(target any) any {
+	return redact(reflect.ValueOf(target))
+}
+
+func redact(v reflect.Value) any {
+	for v.Kind() == reflect.Pointer || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch v.Kind() {
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			return t
+		}
+		res := map[string]any{}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name := keyName(field)
+			if !field.IsExported() || name == "" {
+				continue
+			}
+			switch {
+			case isSquashed(field):
+				if embedded, ok := redact(v.Field(i)).(map[string]any); ok {
+					for k, val := range embedded {
+						res[k] = val
+					}
+				}
+			case field.Tag.Get("log") == "-" && !v.Field(i).IsZero():
+				res[name] = redacted
+			case field.Tag.Get("log") == "-":
+				res[name] = ""
+			default:
+				res[name] = redact(v.Field(i))
+			}
+		}
+		return res
+	case reflect.Slice, reflect.Array:
+		res := make([]any, v.Len())
+		for i := range res {
+			res[i] = redact(v.Index(i))
+		}
+		return res
+	case reflect.Map:
+		res := map[string]any{}
+		for _, k := range v.MapKeys() {
+			res[fmt.Sprint(k.Interface())] = redact(v.MapIndex(k))
+		}
+		return res
+	case reflect.Invalid:
+		return nil
+	}
+	if d, ok := v.Interface().(time.Duration); ok {
+		return d.String()
+	}
+	return v.Interface()
+}
@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
+	"reflect"
 	"strings"
+	"sync"
 
 	"github.com/joho/godotenv"
 	"github.com/spf13/viper"
@@ -23,29 +26,96 @@ type AppConfig interface {
 	SetEnv(string)
 }
 
-func Load(ctx context.Context, target AppConfig) {
+// Option configures LoadE
+type Option func(*loadOptions)
+
+type loadOptions struct {
+	sources []Source
+}
+
+// WithSources merges sources over the config files, env vars still override them
+func WithSources(sources ...Source) Option {
+	return func(o *loadOptions) { o.sources = append(o.sources, sources...) }
+}
+
+// Load reads the config files of target's env and env var overrides into target and validates
+// it, see LoadE.
+func Load(ctx context.Context, target AppConfig) error {
+	return LoadE(ctx, target)
+}
+
+// LoadE loads base.yml, the SourcePath file and <env>.local.yml (same dir and extension as
+// SourcePath), then the sources of opts, with env vars overriding all of them. ${file:path} and
+// ${env:NAME} in values are replaced with the file content or env var. The result is validated,
+// see Validate, a ValidationError lists all invalid values.
+func LoadE(ctx context.Context, target AppConfig, opts ...Option) error {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	loadMu.Lock()
+	defer loadMu.Unlock()
+	return load(ctx, viper.GetViper(), target, o)
+}
+
+var (
+	// loadMu serializes loads as LoadE uses the global viper instance and loads set env vars
+	loadMu sync.Mutex
+	// dotenvKeys are the env vars set from .env files, reloads update them but never env vars
+	// set by the environment
+	dotenvKeys = map[string]bool{}
+)
+
+func load(ctx context.Context, vp *viper.Viper, target AppConfig, o loadOptions) error {
 	// override from env
-	viper.AutomaticEnv()
-	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
-	if err := godotenv.Load(target.EnvPath()); err == nil {
+	vp.AutomaticEnv()
+	vp.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	if err := loadDotenv(target.EnvPath()); err == nil {
 		fmt.Print(ctx, "Environment variables set from", target.EnvPath())
 	}
 
 	// read from config files
-	env := viper.GetString("env")
+	env := vp.GetString("env")
 	if env == "" {
 		env = "development"
 	}
 	target.SetEnv(strings.ToLower(env))
-	viper.SetConfigFile(target.SourcePath())
 	log.Printf("Loading config from %s", target.SourcePath())
 
-	if err := viper.MergeInConfig(); err != nil {
-		log.Fatal(ctx, "error reading environment config file", err)
+	if err := merge(ctx, vp, append(layers(target), o.sources...)); err != nil {
+		return err
+	}
+	bindEnvs(vp, reflect.ValueOf(target), "")
+	if err := resolveSecrets(vp); err != nil {
+		return err
 	}
 
-	if err := viper.Unmarshal(target); err != nil {
-		log.Fatal(ctx, "error parsing config", err)
+	if err := vp.Unmarshal(target); err != nil {
+		return fmt.Errorf("config: error parsing: %w", err)
 	}
 	// log.Printf("Loaded config %+v", target)
+	return Validate(target)
+}
+
+// loadDotenv sets the env vars of the .env file at path unless they are set by the environment,
+// env vars set from an earlier read of the file are updated or unset if removed from it.
+func loadDotenv(path string) error {
+	values, err := godotenv.Read(path)
+	if err != nil {
+		return err
+	}
+	for key := range dotenvKeys {
+		if _, ok := values[key]; !ok {
+			os.Unsetenv(key)
+			delete(dotenvKeys, key)
+		}
+	}
+	for key, value := range values {
+		if _, set := os.LookupEnv(key); set && !dotenvKeys[key] {
+			continue
+		}
+		os.Setenv(key, value)
+		dotenvKeys[key] = true
+	}
+	return nil
 }
@@ -0,0 +1,60 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+type loaderTestConfig struct {
+	BaseConfig `mapstructure:",squash"`
+	dir        string
+	Name       string
+	Port       int
+	Region     string
+	DB         struct {
+		Host     string
+		Password string `log:"-"`
+	}
+	APIKey string `log:"-" validate:"required"`
+}
+
+func (c *loaderTestConfig) SourcePath() string { return filepath.Join(c.dir, c.Env+".yml") }
+func (c *loaderTestConfig) EnvPath() string    { return filepath.Join(c.dir, ".env") }
+
+func TestLoadE(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("base.yml", "name: base\nport: 80\nregion: us\n")
+	write("development.yml", fmt.Sprintf("port: 8080\ndb:\n  host: db\n  password: ${file:%s}\n", filepath.Join(dir, "db_password")))
+	write("development.local.yml", "region: ${env:TEST_CONFIG_REGION}\n")
+	write("db_password", "s3cret\n")
+	t.Setenv("TEST_CONFIG_REGION", "eu")
+	t.Setenv("APIKEY", "${env:TEST_CONFIG_REGION}-key")
+
+	conf := loaderTestConfig{dir: dir}
+	conf.Env = "development"
+	err := LoadE(context.Background(), &conf, WithSources(Map("remote", map[string]any{"name": "remote"})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Name != "remote" || conf.Port != 8080 || conf.Region != "eu" || conf.DB.Password != "s3cret" || conf.APIKey != "eu-key" {
+		t.Errorf("unexpected config %+v", conf)
+	}
+
+	dump := Dump(&conf)
+	if strings.Contains(dump, "s3cret") || strings.Contains(dump, "eu-key") || !strings.Contains(dump, `"Password": "[REDACTED]"`) {
+		t.Errorf("secrets not redacted: %s", dump)
+	}
+}
@@ -0,0 +1,51 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+var secretRef = regexp.MustCompile(`\$\{(file|env):([^}]+)\}`)
+
+// resolveSecrets replaces ${file:/path} with the trimmed file content and ${env:NAME} with
+// the env var in all string values, including values set from env vars.
+func resolveSecrets(vp *viper.Viper) error {
+	for _, key := range vp.AllKeys() {
+		value, ok := vp.Get(key).(string)
+		if !ok || !strings.Contains(value, "${") {
+			continue
+		}
+		resolved, err := resolveRefs(value)
+		if err != nil {
+			return fmt.Errorf("config: %s: %w", key, err)
+		}
+		vp.Set(key, resolved)
+	}
+	return nil
+}
+
+func resolveRefs(value string) (string, error) {
+	var err error
+	resolved := secretRef.ReplaceAllStringFunc(value, func(ref string) string {
+		m := secretRef.FindStringSubmatch(ref)
+		switch m[1] {
+		case "file":
+			data, readErr := os.ReadFile(m[2])
+			if readErr != nil && err == nil {
+				err = readErr
+			}
+			return strings.TrimRight(string(data), "\r\n")
+		default:
+			v, found := os.LookupEnv(m[2])
+			if !found && err == nil {
+				err = fmt.Errorf("env var %s is not set", m[2])
+			}
+			return v
+		}
+	})
+	return resolved, err
+}
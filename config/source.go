@@ -0,0 +1,131 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+// Source provides config values merged over the config files, e.g. a remote config service.
+// Keys may be nested maps, later sources override earlier ones.
+type Source interface {
+	Name() string
+	Read(ctx context.Context) (map[string]any, error)
+}
+
+type fileSource struct {
+	path     string
+	optional bool
+}
+
+// File is a yaml, json or toml config file source
+func File(path string) Source { return fileSource{path: path} }
+
+// OptionalFile is File that's skipped if the file doesn't exist
+func OptionalFile(path string) Source { return fileSource{path: path, optional: true} }
+
+func (s fileSource) Name() string { return s.path }
+
+func (s fileSource) Read(ctx context.Context) (map[string]any, error) {
+	if _, err := os.Stat(s.path); s.optional && errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	v := viper.New()
+	v.SetConfigFile(s.path)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, err
+	}
+	return v.AllSettings(), nil
+}
+
+type mapSource struct {
+	name   string
+	values map[string]any
+}
+
+// Map is a source of static values, e.g. defaults or values fetched by the app
+func Map(name string, values map[string]any) Source { return mapSource{name, values} }
+
+func (s mapSource) Name() string                                     { return s.name }
+func (s mapSource) Read(ctx context.Context) (map[string]any, error) { return s.values, nil }
+
+func layerPaths(target AppConfig) []string {
+	var paths []string
+	for _, s := range layers(target) {
+		paths = append(paths, s.Name())
+	}
+	return paths
+}
+
+// layers returns the config files for target: base<ext> and <env>.local<ext> next to
+// SourcePath are optional and merged before and after it.
+func layers(target AppConfig) []Source {
+	path := target.SourcePath()
+	dir, ext := filepath.Dir(path), filepath.Ext(path)
+	name := strings.TrimSuffix(filepath.Base(path), ext)
+	return []Source{
+		OptionalFile(filepath.Join(dir, "base"+ext)),
+		File(path),
+		OptionalFile(filepath.Join(dir, name+".local"+ext)),
+	}
+}
+
+func merge(ctx context.Context, vp *viper.Viper, sources []Source) error {
+	for _, s := range sources {
+		values, err := s.Read(ctx)
+		if err != nil {
+			return fmt.Errorf("config: error reading %s: %w", s.Name(), err)
+		}
+		if values == nil {
+			continue
+		}
+		if err := vp.MergeConfigMap(values); err != nil {
+			return fmt.Errorf("config: error merging %s: %w", s.Name(), err)
+		}
+	}
+	return nil
+}
+
+// bindEnvs binds the env var of every field of target, AutomaticEnv alone only overrides
+// keys present in a config source.
+func bindEnvs(vp *viper.Viper, v reflect.Value, prefix string) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			v = reflect.New(v.Type().Elem())
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct || v.Type() == reflect.TypeOf(time.Time{}) {
+		if prefix != "" {
+			vp.BindEnv(prefix)
+		}
+		return
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		name := keyName(field)
+		if !field.IsExported() || name == "" {
+			continue
+		}
+		key := strings.ToLower(name)
+		if prefix != "" {
+			key = prefix + "." + key
+		}
+		if isSquashed(field) {
+			key = prefix
+		}
+		bindEnvs(vp, v.Field(i), key)
+	}
+}
+
+func isSquashed(field reflect.StructField) bool {
+	_, opts, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+	return strings.Contains(opts, "squash")
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// FieldError is a config value failing its validate tag, Env is the env var overriding it
+type FieldError struct {
+	Path  string
+	Env   string
+	Tag   string
+	Param string
+}
+
+func (e FieldError) String() string {
+	rule := e.Tag
+	if e.Param != "" {
+		rule += "=" + e.Param
+	}
+	return fmt.Sprintf("%s (%s): failed %s", e.Path, e.Env, rule)
+}
+
+// ValidationError lists all invalid config values
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.String()
+	}
+	return fmt.Sprintf("config: %d invalid values: %s", len(e.Fields), strings.Join(msgs, "; "))
+}
+
+var configValidator = newValidator()
+
+func newValidator() *validator.Validate {
+	v := validator.New()
+	v.RegisterTagNameFunc(keyName)
+	return v
+}
+
+// Validate checks the validate tags of target, fields are named by their config keys.
+// Nested structs tagged `validate:"omitempty"` are optional sections, they are only
+// validated if any of their values is set.
+func Validate(target any) error {
+	skip := map[string]struct{}{}
+	optionalSections(reflect.ValueOf(target), rootName(target), skip)
+	err := configValidator.StructFiltered(target, func(ns []byte) bool {
+		for prefix := range skip {
+			if strings.HasPrefix(string(ns), prefix) {
+				return true
+			}
+		}
+		return false
+	})
+
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+	res := &ValidationError{}
+	for _, e := range errs {
+		// namespace starts with the root struct name which isn't part of the config key
+		_, path, _ := strings.Cut(e.Namespace(), ".")
+		res.Fields = append(res.Fields, FieldError{
+			Path:  path,
+			Env:   strings.ToUpper(strings.ReplaceAll(path, ".", "_")),
+			Tag:   e.Tag(),
+			Param: e.Param(),
+		})
+	}
+	return res
+}
+
+// optionalSections adds the namespace prefixes of zero valued optional sections to skip
+func optionalSections(v reflect.Value, ns string, skip map[string]struct{}) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return
+	}
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name := ns + "." + field.Name // filter namespaces use go field names
+		fv := v.Field(i)
+		if fv.Kind() == reflect.Struct && field.Tag.Get("validate") == "omitempty" && fv.IsZero() {
+			skip[name+"."] = struct{}{}
+			continue
+		}
+		optionalSections(fv, name, skip)
+	}
+}
+
+// keyName returns the config key of a field, its mapstructure name if set as viper uses that
+func keyName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("mapstructure"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
+
+func rootName(target any) string {
+	t := reflect.TypeOf(target)
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Name()
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+type testSection struct {
+	APIKey string `validate:"required"`
+}
+
+type testConfig struct {
+	BaseConfig
+	SecretKey string      `validate:"required"`
+	FromEmail string      `mapstructure:"fromEmail" validate:"required,email"`
+	Provider  testSection `validate:"required"`
+	Optional  testSection `validate:"omitempty"`
+	Limit     int         `validate:"gte=1"`
+}
+
+func TestValidate(t *testing.T) {
+	conf := testConfig{FromEmail: "invalid", Limit: 1}
+	err := Validate(&conf)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("expected ValidationError, got %v", err)
+	}
+	want := []FieldError{
+		{Path: "SecretKey", Env: "SECRETKEY", Tag: "required"},
+		{Path: "fromEmail", Env: "FROMEMAIL", Tag: "email"},
+		{Path: "Provider.APIKey", Env: "PROVIDER_APIKEY", Tag: "required"},
+	}
+	if len(verr.Fields) != len(want) {
+		t.Fatalf("expected %d errors, got %v", len(want), verr)
+	}
+	for i := range want {
+		if verr.Fields[i] != want[i] {
+			t.Errorf("field %d: expected %+v, got %+v", i, want[i], verr.Fields[i])
+		}
+	}
+
+	// optional sections are validated once configured
+	conf = testConfig{SecretKey: "s", FromEmail: "a@b.com", Provider: testSection{"k"}, Limit: 1}
+	if err := Validate(&conf); err != nil {
+		t.Errorf("expected valid config, got %v", err)
+	}
+	conf.Optional.APIKey = "k"
+	conf.Limit = 0
+	if err := Validate(&conf); !errors.As(err, &verr) || len(verr.Fields) != 1 || verr.Fields[0].Path != "Limit" {
+		t.Errorf("expected Limit error, got %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/spf13/viper"
+)
+
+const reloadDebounce = 200 * time.Millisecond
+
+// Value holds a config that's reloaded on change, see Watch. Get is safe for concurrent use,
+// keep the returned config for the duration of a request instead of calling Get repeatedly.
+type Value[T any] struct {
+	current atomic.Pointer[T]
+	load    func(ctx context.Context) (*T, error)
+
+	mu     sync.Mutex
+	subs   map[int]func(old, new *T)
+	nextID int
+}
+
+// Get returns the current config, it must not be modified
+func (v *Value[T]) Get() *T { return v.current.Load() }
+
+// Subscribe calls fn after every successful reload, returned func unsubscribes
+func (v *Value[T]) Subscribe(fn func(old, new *T)) (unsubscribe func()) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	id := v.nextID
+	v.nextID++
+	v.subs[id] = fn
+	return func() {
+		v.mu.Lock()
+		defer v.mu.Unlock()
+		delete(v.subs, id)
+	}
+}
+
+// Reload loads the config again and swaps it if valid, an invalid config is rejected and
+// the current one kept.
+func (v *Value[T]) Reload(ctx context.Context) error {
+	next, err := v.load(ctx)
+	if err != nil {
+		return err
+	}
+	old := v.current.Swap(next)
+	v.mu.Lock()
+	subs := make([]func(old, new *T), 0, len(v.subs))
+	for _, fn := range v.subs {
+		subs = append(subs, fn)
+	}
+	v.mu.Unlock()
+	for _, fn := range subs {
+		fn(old, next)
+	}
+	return nil
+}
+
+// Watch loads target like LoadE and reloads it into a copy of target whenever the config or
+// .env files change, until ctx is done. Sources of opts are read again on every reload.
+func Watch[T any, PT interface {
+	*T
+	AppConfig
+}](ctx context.Context, target PT, opts ...Option) (*Value[T], error) {
+	var o loadOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	template := *target
+	v := &Value[T]{subs: map[int]func(old, new *T){}}
+	v.load = func(ctx context.Context) (*T, error) {
+		next := new(T)
+		*next = template
+		loadMu.Lock()
+		defer loadMu.Unlock()
+		if err := load(ctx, viper.New(), PT(next), o); err != nil {
+			return nil, err
+		}
+		return next, nil
+	}
+
+	if err := LoadE(ctx, target, opts...); err != nil {
+		return nil, err
+	}
+	loaded := new(T)
+	*loaded = *target
+	v.current.Store(loaded)
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, fmt.Errorf("config: error watching files: %w", err)
+	}
+	files := map[string]struct{}{}
+	for _, path := range append(layerPaths(target), target.EnvPath()) {
+		abs, err := filepath.Abs(path)
+		if err != nil {
+			watcher.Close()
+			return nil, err
+		}
+		files[abs] = struct{}{}
+		if err := watcher.Add(filepath.Dir(abs)); err != nil {
+			watcher.Close()
+			return nil, fmt.Errorf("config: error watching %s: %w", filepath.Dir(abs), err)
+		}
+	}
+	go v.watch(ctx, watcher, files)
+	return v, nil
+}
+
+// watch reloads after changes of files settle, directories are watched as editors and
+// kubernetes config maps replace files instead of writing them.
+func (v *Value[T]) watch(ctx context.Context, watcher *fsnotify.Watcher, files map[string]struct{}) {
+	defer watcher.Close()
+	timer := time.NewTimer(reloadDebounce)
+	timer.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return
+			}
+			if _, watched := files[filepath.Clean(event.Name)]; watched || filepath.Base(event.Name) == "..data" {
+				timer.Reset(reloadDebounce)
+			}
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("config: error watching files: %v", err)
+		case <-timer.C:
+			if err := v.Reload(ctx); err != nil {
+				log.Printf("config: rejected reload, keeping current config: %v", err)
+			}
+		}
+	}
+}
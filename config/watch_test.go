@@ -0,0 +1,96 @@
+package config
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func TestWatch(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	path := filepath.Join(dir, "development.yml")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("port: 1\napikey: k\n")
+
+	conf := &loaderTestConfig{dir: dir}
+	conf.Env = "development"
+	value, err := Watch(ctx, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes := make(chan int, 1)
+	value.Subscribe(func(old, new *loaderTestConfig) { changes <- new.Port })
+	if value.Get().Port != 1 {
+		t.Fatalf("unexpected initial config %+v", value.Get())
+	}
+
+	write("port: 2\napikey: k\n")
+	select {
+	case port := <-changes:
+		if port != 2 || value.Get().Port != 2 {
+			t.Errorf("expected reloaded port 2, got %d", port)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("config not reloaded")
+	}
+
+	// invalid config is rejected
+	write("port: 3\n")
+	if err := value.Reload(ctx); err == nil {
+		t.Errorf("expected invalid reload to fail")
+	}
+	if value.Get().Port != 2 {
+		t.Errorf("expected config to be kept, got %+v", value.Get())
+	}
+}
+
+func TestWatch_EnvOverridesDotenv(t *testing.T) {
+	viper.Reset()
+	defer viper.Reset()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	dir := t.TempDir()
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("development.yml", "port: 1\napikey: k\n")
+	write(".env", "REGION=dotenv\nNAME=first\n")
+	t.Setenv("REGION", "env")
+	t.Cleanup(func() {
+		os.Unsetenv("NAME")
+		delete(dotenvKeys, "NAME")
+	})
+
+	conf := &loaderTestConfig{dir: dir}
+	conf.Env = "development"
+	value, err := Watch(ctx, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("app.key", "set by app")
+
+	write(".env", "REGION=dotenv\nNAME=second\n")
+	if err := value.Reload(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if got := value.Get(); got.Region != "env" || got.Name != "second" {
+		t.Errorf("expected env var to override .env after reload, got region=%q name=%q", got.Region, got.Name)
+	}
+	if viper.GetString("app.key") != "set by app" {
+		t.Errorf("expected reload to keep global viper keys")
+	}
+}
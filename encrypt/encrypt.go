@@ -1,16 +1,16 @@
 // Package encrypt provides AES-256-GCM encryption for sensitive values at rest (e.g. API keys).
 // Stored format: "v1:" + base64(nonce || ciphertext || tag). Values without the "v1:" prefix
 // are treated as legacy plaintext for backward compatibility.
+//
+// Keyring stores "v2:<kid>:" values so keys can be rotated, and Envelope stores "v2e:" values
+// whose data keys are wrapped by a KeyWrapper such as a KMS.
 package encrypt
 
 import (
-	"crypto/aes"
-	"crypto/cipher"
-	"crypto/rand"
 	"encoding/base64"
+	"encoding/binary"
 	"encoding/hex"
 	"errors"
-	"io"
 	"strings"
 )
 
@@ -24,39 +24,30 @@ const (
 // Encrypt encrypts plaintext with AES-256-GCM. Returns "v1:" + base64(nonce || ciphertext || tag).
 // key must be 32 bytes. If plaintext is empty, returns empty string without error.
 func Encrypt(plaintext string, key []byte) (string, error) {
+	return EncryptWithAAD(plaintext, key, nil)
+}
+
+// EncryptWithAAD is Encrypt with additional data, e.g. AAD("users", "api_key", id), the value
+// then decrypts only with the same aad so it can't be copied to another row or column.
+func EncryptWithAAD(plaintext string, key []byte, aad []byte) (string, error) {
 	if plaintext == "" {
 		return "", nil
 	}
-	if len(key) != aesKeySize {
-		return "", errors.New("encrypt: key must be 32 bytes")
-	}
-
-	block, err := aes.NewCipher(key)
-	if err != nil {
-		return "", err
-	}
-	aead, err := cipher.NewGCM(block)
+	sealed, err := EncryptBytes([]byte(plaintext), key, aad)
 	if err != nil {
 		return "", err
 	}
-
-	nonce := make([]byte, nonceSize)
-	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
-		return "", err
-	}
-
-	ciphertext := aead.Seal(nil, nonce, []byte(plaintext), nil)
-	// combined = nonce || ciphertext (which includes tag in GCM)
-	combined := make([]byte, 0, len(nonce)+len(ciphertext))
-	combined = append(combined, nonce...)
-	combined = append(combined, ciphertext...)
-
-	return versionPrefix + base64.StdEncoding.EncodeToString(combined), nil
+	return versionPrefix + base64.StdEncoding.EncodeToString(sealed), nil
 }
 
 // Decrypt decrypts a value produced by Encrypt. If the value does not have the "v1:" prefix,
 // it is treated as legacy plaintext and returned as-is.
 func Decrypt(ciphertext string, key []byte) (string, error) {
+	return DecryptWithAAD(ciphertext, key, nil)
+}
+
+// DecryptWithAAD decrypts a value of EncryptWithAAD, aad must match the one used to encrypt.
+func DecryptWithAAD(ciphertext string, key []byte, aad []byte) (string, error) {
 	if ciphertext == "" {
 		return "", nil
 	}
@@ -64,36 +55,45 @@ func Decrypt(ciphertext string, key []byte) (string, error) {
 		// Legacy plaintext
 		return ciphertext, nil
 	}
-	if len(key) != aesKeySize {
-		return "", errors.New("encrypt: key must be 32 bytes")
-	}
-
-	encoded := ciphertext[len(versionPrefix):]
-	combined, err := base64.StdEncoding.DecodeString(encoded)
+	combined, err := base64.StdEncoding.DecodeString(ciphertext[len(versionPrefix):])
 	if err != nil {
 		return "", err
 	}
-	if len(combined) < nonceSize+gcmTagSize {
-		return "", errors.New("encrypt: ciphertext too short")
-	}
-
-	nonce := combined[:nonceSize]
-	sealed := combined[nonceSize:]
-
-	block, err := aes.NewCipher(key)
+	plain, err := DecryptBytes(combined, key, aad)
 	if err != nil {
 		return "", err
 	}
-	aead, err := cipher.NewGCM(block)
+	return string(plain), nil
+}
+
+// EncryptBytes encrypts plaintext with AES-256-GCM and returns nonce || ciphertext || tag.
+// aad may be nil.
+func EncryptBytes(plaintext, key, aad []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
+	return seal(aead, plaintext, aad)
+}
 
-	plain, err := aead.Open(nil, nonce, sealed, nil)
+// DecryptBytes decrypts a value of EncryptBytes
+func DecryptBytes(ciphertext, key, aad []byte) ([]byte, error) {
+	aead, err := newAEAD(key)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	return string(plain), nil
+	return open(aead, ciphertext, aad)
+}
+
+// AAD builds additional data from parts, each part is length prefixed so that e.g.
+// ("ab", "c") and ("a", "bc") differ.
+func AAD(parts ...string) []byte {
+	var buf []byte
+	for _, p := range parts {
+		buf = binary.BigEndian.AppendUint32(buf, uint32(len(p)))
+		buf = append(buf, p...)
+	}
+	return buf
 }
 
 // DecodeKey decodes a 32-byte key from config. Accepts:
@@ -0,0 +1,176 @@
+package encrypt
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// envelope format: "v2e:" + base64(len(kid) || kid || len(wrapped key) || wrapped key ||
+// nonce || ciphertext || tag), lengths are 2 byte big endian.
+const envelopePrefix = "v2e:"
+
+var (
+	errEnvelopeValue   = errors.New("encrypt: envelope encrypted value, use Envelope.Decrypt")
+	errInvalidEnvelope = errors.New("encrypt: invalid envelope")
+)
+
+// KeyWrapper encrypts data keys with a master key it holds, e.g. a KMS. keyID identifies the
+// master key used and is passed back to UnwrapKey.
+type KeyWrapper interface {
+	WrapKey(ctx context.Context, dataKey []byte) (wrapped []byte, keyID string, err error)
+	UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error)
+}
+
+type localKeyWrapper struct {
+	keyring *Keyring
+}
+
+// NewLocalKeyWrapper wraps data keys with the active key of keyring, for setups without a KMS.
+func NewLocalKeyWrapper(keyring *Keyring) KeyWrapper {
+	return &localKeyWrapper{keyring: keyring}
+}
+
+func (w *localKeyWrapper) WrapKey(ctx context.Context, dataKey []byte) ([]byte, string, error) {
+	wrapped, err := seal(w.keyring.keys[w.keyring.active], dataKey, nil)
+	return wrapped, w.keyring.active, err
+}
+
+func (w *localKeyWrapper) UnwrapKey(ctx context.Context, keyID string, wrapped []byte) ([]byte, error) {
+	aead, ok := w.keyring.keys[keyID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, keyID)
+	}
+	return open(aead, wrapped, nil)
+}
+
+// Envelope encrypts each value with a new data key that's stored wrapped by KeyWrapper along
+// with the value, so the master key never leaves the KMS and can be rotated with Rewrap.
+type Envelope struct {
+	wrapper KeyWrapper
+}
+
+func NewEnvelope(wrapper KeyWrapper) *Envelope {
+	return &Envelope{wrapper: wrapper}
+}
+
+// Encrypt encrypts plaintext with a new data key, empty plaintext returns empty string.
+func (e *Envelope) Encrypt(ctx context.Context, plaintext string) (string, error) {
+	return e.EncryptWithAAD(ctx, plaintext, nil)
+}
+
+// EncryptWithAAD is Encrypt bound to additional data, see AAD.
+func (e *Envelope) EncryptWithAAD(ctx context.Context, plaintext string, aad []byte) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	dataKey := make([]byte, aesKeySize)
+	if _, err := io.ReadFull(rand.Reader, dataKey); err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(aead, []byte(plaintext), aad)
+	if err != nil {
+		return "", err
+	}
+	wrapped, keyID, err := e.wrapper.WrapKey(ctx, dataKey)
+	if err != nil {
+		return "", err
+	}
+	return encodeEnvelope(keyID, wrapped, sealed), nil
+}
+
+// Decrypt decrypts values of Encrypt, values without a version prefix are returned as-is.
+func (e *Envelope) Decrypt(ctx context.Context, ciphertext string) (string, error) {
+	return e.DecryptWithAAD(ctx, ciphertext, nil)
+}
+
+// DecryptWithAAD decrypts values of EncryptWithAAD, aad must match the one used to encrypt.
+func (e *Envelope) DecryptWithAAD(ctx context.Context, ciphertext string, aad []byte) (string, error) {
+	if ciphertext == "" || !strings.HasPrefix(ciphertext, envelopePrefix) {
+		return ciphertext, nil
+	}
+	keyID, wrapped, sealed, err := decodeEnvelope(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := e.wrapper.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		return "", err
+	}
+	aead, err := newAEAD(dataKey)
+	if err != nil {
+		return "", err
+	}
+	plain, err := open(aead, sealed, aad)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
+
+// Rewrap wraps the data key of ciphertext again with the current master key of KeyWrapper,
+// the encrypted data isn't changed.
+func (e *Envelope) Rewrap(ctx context.Context, ciphertext string) (string, error) {
+	keyID, wrapped, sealed, err := decodeEnvelope(ciphertext)
+	if err != nil {
+		return "", err
+	}
+	dataKey, err := e.wrapper.UnwrapKey(ctx, keyID, wrapped)
+	if err != nil {
+		return "", err
+	}
+	wrapped, keyID, err = e.wrapper.WrapKey(ctx, dataKey)
+	if err != nil {
+		return "", err
+	}
+	return encodeEnvelope(keyID, wrapped, sealed), nil
+}
+
+func encodeEnvelope(keyID string, wrapped, sealed []byte) string {
+	buf := make([]byte, 0, 4+len(keyID)+len(wrapped)+len(sealed))
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(keyID)))
+	buf = append(buf, keyID...)
+	buf = binary.BigEndian.AppendUint16(buf, uint16(len(wrapped)))
+	buf = append(buf, wrapped...)
+	buf = append(buf, sealed...)
+	return envelopePrefix + base64.StdEncoding.EncodeToString(buf)
+}
+
+func decodeEnvelope(ciphertext string) (keyID string, wrapped, sealed []byte, err error) {
+	if !strings.HasPrefix(ciphertext, envelopePrefix) {
+		return "", nil, nil, errInvalidEnvelope
+	}
+	buf, err := base64.StdEncoding.DecodeString(ciphertext[len(envelopePrefix):])
+	if err != nil {
+		return "", nil, nil, err
+	}
+	next := func() ([]byte, bool) {
+		if len(buf) < 2 {
+			return nil, false
+		}
+		n := int(binary.BigEndian.Uint16(buf))
+		if len(buf) < 2+n {
+			return nil, false
+		}
+		field := buf[2 : 2+n]
+		buf = buf[2+n:]
+		return field, true
+	}
+	kid, ok := next()
+	if !ok {
+		return "", nil, nil, errInvalidEnvelope
+	}
+	if wrapped, ok = next(); !ok {
+		return "", nil, nil, errInvalidEnvelope
+	}
+	return string(kid), wrapped, buf, nil
+}
@@ -0,0 +1,116 @@
+package encrypt
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	defaultKeyring atomic.Pointer[Keyring]
+	blindIndexKey  atomic.Pointer[[]byte]
+
+	errNoKeyring       = errors.New("encrypt: default keyring is not set, see SetDefaultKeyring")
+	errNoBlindIndexKey = errors.New("encrypt: blind index key is not set, see SetBlindIndexKey")
+)
+
+// SetDefaultKeyring sets the keyring used by String and JSON columns, call it at startup.
+func SetDefaultKeyring(k *Keyring) { defaultKeyring.Store(k) }
+
+// SetBlindIndexKey sets the key of BlindIndex. It must be different from the encryption keys
+// and can't be rotated without recomputing all indexes.
+func SetBlindIndexKey(key []byte) { blindIndexKey.Store(&key) }
+
+// String is a GORM column stored encrypted with the default keyring. Values without a version
+// prefix are read as legacy plaintext.
+type String string
+
+func (s String) Value() (driver.Value, error) {
+	return encryptValue(string(s))
+}
+
+func (s *String) Scan(src any) error {
+	plain, err := decryptValue(src)
+	*s = String(plain)
+	return err
+}
+
+func (s String) String() string { return string(s) }
+
+func (String) GormDataType() string { return "text" }
+
+// JSON is a GORM column storing Data as encrypted JSON, it marshals to JSON as Data.
+type JSON[T any] struct {
+	Data T
+}
+
+func (j JSON[T]) Value() (driver.Value, error) {
+	data, err := json.Marshal(j.Data)
+	if err != nil {
+		return nil, err
+	}
+	return encryptValue(string(data))
+}
+
+func (j *JSON[T]) Scan(src any) error {
+	plain, err := decryptValue(src)
+	if err != nil || plain == "" {
+		return err
+	}
+	return json.Unmarshal([]byte(plain), &j.Data)
+}
+
+func (j JSON[T]) MarshalJSON() ([]byte, error) { return json.Marshal(j.Data) }
+
+func (j *JSON[T]) UnmarshalJSON(data []byte) error { return json.Unmarshal(data, &j.Data) }
+
+func (JSON[T]) GormDataType() string { return "text" }
+
+// BlindIndex returns a keyed hash of value for equality lookups on encrypted columns, store it
+// in a companion indexed column on write and query it with the index of the searched value.
+// Value is trimmed and lower cased, e.g. for emails.
+func BlindIndex(value string) (string, error) {
+	key := blindIndexKey.Load()
+	if key == nil {
+		return "", errNoBlindIndexKey
+	}
+	mac := hmac.New(sha256.New, *key)
+	mac.Write([]byte(strings.ToLower(strings.TrimSpace(value))))
+	return hex.EncodeToString(mac.Sum(nil)), nil
+}
+
+func encryptValue(plain string) (driver.Value, error) {
+	k := defaultKeyring.Load()
+	if k == nil {
+		return nil, errNoKeyring
+	}
+	return k.Encrypt(plain)
+}
+
+func decryptValue(src any) (string, error) {
+	var value string
+	switch v := src.(type) {
+	case nil:
+		return "", nil
+	case string:
+		value = v
+	case []byte:
+		value = string(v)
+	default:
+		return "", fmt.Errorf("encrypt: can't scan %T", src)
+	}
+	k := defaultKeyring.Load()
+	if k == nil {
+		if strings.HasPrefix(value, versionPrefix) || strings.HasPrefix(value, keyringPrefix) {
+			return "", errNoKeyring
+		}
+		return value, nil
+	}
+	return k.Decrypt(value)
+}
@@ -0,0 +1,50 @@
+package encrypt
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestString_ValueScan(t *testing.T) {
+	keyring, _ := NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	SetDefaultKeyring(keyring)
+	defer SetDefaultKeyring(nil)
+
+	stored, err := String("sk-123").Value()
+	if err != nil || !strings.HasPrefix(stored.(string), "v2:k1:") {
+		t.Fatalf("unexpected stored value %v %v", stored, err)
+	}
+	var s String
+	if err := s.Scan([]byte(stored.(string))); err != nil || s != "sk-123" {
+		t.Errorf("scan: %q %v", s, err)
+	}
+	if err := s.Scan("legacy plaintext"); err != nil || s != "legacy plaintext" {
+		t.Errorf("legacy scan: %q %v", s, err)
+	}
+
+	type settings struct {
+		Token string `json:"token"`
+	}
+	stored, err = JSON[settings]{Data: settings{Token: "t"}}.Value()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var j JSON[settings]
+	if err := j.Scan(stored); err != nil || j.Data.Token != "t" {
+		t.Errorf("json scan: %+v %v", j, err)
+	}
+}
+
+func TestBlindIndex(t *testing.T) {
+	if _, err := BlindIndex("a@b.com"); err == nil {
+		t.Errorf("expected error without key")
+	}
+	SetBlindIndexKey([]byte("index-key"))
+	a, _ := BlindIndex("A@b.com ")
+	b, _ := BlindIndex("a@b.com")
+	c, _ := BlindIndex("c@b.com")
+	if a != b || a == c || len(a) != 64 {
+		t.Errorf("unexpected indexes %s %s %s", a, b, c)
+	}
+}
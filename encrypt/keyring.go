@@ -0,0 +1,264 @@
+package encrypt
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+)
+
+// v2 format: "v2:<kid>:" + base64(nonce || ciphertext || tag)
+const keyringPrefix = "v2:"
+
+var (
+	ErrUnknownKey = errors.New("encrypt: unknown key id")
+	errInvalidKID = errors.New("encrypt: key id must match [A-Za-z0-9_-]+")
+	validKID      = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+)
+
+// Keyring encrypts with the active key and decrypts with any known key, the key id is stored
+// in the value so keys can be rotated without re-encrypting everything at once.
+type Keyring struct {
+	keys    map[string]cipher.AEAD
+	rawKeys map[string][]byte // for deriving stream keys
+	active  string
+	legacy  cipher.AEAD
+}
+
+// NewKeyring creates a keyring from 32-byte keys by id, activeID is used to encrypt.
+func NewKeyring(activeID string, keys map[string][]byte) (*Keyring, error) {
+	if _, ok := keys[activeID]; !ok {
+		return nil, fmt.Errorf("%w: active key %q", ErrUnknownKey, activeID)
+	}
+	k := &Keyring{keys: make(map[string]cipher.AEAD, len(keys)), rawKeys: make(map[string][]byte, len(keys)), active: activeID}
+	for id, key := range keys {
+		if !validKID.MatchString(id) {
+			return nil, errInvalidKID
+		}
+		aead, err := newAEAD(key)
+		if err != nil {
+			return nil, err
+		}
+		k.keys[id], k.rawKeys[id] = aead, key
+	}
+	return k, nil
+}
+
+// ParseKeyring parses "kid:key,kid:key" where the first key is active and keys are in any
+// format accepted by DecodeKey, e.g. SECRETENCRYPTIONKEYS=k2:<base64>,k1:<base64>.
+func ParseKeyring(s string) (*Keyring, error) {
+	var active string
+	keys := map[string][]byte{}
+	for _, entry := range strings.Split(s, ",") {
+		id, encoded, ok := strings.Cut(strings.TrimSpace(entry), ":")
+		if !ok {
+			return nil, errors.New("encrypt: keyring entry must be kid:key")
+		}
+		key, err := DecodeKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt: key %q: %w", id, err)
+		}
+		if _, ok := keys[id]; ok {
+			return nil, fmt.Errorf("encrypt: duplicate key id %q", id)
+		}
+		if active == "" {
+			active = id
+		}
+		keys[id] = key
+	}
+	return NewKeyring(active, keys)
+}
+
+// WithLegacyKey allows decrypting "v1:" values of Encrypt, they are re-encrypted by Rotate.
+func (k *Keyring) WithLegacyKey(key []byte) (*Keyring, error) {
+	aead, err := newAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	k.legacy = aead
+	return k, nil
+}
+
+func (k *Keyring) ActiveKeyID() string { return k.active }
+
+// Encrypt encrypts plaintext with the active key, empty plaintext returns empty string.
+func (k *Keyring) Encrypt(plaintext string) (string, error) {
+	return k.EncryptWithAAD(plaintext, nil)
+}
+
+// EncryptWithAAD is Encrypt bound to additional data, see AAD.
+func (k *Keyring) EncryptWithAAD(plaintext string, aad []byte) (string, error) {
+	if plaintext == "" {
+		return "", nil
+	}
+	sealed, err := seal(k.keys[k.active], []byte(plaintext), aad)
+	if err != nil {
+		return "", err
+	}
+	return keyringPrefix + k.active + ":" + base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt decrypts values of Encrypt with the key named in them. "v1:" values need
+// WithLegacyKey and values without a version prefix are returned as-is like Decrypt.
+func (k *Keyring) Decrypt(ciphertext string) (string, error) {
+	return k.DecryptWithAAD(ciphertext, nil)
+}
+
+// DecryptWithAAD decrypts values of EncryptWithAAD, aad must match the one used to encrypt.
+func (k *Keyring) DecryptWithAAD(ciphertext string, aad []byte) (string, error) {
+	if ciphertext == "" {
+		return "", nil
+	}
+	if strings.HasPrefix(ciphertext, envelopePrefix) {
+		return "", errEnvelopeValue
+	}
+	if strings.HasPrefix(ciphertext, versionPrefix) {
+		if k.legacy == nil {
+			return "", fmt.Errorf("%w: v1", ErrUnknownKey)
+		}
+		return openEncoded(k.legacy, ciphertext[len(versionPrefix):], aad)
+	}
+	kid, encoded, ok := splitKeyID(ciphertext)
+	if !ok {
+		return ciphertext, nil
+	}
+	aead, found := k.keys[kid]
+	if !found {
+		return "", fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+	return openEncoded(aead, encoded, aad)
+}
+
+// NeedsRotation reports whether ciphertext isn't encrypted with the active key. Envelope
+// values never do, their data keys are rotated with Envelope.Rewrap.
+func (k *Keyring) NeedsRotation(ciphertext string) bool {
+	if ciphertext == "" || strings.HasPrefix(ciphertext, envelopePrefix) {
+		return false
+	}
+	kid, _, ok := splitKeyID(ciphertext)
+	return !ok || kid != k.active
+}
+
+// Reencrypt returns ciphertext encrypted with the active key, and whether it changed.
+// Legacy plaintext values are encrypted as well.
+func (k *Keyring) Reencrypt(ciphertext string) (string, bool, error) {
+	return k.ReencryptWithAAD(ciphertext, nil)
+}
+
+// ReencryptWithAAD is Reencrypt for values of EncryptWithAAD
+func (k *Keyring) ReencryptWithAAD(ciphertext string, aad []byte) (string, bool, error) {
+	if !k.NeedsRotation(ciphertext) {
+		return ciphertext, false, nil
+	}
+	plain, err := k.DecryptWithAAD(ciphertext, aad)
+	if err != nil {
+		return "", false, err
+	}
+	res, err := k.EncryptWithAAD(plain, aad)
+	return res, err == nil, err
+}
+
+// Ciphertext is a stored encrypted value identified by ID, see RotationSource. AAD is the
+// additional data the value was encrypted with, if any.
+type Ciphertext struct {
+	ID    int
+	Value string
+	AAD   []byte
+}
+
+// RotationSource reads and updates stored encrypted values for Keyring.Rotate, e.g. a column
+// of a table. Next returns up to limit values with ID greater than afterID ordered by ID.
+type RotationSource interface {
+	Next(ctx context.Context, afterID, limit int) ([]Ciphertext, error)
+	Update(ctx context.Context, id int, value string) error
+}
+
+type RotationStats struct {
+	Scanned int
+	Rotated int
+}
+
+// Rotate re-encrypts all values of src not encrypted with the active key in batches. It can
+// be re-run after a failure, already rotated values and envelope values are skipped.
+func (k *Keyring) Rotate(ctx context.Context, src RotationSource, batchSize int) (RotationStats, error) {
+	var stats RotationStats
+	if batchSize <= 0 {
+		batchSize = 100
+	}
+	for afterID := 0; ; {
+		batch, err := src.Next(ctx, afterID, batchSize)
+		if err != nil {
+			return stats, err
+		}
+		for _, c := range batch {
+			stats.Scanned++
+			value, changed, err := k.ReencryptWithAAD(c.Value, c.AAD)
+			if err != nil {
+				return stats, fmt.Errorf("encrypt: rotating id %d: %w", c.ID, err)
+			}
+			if changed {
+				if err := src.Update(ctx, c.ID, value); err != nil {
+					return stats, err
+				}
+				stats.Rotated++
+			}
+			afterID = c.ID
+		}
+		if len(batch) < batchSize {
+			return stats, nil
+		}
+	}
+}
+
+// splitKeyID splits "v2:<kid>:<data>"
+func splitKeyID(ciphertext string) (kid, encoded string, ok bool) {
+	if !strings.HasPrefix(ciphertext, keyringPrefix) {
+		return "", "", false
+	}
+	return strings.Cut(ciphertext[len(keyringPrefix):], ":")
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != aesKeySize {
+		return nil, errors.New("encrypt: key must be 32 bytes")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// seal returns nonce || ciphertext || tag
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce := make([]byte, nonceSize, nonceSize+len(plaintext)+gcmTagSize)
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, err
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(aead cipher.AEAD, combined, aad []byte) ([]byte, error) {
+	if len(combined) < nonceSize+gcmTagSize {
+		return nil, errors.New("encrypt: ciphertext too short")
+	}
+	return aead.Open(nil, combined[:nonceSize], combined[nonceSize:], aad)
+}
+
+func openEncoded(aead cipher.AEAD, encoded string, aad []byte) (string, error) {
+	combined, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return "", err
+	}
+	plain, err := open(aead, combined, aad)
+	if err != nil {
+		return "", err
+	}
+	return string(plain), nil
+}
@@ -0,0 +1,95 @@
+package encrypt
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+)
+
+type memorySource map[int]string
+
+func (s memorySource) Next(ctx context.Context, afterID, limit int) ([]Ciphertext, error) {
+	var res []Ciphertext
+	for id := afterID + 1; len(res) < limit && id <= len(s); id++ {
+		res = append(res, Ciphertext{ID: id, Value: s[id]})
+	}
+	return res, nil
+}
+
+func (s memorySource) Update(ctx context.Context, id int, value string) error {
+	s[id] = value
+	return nil
+}
+
+func TestKeyring_Rotate(t *testing.T) {
+	ctx := context.Background()
+	k1, k2 := bytes.Repeat([]byte{1}, 32), bytes.Repeat([]byte{2}, 32)
+	old, err := NewKeyring("k1", map[string][]byte{"k1": k1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	v1, _ := Encrypt("legacy", k1)
+	encrypted, _ := old.Encrypt("secret")
+	if !strings.HasPrefix(encrypted, "v2:k1:") {
+		t.Fatalf("unexpected ciphertext %s", encrypted)
+	}
+
+	keyring, err := NewKeyring("k2", map[string][]byte{"k1": k1, "k2": k2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyring.WithLegacyKey(k1)
+	if plain, err := keyring.Decrypt(encrypted); err != nil || plain != "secret" {
+		t.Errorf("decrypt with old key: %q %v", plain, err)
+	}
+
+	src := memorySource{1: encrypted, 2: v1, 3: "plaintext", 4: ""}
+	stats, err := keyring.Rotate(ctx, src, 3)
+	if err != nil || stats.Scanned != 4 || stats.Rotated != 3 {
+		t.Fatalf("unexpected rotation %+v %v", stats, err)
+	}
+	for id, want := range map[int]string{1: "secret", 2: "legacy", 3: "plaintext"} {
+		if !strings.HasPrefix(src[id], "v2:k2:") {
+			t.Errorf("id %d not rotated: %s", id, src[id])
+		}
+		if plain, _ := keyring.Decrypt(src[id]); plain != want {
+			t.Errorf("id %d: expected %q, got %q", id, want, plain)
+		}
+	}
+	if _, err := old.Decrypt(src[1]); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected unknown key error, got %v", err)
+	}
+}
+
+func TestEnvelope(t *testing.T) {
+	ctx := context.Background()
+	old, _ := NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	encrypted, err := NewEnvelope(NewLocalKeyWrapper(old)).Encrypt(ctx, "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := old.Decrypt(encrypted); err == nil {
+		t.Errorf("keyring must not decrypt envelope values")
+	}
+
+	keyring, _ := ParseKeyring("k2:" + strings.Repeat("b", 32) + ",k1:" + strings.Repeat("01", 32))
+	if stats, err := keyring.Rotate(ctx, memorySource{1: encrypted}, 10); err != nil || stats.Rotated != 0 {
+		t.Errorf("expected envelope values to be skipped by Rotate, got %+v %v", stats, err)
+	}
+	if _, err := ParseKeyring("k1:" + strings.Repeat("b", 32) + ",k1:" + strings.Repeat("01", 32)); err == nil {
+		t.Errorf("expected duplicate key ids to be rejected")
+	}
+	envelope := NewEnvelope(NewLocalKeyWrapper(keyring))
+	rewrapped, err := envelope.Rewrap(ctx, encrypted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewEnvelope(NewLocalKeyWrapper(old)).Decrypt(ctx, rewrapped); !errors.Is(err, ErrUnknownKey) {
+		t.Errorf("expected rewrapped value to need k2, got %v", err)
+	}
+	if plain, err := envelope.Decrypt(ctx, rewrapped); err != nil || plain != "secret" {
+		t.Errorf("decrypt: %q %v", plain, err)
+	}
+}
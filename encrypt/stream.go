@@ -0,0 +1,243 @@
+package encrypt
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Stream format: header of magic "ENCS", version byte, kid length byte, kid, 32 byte salt and
+// 4 byte chunk size, followed by chunks of AES-256-GCM sealed plaintext. Each stream uses a
+// key derived from the salt and chunks are sealed with nonce = counter || last flag, so chunks
+// can't be reordered, dropped or truncated without failing decryption.
+const (
+	streamMagic     = "ENCS"
+	streamVersion   = 1
+	streamSaltSize  = 32
+	streamChunkSize = 64 << 10
+	maxChunkSize    = 16 << 20
+)
+
+var (
+	errStreamHeader    = errors.New("encrypt: invalid stream header")
+	errStreamTruncated = errors.New("encrypt: stream truncated")
+	errStreamClosed    = errors.New("encrypt: stream writer closed")
+)
+
+type streamWriter struct {
+	w      io.Writer
+	aead   cipher.AEAD
+	aad    []byte
+	buf    []byte
+	chunk  []byte
+	n      uint64
+	closed bool
+}
+
+// NewEncryptWriter returns a writer encrypting to w with key, Close must be called to write
+// the final chunk. aad may be nil, e.g. AAD(objectKey) binds the stream to where it's stored.
+func NewEncryptWriter(w io.Writer, key, aad []byte) (io.WriteCloser, error) {
+	return newEncryptWriter(w, "", key, aad)
+}
+
+// NewEncryptWriter encrypts to w with the active key, see the package level NewEncryptWriter.
+func (k *Keyring) NewEncryptWriter(w io.Writer, aad []byte) (io.WriteCloser, error) {
+	return newEncryptWriter(w, k.active, k.rawKeys[k.active], aad)
+}
+
+func newEncryptWriter(w io.Writer, kid string, key, aad []byte) (io.WriteCloser, error) {
+	salt := make([]byte, streamSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return nil, err
+	}
+	aead, err := streamAEAD(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, 0, 6+len(kid)+streamSaltSize+4)
+	header = append(header, streamMagic...)
+	header = append(header, streamVersion, byte(len(kid)))
+	header = append(header, kid...)
+	header = append(header, salt...)
+	header = binary.BigEndian.AppendUint32(header, streamChunkSize)
+	if _, err := w.Write(header); err != nil {
+		return nil, err
+	}
+	return &streamWriter{
+		w:     w,
+		aead:  aead,
+		aad:   aad,
+		buf:   make([]byte, 0, streamChunkSize),
+		chunk: make([]byte, 0, streamChunkSize+gcmTagSize),
+	}, nil
+}
+
+func (s *streamWriter) Write(p []byte) (int, error) {
+	if s.closed {
+		return 0, errStreamClosed
+	}
+	written := 0
+	for len(p) > 0 {
+		// a full buffer is only flushed once more data arrives, the last chunk is written by Close
+		if len(s.buf) == streamChunkSize {
+			if err := s.flush(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(s.buf[len(s.buf):streamChunkSize], p)
+		s.buf = s.buf[:len(s.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+func (s *streamWriter) Close() error {
+	if s.closed {
+		return nil
+	}
+	s.closed = true
+	return s.flush(true)
+}
+
+func (s *streamWriter) flush(last bool) error {
+	s.chunk = s.aead.Seal(s.chunk[:0], chunkNonce(s.n, last), s.buf, s.aad)
+	s.buf = s.buf[:0]
+	s.n++
+	_, err := s.w.Write(s.chunk)
+	return err
+}
+
+type streamReader struct {
+	r       *bufio.Reader
+	aead    cipher.AEAD
+	aad     []byte
+	chunk   []byte
+	plain   []byte
+	n       uint64
+	done    bool
+	err     error
+	sealLen int
+}
+
+// NewDecryptReader returns a reader decrypting a stream of NewEncryptWriter, aad must match.
+// Data is only returned after its chunk is authenticated.
+func NewDecryptReader(r io.Reader, key, aad []byte) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	kid, salt, chunkSize, err := readStreamHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	if kid != "" {
+		return nil, fmt.Errorf("%w: stream uses keyring key %s", ErrUnknownKey, kid)
+	}
+	return newStreamReader(br, key, salt, chunkSize, aad)
+}
+
+// NewDecryptReader decrypts a stream of Keyring.NewEncryptWriter with the key named in it.
+func (k *Keyring) NewDecryptReader(r io.Reader, aad []byte) (io.Reader, error) {
+	br := bufio.NewReader(r)
+	kid, salt, chunkSize, err := readStreamHeader(br)
+	if err != nil {
+		return nil, err
+	}
+	key, ok := k.rawKeys[kid]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrUnknownKey, kid)
+	}
+	return newStreamReader(br, key, salt, chunkSize, aad)
+}
+
+func newStreamReader(r *bufio.Reader, key, salt []byte, chunkSize int, aad []byte) (io.Reader, error) {
+	aead, err := streamAEAD(key, salt)
+	if err != nil {
+		return nil, err
+	}
+	sealLen := chunkSize + gcmTagSize
+	return &streamReader{r: r, aead: aead, aad: aad, chunk: make([]byte, sealLen), sealLen: sealLen}, nil
+}
+
+func (s *streamReader) Read(p []byte) (int, error) {
+	for len(s.plain) == 0 {
+		if s.err != nil {
+			return 0, s.err
+		}
+		if s.done {
+			return 0, io.EOF
+		}
+		s.err = s.next()
+	}
+	n := copy(p, s.plain)
+	s.plain = s.plain[n:]
+	return n, nil
+}
+
+// next reads and opens a chunk, a chunk is the last one if no data follows it
+func (s *streamReader) next() error {
+	n, err := io.ReadFull(s.r, s.chunk)
+	if err == io.EOF || (err == io.ErrUnexpectedEOF && n < gcmTagSize) {
+		return errStreamTruncated
+	}
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return err
+	}
+	last := err == io.ErrUnexpectedEOF
+	if !last {
+		if _, err := s.r.Peek(1); err == io.EOF {
+			last = true
+		} else if err != nil {
+			return err
+		}
+	}
+	plain, err := s.aead.Open(s.chunk[:0], chunkNonce(s.n, last), s.chunk[:n], s.aad)
+	if err != nil {
+		return err
+	}
+	s.n++
+	s.plain, s.done = plain, last
+	return nil
+}
+
+func readStreamHeader(r *bufio.Reader) (kid string, salt []byte, chunkSize int, err error) {
+	prefix := make([]byte, len(streamMagic)+2)
+	if _, err := io.ReadFull(r, prefix); err != nil || string(prefix[:4]) != streamMagic || prefix[4] != streamVersion {
+		return "", nil, 0, errStreamHeader
+	}
+	rest := make([]byte, int(prefix[5])+streamSaltSize+4)
+	if _, err := io.ReadFull(r, rest); err != nil {
+		return "", nil, 0, errStreamHeader
+	}
+	kidLen := int(prefix[5])
+	chunkSize = int(binary.BigEndian.Uint32(rest[kidLen+streamSaltSize:]))
+	if chunkSize <= 0 || chunkSize > maxChunkSize {
+		return "", nil, 0, errStreamHeader
+	}
+	return string(rest[:kidLen]), rest[kidLen : kidLen+streamSaltSize], chunkSize, nil
+}
+
+func streamAEAD(key, salt []byte) (cipher.AEAD, error) {
+	if len(key) != aesKeySize {
+		return nil, errors.New("encrypt: key must be 32 bytes")
+	}
+	derived, err := hkdf.Key(sha256.New, key, salt, "encrypt stream", aesKeySize)
+	if err != nil {
+		return nil, err
+	}
+	return newAEAD(derived)
+}
+
+// chunkNonce is the 11 byte big endian chunk counter followed by 1 if it's the last chunk
+func chunkNonce(n uint64, last bool) []byte {
+	nonce := make([]byte, nonceSize)
+	binary.BigEndian.PutUint64(nonce[3:11], n)
+	if last {
+		nonce[11] = 1
+	}
+	return nonce
+}
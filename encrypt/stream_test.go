@@ -0,0 +1,60 @@
+package encrypt
+
+import (
+	"bytes"
+	"crypto/rand"
+	"io"
+	"testing"
+)
+
+func TestStream(t *testing.T) {
+	keyring, _ := NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	aad := AAD("docs/7/1_a.pdf")
+	for _, size := range []int{0, 10, streamChunkSize, 2*streamChunkSize + 5} {
+		plain := make([]byte, size)
+		rand.Read(plain)
+		var buf bytes.Buffer
+		w, err := keyring.NewEncryptWriter(&buf, aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write(plain)
+		w.Close()
+		encrypted := buf.Bytes()
+
+		r, err := keyring.NewDecryptReader(bytes.NewReader(encrypted), aad)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, err := io.ReadAll(r); err != nil || !bytes.Equal(got, plain) {
+			t.Errorf("size %d: roundtrip failed %v", size, err)
+		}
+
+		r, _ = keyring.NewDecryptReader(bytes.NewReader(encrypted), AAD("other"))
+		if _, err := io.ReadAll(r); err == nil {
+			t.Errorf("size %d: expected aad mismatch to fail", size)
+		}
+		if size > streamChunkSize {
+			// dropping the last chunk must be detected
+			truncated := encrypted[:len(encrypted)-5-gcmTagSize]
+			r, _ = keyring.NewDecryptReader(bytes.NewReader(truncated), aad)
+			if _, err := io.ReadAll(r); err == nil {
+				t.Errorf("size %d: expected truncated stream to fail", size)
+			}
+		}
+	}
+}
+
+func TestAAD(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+	encrypted, _ := EncryptWithAAD("secret", key, AAD("users", "api_key", "1"))
+	if plain, err := DecryptWithAAD(encrypted, key, AAD("users", "api_key", "1")); err != nil || plain != "secret" {
+		t.Errorf("decrypt: %q %v", plain, err)
+	}
+	if _, err := DecryptWithAAD(encrypted, key, AAD("users", "api_key", "2")); err == nil {
+		t.Errorf("expected value copied to another row to fail")
+	}
+	if bytes.Equal(AAD("ab", "c"), AAD("a", "bc")) {
+		t.Errorf("aad parts must be unambiguous")
+	}
+}
@@ -3,12 +3,17 @@ package main
 import (
 	"context"
 	"log"
+	"net/http"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/krsoninikhil/go-rest-kit/auth"
 	"github.com/krsoninikhil/go-rest-kit/cache"
 	"github.com/krsoninikhil/go-rest-kit/config"
 	"github.com/krsoninikhil/go-rest-kit/crud"
+	"github.com/krsoninikhil/go-rest-kit/health"
 	"github.com/krsoninikhil/go-rest-kit/integrations/twilio"
 	"github.com/krsoninikhil/go-rest-kit/request"
 	"github.com/krsoninikhil/go-rest-kit/sqldb"
@@ -20,7 +25,9 @@ func main() {
 		ctx  = context.Background()
 		conf Config
 	)
-	config.Load(ctx, &conf)
+	if err := config.Load(ctx, &conf); err != nil {
+		log.Fatal(err)
+	}
 
 	// connnections
 	var (
@@ -30,12 +37,15 @@ func main() {
 
 	// inject dependencies for auth service
 	var (
-		userDao        = auth.NewUserDao[User](db)
-		authSvc        = auth.NewService(conf.Auth, userDao)
-		smsProvider    = twilio.NewClient(conf.Auth.Twilio)
-		otpSvc         = auth.NewOTPSvc(conf.Auth.OTP, smsProvider, cache) //
-		authController = auth.NewController(authSvc, otpSvc, cache)
+		userDao     = auth.NewUserDao[User](db)
+		authSvc     = auth.NewService(conf.Auth, userDao)
+		smsProvider = twilio.NewClient(conf.Auth.Twilio)
 	)
+	otpSvc, err := auth.NewOTPSvc(conf.Auth.OTPConfig(), smsProvider, cache)
+	if err != nil {
+		log.Fatal(err)
+	}
+	authController := auth.NewController(authSvc, otpSvc, cache)
 
 	// inject dependencies
 	var (
@@ -58,6 +68,13 @@ func main() {
 
 	r := gin.Default()
 
+	// k8s probes, routed before the auth middleware
+	checks := health.NewRegistry(health.Config{})
+	checks.Register("db", health.DB(db))
+	checks.Register("cache", health.Cache(cache))
+	r.GET("/livez", checks.LivezHandler())
+	r.GET("/readyz", checks.ReadyzHandler())
+
 	r.POST("/auth/otp/send", request.BindCreate(authController.SendOTP))
 	r.POST("/auth/otp/verify", request.BindCreate(authController.VerifyOTP))
 	r.POST("/auth/token/refresh", request.BindCreate(authController.RefreshToken))
@@ -81,8 +98,10 @@ func main() {
 	r.GET("/business/:parentID/products", request.BindGet(productController.List))
 	r.PATCH("/business/:parentID/products/:id", request.BindUpdate(productController.Update))
 
-	// start server
-	if err := r.Run(":8080"); err != nil {
+	// start server, on SIGTERM readiness fails before in-flight requests are drained
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	if err := checks.ListenAndServe(ctx, &http.Server{Addr: ":8080", Handler: r}, 10*time.Second); err != nil {
 		log.Fatal("could not start server", err)
 	}
 }
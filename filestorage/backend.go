@@ -0,0 +1,49 @@
+package filestorage
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+// Backend stores objects by key. Get and Stat return apperrors.NotFoundError for missing keys.
+type Backend interface {
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	Get(ctx context.Context, key string) (*Object, error)
+	Stat(ctx context.Context, key string) (*ObjectInfo, error)
+	Delete(ctx context.Context, key string) error
+	// Presign returns a URL to GET the object that's valid for expiry
+	Presign(ctx context.Context, key string, expiry time.Duration) (string, error)
+	// PublicURL returns the permanent URL of a public object, see Controller.Upload
+	PublicURL(key string) string
+}
+
+// ObjectInfo is the metadata of a stored object
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// Object is a stored object, caller must close Body
+type Object struct {
+	ObjectInfo
+	Body io.ReadCloser
+}
+
+// DirectUploader is implemented by backends that accept uploads directly from clients,
+// see Controller.PresignUpload.
+type DirectUploader interface {
+	// PresignPut returns a URL to PUT the object with the given Content-Type header
+	PresignPut(ctx context.Context, key, contentType string, expiry time.Duration) (string, error)
+	// PresignPost returns a browser form upload limited to contentType and maxBytes
+	PresignPost(ctx context.Context, key, contentType string, maxBytes int64, expiry time.Duration) (*PresignedPost, error)
+}
+
+// PresignedPost is a form upload, Fields must be sent before the "file" field.
+type PresignedPost struct {
+	URL    string            `json:"url"`
+	Fields map[string]string `json:"fields"`
+}
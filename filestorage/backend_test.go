@@ -0,0 +1,205 @@
+package filestorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+	"github.com/krsoninikhil/go-rest-kit/auth"
+)
+
+func TestLocalBackend(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	backend, err := NewLocalBackend(LocalConfig{Root: t.TempDir(), BaseURL: "http://localhost/files", SigningKey: "secret"})
+	if err != nil {
+		t.Fatalf("new local backend: %v", err)
+	}
+	router := gin.New()
+	router.GET("/files/*key", backend.Handler())
+	get := func(rawURL string) *httptest.ResponseRecorder {
+		u, _ := url.Parse(rawURL)
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, u.RequestURI(), nil))
+		return w
+	}
+
+	if err := backend.Put(ctx, "docs/1/a b.txt", strings.NewReader("hello"), "text/plain"); err != nil {
+		t.Fatalf("put: %v", err)
+	}
+	info, err := backend.Stat(ctx, "docs/1/a b.txt")
+	if err != nil || info.Size != 5 || info.ContentType != "text/plain" {
+		t.Fatalf("unexpected stat %+v err=%v", info, err)
+	}
+
+	signed, err := backend.Presign(ctx, "docs/1/a b.txt", time.Minute)
+	if err != nil {
+		t.Fatalf("presign: %v", err)
+	}
+	if w := get(signed); w.Code != http.StatusOK || w.Body.String() != "hello" || w.Header().Get("Content-Type") != "text/plain" {
+		t.Errorf("signed get: unexpected response %d %q", w.Code, w.Body.String())
+	}
+	if w := get(strings.Replace(signed, "a%20b.txt", "b.txt", 1)); w.Code != http.StatusForbidden {
+		t.Errorf("signature of other key: expected 403, got %d", w.Code)
+	}
+	if w := get(backend.PublicURL("docs/1/a b.txt")); w.Code != http.StatusForbidden {
+		t.Errorf("private key without signature: expected 403, got %d", w.Code)
+	}
+	expired, _ := backend.Presign(ctx, "docs/1/a b.txt", -time.Minute)
+	if w := get(expired); w.Code != http.StatusForbidden {
+		t.Errorf("expired signature: expected 403, got %d", w.Code)
+	}
+
+	backend.Put(ctx, "public/logo.png", strings.NewReader("png"), "image/png")
+	if w := get(backend.PublicURL("public/logo.png")); w.Code != http.StatusOK || w.Body.String() != "png" {
+		t.Errorf("public get: unexpected response %d", w.Code)
+	}
+
+	for _, key := range []string{"../etc/passwd", "docs/../../x", ".meta/docs/1/a b.txt.json", ""} {
+		if err := backend.Put(ctx, key, strings.NewReader("x"), ""); err == nil {
+			t.Errorf("expected invalid key error for %q", key)
+		}
+	}
+
+	if err := backend.Delete(ctx, "docs/1/a b.txt"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	var notFound apperrors.NotFoundError
+	if _, err := backend.Get(ctx, "docs/1/a b.txt"); !errors.As(err, &notFound) {
+		t.Errorf("expected not found after delete, got %v", err)
+	}
+}
+
+func TestController_UploadWithMemoryBackend(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	backend := NewMemoryBackend()
+	progress := map[string]int64{}
+	controller := NewControllerWithBackend(backend, &ControllerOpts{
+		AllowedPrefixes:  []string{"public/profiles", "docs"},
+		MaxBytesByPrefix: map[string]int64{"docs": 4},
+		OnProgress:       func(key string, read int64) { progress[key] = read },
+	})
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(auth.CtxKeyUserID, 7) })
+	router.POST("/upload", controller.Upload)
+
+	upload := func(prefix, content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "my photo.png")
+		io.WriteString(fw, content)
+		mw.Close()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/upload?prefix="+prefix, &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	w := upload("public/profiles", "png")
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload: expected 201, got %d %s", w.Code, w.Body.String())
+	}
+	var res UploadResponse
+	json.Unmarshal(w.Body.Bytes(), &res)
+	if !strings.HasPrefix(res.Path, "public/profiles/7/") || !strings.HasSuffix(res.Path, "_my_photo.png") ||
+		res.URL != backend.PublicURL(res.Path) {
+		t.Errorf("unexpected response %+v", res)
+	}
+	if progress[res.Path] != 3 {
+		t.Errorf("expected progress of 3 bytes, got %v", progress)
+	}
+
+	if w := upload("docs", "too large"); w.Code != http.StatusBadRequest {
+		t.Errorf("over limit: expected 400, got %d", w.Code)
+	}
+	if keys := backend.Keys(); len(keys) != 1 || keys[0] != res.Path {
+		t.Errorf("over limit upload must not be stored, keys %v", keys)
+	}
+	if w := upload("other", "x"); w.Code != http.StatusBadRequest {
+		t.Errorf("prefix not allowed: expected 400, got %d", w.Code)
+	}
+}
+
+type memoryRegistry map[string]File
+
+func (r memoryRegistry) Record(ctx context.Context, f File) error { r[f.Key] = f; return nil }
+
+func (r memoryRegistry) GetByKey(ctx context.Context, key string) (*File, error) {
+	if f, ok := r[key]; ok {
+		return &f, nil
+	}
+	return nil, apperrors.NewNotFoundError("file")
+}
+
+func TestController_SignedURLOwnership(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	backend := NewMemoryBackend()
+	registry := memoryRegistry{}
+	shared := "docs/9/1_shared.pdf"
+	controller := NewControllerWithBackend(backend, &ControllerOpts{
+		CanAccess: func(ctx context.Context, userID int, key string) bool { return key == shared },
+	})
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(auth.CtxKeyUserID, 7) })
+	router.GET("/signed", controller.SignedURL)
+	sign := func(path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/signed?path="+url.QueryEscape(path), nil))
+		return w.Code
+	}
+
+	// without a registry the owner is read from the key
+	for path, want := range map[string]int{
+		"docs/7/1_mine.pdf":   http.StatusOK,
+		"docs/9/1_other.pdf":  http.StatusNotFound,
+		"public/9/1_logo.png": http.StatusOK,
+		shared:                http.StatusOK,
+	} {
+		if code := sign(path); code != want {
+			t.Errorf("%s: expected %d, got %d", path, want, code)
+		}
+	}
+
+	controller.WithFileRegistry(registry)
+	registry.Record(ctx, newFile("docs/9/2_moved.pdf", 7, ObjectInfo{Size: 1}))
+	for path, want := range map[string]int{
+		"docs/9/2_moved.pdf": http.StatusOK,
+		"docs/7/1_mine.pdf":  http.StatusNotFound, // not registered
+		shared:               http.StatusOK,
+	} {
+		if code := sign(path); code != want {
+			t.Errorf("registry %s: expected %d, got %d", path, want, code)
+		}
+	}
+
+	router.POST("/upload", controller.Upload)
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "a.txt")
+	io.WriteString(fw, "abc")
+	mw.Close()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/upload?prefix=docs", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	router.ServeHTTP(w, req)
+	var res UploadResponse
+	json.Unmarshal(w.Body.Bytes(), &res)
+	f, ok := registry[res.Path]
+	if !ok || f.OwnerID != 7 || f.Size != 3 || f.Prefix != "docs" || f.Visibility != VisibilityPrivate ||
+		f.Checksum != "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" {
+		t.Errorf("unexpected recorded file %+v", f)
+	}
+}
@@ -0,0 +1,128 @@
+package filestorage
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	defaultClamdChunkSize = 64 << 10
+	defaultClamdTimeout   = time.Minute
+)
+
+// ClamdConfig configures the clamd scanner, Network is "tcp" (default) or "unix".
+// ChunkSize must not exceed StreamMaxLength of clamd.
+type ClamdConfig struct {
+	Network   string
+	Address   string
+	Timeout   time.Duration // default 1m
+	ChunkSize int           // default 64KB
+}
+
+// ClamdScanner scans with clamd, a connection is opened per scan
+type ClamdScanner struct {
+	config ClamdConfig
+}
+
+// NewClamdScanner returns a Scanner streaming to clamd with the INSTREAM command
+func NewClamdScanner(config ClamdConfig) *ClamdScanner {
+	if config.Network == "" {
+		config.Network = "tcp"
+	}
+	if config.Timeout == 0 {
+		config.Timeout = defaultClamdTimeout
+	}
+	if config.ChunkSize <= 0 {
+		config.ChunkSize = defaultClamdChunkSize
+	}
+	return &ClamdScanner{config: config}
+}
+
+func (s *ClamdScanner) Scan(ctx context.Context, r io.Reader) (*ScanResult, error) {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+
+	if _, err := conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return nil, fmt.Errorf("clamd: error sending command: %w", err)
+	}
+	buf := make([]byte, 4+s.config.ChunkSize)
+	for {
+		n, err := io.ReadFull(r, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, err := conn.Write(buf[:4+n]); err != nil {
+				return nil, fmt.Errorf("clamd: error streaming: %w", err)
+			}
+		}
+		if err == io.EOF || err == io.ErrUnexpectedEOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	if _, err := conn.Write([]byte{0, 0, 0, 0}); err != nil {
+		return nil, fmt.Errorf("clamd: error ending stream: %w", err)
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if err != nil && !(err == io.EOF && reply != "") {
+		return nil, fmt.Errorf("clamd: error reading reply: %w", err)
+	}
+	return parseClamdReply(strings.TrimRight(reply, "\x00\n"))
+}
+
+// Ping checks that clamd is reachable
+func (s *ClamdScanner) Ping(ctx context.Context) error {
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	if _, err := conn.Write([]byte("zPING\x00")); err != nil {
+		return fmt.Errorf("clamd: %w", err)
+	}
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if strings.TrimRight(reply, "\x00") != "PONG" {
+		return fmt.Errorf("clamd: unexpected ping reply %q: %v", reply, err)
+	}
+	return nil
+}
+
+func (s *ClamdScanner) dial(ctx context.Context) (net.Conn, error) {
+	dialer := net.Dialer{Timeout: s.config.Timeout}
+	conn, err := dialer.DialContext(ctx, s.config.Network, s.config.Address)
+	if err != nil {
+		return nil, fmt.Errorf("clamd: error connecting: %w", err)
+	}
+	deadline := time.Now().Add(s.config.Timeout)
+	if d, ok := ctx.Deadline(); ok && d.Before(deadline) {
+		deadline = d
+	}
+	conn.SetDeadline(deadline)
+	return conn, nil
+}
+
+var errClamd = errors.New("clamd error")
+
+// parseClamdReply parses "stream: OK" or "stream: <signature> FOUND"
+func parseClamdReply(reply string) (*ScanResult, error) {
+	result := strings.TrimPrefix(reply, "stream: ")
+	switch {
+	case result == "OK":
+		return &ScanResult{}, nil
+	case strings.HasSuffix(result, " FOUND"):
+		return &ScanResult{Infected: true, Signature: strings.TrimSuffix(result, " FOUND")}, nil
+	}
+	return nil, fmt.Errorf("%w: %s", errClamd, reply)
+}
@@ -0,0 +1,134 @@
+package filestorage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krsoninikhil/go-rest-kit/auth"
+)
+
+const eicar = `X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`
+
+// fakeClamd serves INSTREAM and PING, streams containing the EICAR string are infected
+func fakeClamd(t *testing.T) string {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				cmd, _ := r.ReadString(0)
+				if cmd == "zPING\x00" {
+					conn.Write([]byte("PONG\x00"))
+					return
+				}
+				var data []byte
+				for {
+					var size uint32
+					if binary.Read(r, binary.BigEndian, &size) != nil {
+						return
+					}
+					if size == 0 {
+						break
+					}
+					chunk := make([]byte, size)
+					io.ReadFull(r, chunk)
+					data = append(data, chunk...)
+				}
+				if bytes.Contains(data, []byte(eicar)) {
+					conn.Write([]byte("stream: Eicar-Test-Signature FOUND\x00"))
+				} else {
+					conn.Write([]byte("stream: OK\x00"))
+				}
+			}()
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func TestClamdScanner(t *testing.T) {
+	ctx := context.Background()
+	scanner := NewClamdScanner(ClamdConfig{Address: fakeClamd(t), ChunkSize: 16})
+	if err := scanner.Ping(ctx); err != nil {
+		t.Fatalf("ping: %v", err)
+	}
+	res, err := scanner.Scan(ctx, strings.NewReader(strings.Repeat("clean ", 10)))
+	if err != nil || res.Infected {
+		t.Errorf("expected clean, got %+v %v", res, err)
+	}
+	res, err = scanner.Scan(ctx, strings.NewReader("prefix "+eicar))
+	if err != nil || !res.Infected || res.Signature != "Eicar-Test-Signature" {
+		t.Errorf("expected infected, got %+v %v", res, err)
+	}
+	if _, err := parseClamdReply("INSTREAM size limit exceeded. ERROR"); err == nil {
+		t.Errorf("expected error reply to fail")
+	}
+}
+
+func TestController_UploadScanned(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	backend := NewMemoryBackend()
+	controller := NewControllerWithBackend(backend, &ControllerOpts{
+		Scanner: NewClamdScanner(ClamdConfig{Address: fakeClamd(t)}),
+	})
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(auth.CtxKeyUserID, 7) })
+	router.POST("/upload", controller.Upload)
+	upload := func(content string) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "a.txt")
+		io.WriteString(fw, content)
+		mw.Close()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/upload?prefix=docs", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := upload("hello"); w.Code != http.StatusCreated || !strings.Contains(w.Body.String(), `"scan_status":"clean"`) {
+		t.Errorf("clean upload: unexpected response %d %s", w.Code, w.Body.String())
+	}
+	if w := upload(eicar); w.Code != http.StatusBadRequest {
+		t.Errorf("infected upload: expected 400, got %d", w.Code)
+	}
+	var quarantined, stored int
+	for _, key := range backend.Keys() {
+		if strings.HasPrefix(key, "quarantine/docs/7/") {
+			quarantined++
+		} else {
+			stored++
+		}
+	}
+	if quarantined != 1 || stored != 1 {
+		t.Errorf("expected infected upload to be quarantined, keys %v", backend.Keys())
+	}
+
+	router.GET("/signed", controller.SignedURL)
+	for _, key := range backend.Keys() {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/signed?path="+key, nil))
+		if strings.HasPrefix(key, "quarantine/") && w.Code != http.StatusNotFound {
+			t.Errorf("quarantined %s: expected 404, got %d", key, w.Code)
+		}
+	}
+}
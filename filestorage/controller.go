@@ -1,11 +1,13 @@
 package filestorage
 
 import (
-	"bytes"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"io"
+	"log"
 	"path/filepath"
 	"regexp"
 	"strconv"
@@ -25,14 +27,32 @@ import (
 type ControllerOpts struct {
 	AllowedPrefixes  []string
 	MaxBytesByPrefix map[string]int64
+	// AllowedTypesByPrefix limits the sniffed content types of a prefix, e.g. "image/*"
+	AllowedTypesByPrefix map[string][]string
+	// Processors run on every upload before it's stored, see NewImageProcessor
+	Processors []Processor
+	// Scanner scans uploads while they are stored, infected objects are moved under
+	// QuarantinePrefix (default "quarantine"). With ScanAsync the upload is scanned after
+	// the response and the scan status is recorded in the file registry.
+	Scanner          Scanner
+	ScanAsync        bool
+	QuarantinePrefix string
+	// OnProgress is called as the upload of key is read from the request
+	OnProgress func(key string, bytesRead int64)
+	// CanAccess allows userID to get a signed url for another user's key, e.g. for shared
+	// files. By default users can only sign their own and public keys.
+	CanAccess func(ctx context.Context, userID int, key string) bool
 }
 
 var errFileHeaderRequired = errors.New("file header is required")
 
 // UploadResponse is the JSON response for a successful upload.
+// URLs are omitted while the scan is pending, see ControllerOpts.ScanAsync.
 type UploadResponse struct {
-	Path string `json:"path"`
-	URL  string `json:"url,omitempty"`
+	Path       string            `json:"path"`
+	URL        string            `json:"url,omitempty"`
+	Variants   map[string]string `json:"variants,omitempty"` // variant name to url
+	ScanStatus string            `json:"scan_status,omitempty"`
 }
 
 // SignedURLResponse is the JSON response for a signed URL.
@@ -40,21 +60,51 @@ type SignedURLResponse struct {
 	URL string `json:"url"`
 }
 
-// Controller exposes Gin handlers for upload and signed URL using AWS S3.
+// Controller exposes Gin handlers for upload and signed URL using a storage Backend.
 type Controller struct {
-	s3               *aws.S3
-	allowedPrefixes  map[string]struct{} // nil = no validation
-	maxBytesByPrefix map[string]int64
+	backend              Backend
+	allowedPrefixes      map[string]struct{} // nil = no validation
+	maxBytesByPrefix     map[string]int64
+	allowedTypesByPrefix map[string][]string
+	processors           []Processor
+	scanner              Scanner
+	scanAsync            bool
+	quarantinePrefix     string
+	onProgress           func(key string, bytesRead int64)
+	canAccess            func(ctx context.Context, userID int, key string) bool
+	uploadStore          UploadStore
+	registry             FileRegistry
 }
 
-// NewController creates a filestorage controller from AWS config.
+// NewController creates a filestorage controller backed by S3 from AWS config.
 // If opts is non-nil and AllowedPrefixes is set, only those prefixes are accepted on Upload.
 func NewController(cfg aws.Config, opts *ControllerOpts) (*Controller, error) {
 	s3Client, err := aws.NewS3(context.Background(), cfg)
 	if err != nil {
 		return nil, err
 	}
-	c := &Controller{s3: s3Client}
+	return NewControllerWithBackend(NewS3Backend(s3Client), opts), nil
+}
+
+// NewControllerWithS3 creates a controller with an existing S3 client.
+func NewControllerWithS3(s3 *aws.S3) *Controller {
+	return NewControllerWithBackend(NewS3Backend(s3), nil)
+}
+
+// NewControllerWithBackend creates a controller storing files in backend, e.g. NewLocalBackend
+// for local development or NewMemoryBackend for tests.
+func NewControllerWithBackend(backend Backend, opts *ControllerOpts) *Controller {
+	c := &Controller{backend: backend, quarantinePrefix: defaultQuarantinePrefix}
+	if opts != nil {
+		c.onProgress = opts.OnProgress
+		c.canAccess = opts.CanAccess
+		c.allowedTypesByPrefix = opts.AllowedTypesByPrefix
+		c.processors = opts.Processors
+		c.scanner, c.scanAsync = opts.Scanner, opts.ScanAsync
+		if opts.QuarantinePrefix != "" {
+			c.quarantinePrefix = opts.QuarantinePrefix
+		}
+	}
 	if opts != nil && len(opts.AllowedPrefixes) > 0 {
 		c.allowedPrefixes = make(map[string]struct{})
 		for _, p := range opts.AllowedPrefixes {
@@ -69,91 +119,125 @@ func NewController(cfg aws.Config, opts *ControllerOpts) (*Controller, error) {
 			}
 		}
 	}
-	return c, nil
+	return c
 }
 
-// NewControllerWithS3 creates a controller with an existing S3 client (e.g. for testing).
-func NewControllerWithS3(s3 *aws.S3) *Controller {
-	return &Controller{s3: s3}
+// put stores body of the upload, scanning it inline if a scanner is configured without ScanAsync
+func (c *Controller) put(ctx context.Context, file *UploadFile, body io.Reader) (*ScanResult, error) {
+	if c.scanner != nil && !c.scanAsync {
+		return c.putScanned(ctx, file, body)
+	}
+	sealed := file.sealed(body)
+	defer sealed.Close()
+	return nil, c.backend.Put(ctx, file.Key, sealed, file.ContentType)
+}
+
+func (c *Controller) scanStatus(scan *ScanResult) string {
+	switch {
+	case scan != nil:
+		return scan.Status()
+	case c.scanner != nil:
+		return ScanPending
+	}
+	return ""
 }
 
 // Upload handles POST multipart form: file "file", query "prefix" (required).
 // Public access is derived from prefix: prefixes starting with "public/" are treated as public (return permanent URL); others get a presigned URL.
+// The file is streamed to the backend without buffering it whole, size limits are enforced while streaming.
 func (c *Controller) Upload(ctx *gin.Context) {
 	userID := auth.UserID(ctx)
 	if userID == 0 {
 		request.Respond(ctx, nil, apperrors.NewPermissionError("user_id"))
 		return
 	}
-	file, header, err := ctx.Request.FormFile("file")
-	if err != nil {
-		request.Respond(ctx, nil, apperrors.NewInvalidParamsError("filestorage", err))
+
+	prefix := ctx.Query("prefix")
+
+	if err := c.checkPrefix(prefix); err != nil {
+		request.Respond(ctx, nil, err)
 		return
 	}
-	defer file.Close()
-	if header == nil {
-		request.Respond(ctx, nil, apperrors.NewInvalidParamsError("filestorage", errFileHeaderRequired))
+
+	part, err := filePart(ctx.Request)
+	if err != nil {
+		request.Respond(ctx, nil, apperrors.NewInvalidParamsError("filestorage", err))
 		return
 	}
+	defer part.Close()
 
-	prefix := ctx.Query("prefix")
-	public := strings.HasPrefix(prefix, "public/")
-
-	if c.allowedPrefixes != nil {
-		if _, ok := c.allowedPrefixes[prefix]; !ok {
-			request.Respond(ctx, nil, apperrors.NewInvalidParamsError("filestorage", errors.New("prefix not allowed")))
-			return
-		}
+	key := buildKey(part.FileName(), prefix, userID)
+	maxBytes := c.maxBytesByPrefix[prefix]
+	body := &uploadReader{r: part, maxBytes: maxBytes}
+	if c.onProgress != nil {
+		body.onProgress = func(read int64) { c.onProgress(key, read) }
 	}
 
-	key := buildKey(header.Filename, prefix, userID)
-	maxBytes, hasLimit := c.maxBytesByPrefix[prefix]
-	if hasLimit && isOverMaxBytes(maxBytes, header.Size, 0) {
-		request.Respond(ctx, nil, apperrors.NewInvalidParamsError("filestorage", fileTooLargeErr(prefix, maxBytes)))
+	file := &UploadFile{Key: key, Prefix: prefix, Body: body}
+	if err := c.process(ctx, file, part.Header.Get("Content-Type")); err != nil {
+		if body.tooLarge {
+			err = apperrors.NewInvalidParamsError("filestorage", fileTooLargeErr(prefix, maxBytes))
+		}
+		request.Respond(ctx, nil, err)
 		return
 	}
-
-	var body []byte
-	if hasLimit && maxBytes > 0 {
-		limited := io.LimitReader(file, maxBytes+1)
-		body, err = io.ReadAll(limited)
-		if err != nil {
-			request.Respond(ctx, nil, apperrors.NewServerError(err))
-			return
+	stored := &checksumWriter{hash: sha256.New()}
+	scan, err := c.put(ctx.Request.Context(), file, io.TeeReader(file.Body, stored))
+	if closer, ok := file.Body.(io.Closer); ok {
+		closer.Close() // stops processors writing to a pipe the backend didn't read fully
+	}
+	if err != nil {
+		if body.tooLarge {
+			err = apperrors.NewInvalidParamsError("filestorage", fileTooLargeErr(prefix, maxBytes))
 		}
-		if isOverMaxBytes(maxBytes, 0, int64(len(body))) {
-			request.Respond(ctx, nil, apperrors.NewInvalidParamsError("filestorage", fileTooLargeErr(prefix, maxBytes)))
-			return
+		request.Respond(ctx, nil, err)
+		return
+	}
+	f := newFile(key, userID, ObjectInfo{Size: stored.n, ContentType: file.ContentType})
+	f.Checksum = hex.EncodeToString(stored.hash.Sum(nil))
+	if scan != nil && scan.Infected {
+		f.ScanStatus, f.ScanSignature = ScanInfected, scan.Signature
+		if c.registry != nil {
+			if err := c.registry.Record(ctx, f); err != nil {
+				log.Printf("filestorage: error recording infected upload key=%s err=%v", key, err)
+			}
 		}
-	} else {
-		body, err = io.ReadAll(file)
-		if err != nil {
-			request.Respond(ctx, nil, apperrors.NewServerError(err))
+		request.Respond(ctx, nil, apperrors.NewInvalidParamsError("filestorage", fmt.Errorf("%w: %s", errInfected, scan.Signature)))
+		return
+	}
+	variants, err := c.putVariants(ctx, key, file.Variants)
+	if err != nil {
+		c.backend.Delete(ctx, key)
+		request.Respond(ctx, nil, err)
+		return
+	}
+	if c.registry != nil {
+		f.Variants = variants
+		f.ScanStatus = c.scanStatus(scan)
+		if err := c.registry.Record(ctx, f); err != nil {
+			c.backend.Delete(ctx, key)
+			c.deleteKeys(ctx, variants)
+			request.Respond(ctx, nil, err)
 			return
 		}
 	}
-	contentType := header.Header.Get("Content-Type")
-	if contentType == "" {
-		contentType = "application/octet-stream"
-	}
-	if err := c.s3.PutObject(ctx.Request.Context(), key, bytes.NewReader(body), contentType, public); err != nil {
-		request.Respond(ctx, nil, err)
+	if c.scanner != nil && c.scanAsync {
+		c.scanInBackground(key, variants, file.unseal)
+		request.Respond(ctx, &UploadResponse{Path: key, ScanStatus: ScanPending}, nil)
 		return
 	}
 
-	var url string
-	if public {
-		url = c.s3.PublicURL(key)
-	} else {
-		signed, err := c.s3.PresignGet(ctx.Request.Context(), key, time.Hour)
-		if err == nil {
-			url = signed
-		}
+	res := c.uploadResponse(ctx, key)
+	res.ScanStatus = c.scanStatus(scan)
+	res.Variants = make(map[string]string, len(variants))
+	for name, vkey := range variants {
+		res.Variants[name] = c.uploadResponse(ctx, vkey).URL
 	}
-	request.Respond(ctx, &UploadResponse{Path: key, URL: url}, nil)
+	request.Respond(ctx, res, nil)
 }
 
 // SignedURL handles GET query path= and optional expiry= (duration string, e.g. "1h").
+// The path must be public, owned by the user or allowed by ControllerOpts.CanAccess.
 func (c *Controller) SignedURL(ctx *gin.Context) {
 	userID := auth.UserID(ctx)
 	if userID == 0 {
@@ -175,7 +259,11 @@ func (c *Controller) SignedURL(ctx *gin.Context) {
 	if expiry > 7*24*time.Hour {
 		expiry = 7 * 24 * time.Hour
 	}
-	signed, err := c.s3.PresignGet(ctx.Request.Context(), path, expiry)
+	if err := c.authorize(ctx, userID, path); err != nil {
+		request.Respond(ctx, nil, err)
+		return
+	}
+	signed, err := c.backend.Presign(ctx.Request.Context(), path, expiry)
 	if err != nil {
 		request.Respond(ctx, nil, err)
 		return
@@ -183,6 +271,53 @@ func (c *Controller) SignedURL(ctx *gin.Context) {
 	request.Respond(ctx, &SignedURLResponse{URL: signed}, nil)
 }
 
+// WithFileRegistry records uploads in registry, ownership of keys is then checked against it.
+func (c *Controller) WithFileRegistry(registry FileRegistry) *Controller {
+	c.registry = registry
+	return c
+}
+
+// authorize returns not found for keys the user can't access to not reveal their existence
+func (c *Controller) authorize(ctx context.Context, userID int, key string) error {
+	if strings.HasPrefix(key, c.quarantinePrefix+"/") {
+		return apperrors.NewNotFoundError("file")
+	}
+	if c.canAccess != nil && c.canAccess(ctx, userID, key) {
+		return nil
+	}
+	if c.registry == nil {
+		if isPublicKey(key) || keyOwner(key) == userID {
+			return nil
+		}
+		return apperrors.NewNotFoundError("file")
+	}
+	f, err := c.registry.GetByKey(ctx, key)
+	if err != nil {
+		return err
+	}
+	if err := scanError(f.ScanStatus); err != nil {
+		return err
+	}
+	if f.IsPublic() || f.OwnerID == userID {
+		return nil
+	}
+	return apperrors.NewNotFoundError("file")
+}
+
+func (c *Controller) storeIsRegistry() bool {
+	r, ok := c.uploadStore.(FileRegistry)
+	return ok && r == c.registry
+}
+
+func (c *Controller) checkPrefix(prefix string) error {
+	if c.allowedPrefixes != nil {
+		if _, ok := c.allowedPrefixes[prefix]; !ok {
+			return apperrors.NewInvalidParamsError("filestorage", errors.New("prefix not allowed"))
+		}
+	}
+	return nil
+}
+
 func buildKey(filename, prefix string, userID int) string {
 	safe := sanitizeFilename(filename)
 	if safe == "" {
@@ -195,6 +330,29 @@ func buildKey(filename, prefix string, userID int) string {
 	return strconv.Itoa(userID) + "/" + ts + "_" + safe
 }
 
+func isPublicKey(key string) bool {
+	return strings.HasPrefix(key, "public/")
+}
+
+// keyOwner returns the user id segment added by buildKey or 0 if key has none
+func keyOwner(key string) int {
+	parts := strings.Split(key, "/")
+	if len(parts) < 2 {
+		return 0
+	}
+	id, _ := strconv.Atoi(parts[len(parts)-2])
+	return id
+}
+
+// keyPrefix returns the upload prefix of a key built by buildKey
+func keyPrefix(key string) string {
+	parts := strings.Split(key, "/")
+	if len(parts) < 3 {
+		return ""
+	}
+	return strings.Join(parts[:len(parts)-2], "/")
+}
+
 var unsafeFilename = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
 
 func sanitizeFilename(name string) string {
@@ -0,0 +1,54 @@
+package filestorage
+
+import (
+	"bytes"
+	"context"
+	"io"
+
+	"github.com/krsoninikhil/go-rest-kit/encrypt"
+)
+
+// NewEncryptProcessor encrypts uploads and their variants with the active key of keyring,
+// bound to their key so objects can't be swapped. Add it after processors adding variants.
+// The upload is encrypted as it's stored, after the configured Scanner read it.
+// ContentType, Size and Checksum are of the decrypted content, read objects with
+// keyring.NewDecryptReader(obj.Body, encrypt.AAD(key)). Presigned uploads are not encrypted
+// as clients upload them directly to the backend.
+func NewEncryptProcessor(keyring *encrypt.Keyring) Processor {
+	return ProcessorFunc(func(ctx context.Context, f *UploadFile) error {
+		for i, v := range f.Variants {
+			var buf bytes.Buffer
+			w, err := keyring.NewEncryptWriter(&buf, encrypt.AAD(variantKey(f.Key, v.Name, v.ContentType)))
+			if err != nil {
+				return err
+			}
+			if _, err := w.Write(v.Data); err != nil {
+				return err
+			}
+			if err := w.Close(); err != nil {
+				return err
+			}
+			f.Variants[i].Data = buf.Bytes()
+		}
+
+		aad := encrypt.AAD(f.Key)
+		f.seal = func(body io.Reader) io.ReadCloser {
+			pr, pw := io.Pipe()
+			go func() {
+				w, err := keyring.NewEncryptWriter(pw, aad)
+				if err == nil {
+					_, err = io.Copy(w, body)
+				}
+				if err == nil {
+					err = w.Close()
+				}
+				pw.CloseWithError(err)
+			}()
+			return pr
+		}
+		f.unseal = func(stored io.Reader) (io.Reader, error) {
+			return keyring.NewDecryptReader(stored, aad)
+		}
+		return nil
+	})
+}
@@ -0,0 +1,97 @@
+package filestorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krsoninikhil/go-rest-kit/auth"
+	"github.com/krsoninikhil/go-rest-kit/encrypt"
+)
+
+func TestController_UploadEncrypted(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	backend := NewMemoryBackend()
+	keyring, _ := encrypt.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	controller := NewControllerWithBackend(backend, &ControllerOpts{
+		Processors: []Processor{NewEncryptProcessor(keyring)},
+	})
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(auth.CtxKeyUserID, 7) })
+	router.POST("/upload", controller.Upload)
+
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	fw, _ := mw.CreateFormFile("file", "a.txt")
+	io.WriteString(fw, "secret report")
+	mw.Close()
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPost, "/upload?prefix=docs", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	router.ServeHTTP(w, req)
+	var res UploadResponse
+	json.Unmarshal(w.Body.Bytes(), &res)
+
+	obj, err := backend.Get(context.Background(), res.Path)
+	if err != nil {
+		t.Fatalf("get: %v %s", err, w.Body.String())
+	}
+	r, err := keyring.NewDecryptReader(obj.Body, encrypt.AAD(res.Path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if plain, err := io.ReadAll(r); err != nil || string(plain) != "secret report" {
+		t.Errorf("decrypted %q %v", plain, err)
+	}
+}
+
+// plainScanner flags content containing "EICAR" and sends what it read to scanned
+type plainScanner struct{ scanned chan string }
+
+func (s plainScanner) Scan(ctx context.Context, r io.Reader) (*ScanResult, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	s.scanned <- string(data)
+	return &ScanResult{Infected: bytes.Contains(data, []byte("EICAR")), Signature: "Eicar-Test-Signature"}, nil
+}
+
+func TestController_UploadEncryptedScansPlainContent(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	keyring, _ := encrypt.NewKeyring("k1", map[string][]byte{"k1": bytes.Repeat([]byte{1}, 32)})
+	for _, async := range []bool{false, true} {
+		scanner := plainScanner{scanned: make(chan string, 1)}
+		controller := NewControllerWithBackend(NewMemoryBackend(), &ControllerOpts{
+			Processors: []Processor{NewEncryptProcessor(keyring)},
+			Scanner:    scanner,
+			ScanAsync:  async,
+		})
+		router := gin.New()
+		router.Use(func(c *gin.Context) { c.Set(auth.CtxKeyUserID, 7) })
+		router.POST("/upload", controller.Upload)
+
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreateFormFile("file", "a.txt")
+		io.WriteString(fw, "EICAR test file")
+		mw.Close()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/upload?prefix=docs", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		router.ServeHTTP(w, req)
+
+		if scanned := <-scanner.scanned; scanned != "EICAR test file" {
+			t.Errorf("async=%v: expected scanner to read plain content, got %q", async, scanned)
+		}
+		if !async && w.Code != http.StatusBadRequest {
+			t.Errorf("expected infected upload to be rejected, got %d %s", w.Code, w.Body.String())
+		}
+	}
+}
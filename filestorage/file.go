@@ -0,0 +1,96 @@
+package filestorage
+
+import (
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krsoninikhil/go-rest-kit/crud"
+	"github.com/krsoninikhil/go-rest-kit/sqldb"
+)
+
+const (
+	VisibilityPublic  = "public"
+	VisibilityPrivate = "private"
+)
+
+// File is the registry record of a stored object, migrate it to use FileDao.
+// Presigned uploads are recorded unconfirmed with ExpiresAt until ConfirmUpload.
+type File struct {
+	Key         string `gorm:"uniqueIndex"`
+	OwnerID     int    `gorm:"index"`
+	Prefix      string
+	Size        int64
+	ContentType string
+	Checksum    string // md5 etag from the backend or sha256 of streamed uploads
+	Visibility  string
+	Variants    map[string]string `gorm:"serializer:json"` // variant name to key
+	// ScanStatus is empty if no Scanner is configured, see ControllerOpts.Scanner
+	ScanStatus    string
+	ScanSignature string
+	Confirmed     bool
+	ExpiresAt     *time.Time
+	sqldb.BaseModel
+}
+
+func (f File) ResourceName() string { return "file" }
+func (f *File) SetCreatedBy(id int) { f.OwnerID = id }
+func (f *File) CreatedByID() int    { return f.OwnerID }
+func (f File) IsPublic() bool       { return f.Visibility == VisibilityPublic }
+
+type (
+	// FileRequest is required by crud.Controller, files are only created by upload
+	// handlers so Create and Update of the crud controller should not be routed.
+	FileRequest struct{}
+
+	FileResponse struct {
+		ID          int               `json:"id"`
+		Path        string            `json:"path"`
+		Prefix      string            `json:"prefix"`
+		Size        int64             `json:"size"`
+		ContentType string            `json:"content_type"`
+		Checksum    string            `json:"checksum,omitempty"`
+		Visibility  string            `json:"visibility"`
+		Variants    map[string]string `json:"variants,omitempty"` // variant name to path
+		ScanStatus  string            `json:"scan_status,omitempty"`
+		CreatedAt   time.Time         `json:"created_at"`
+	}
+)
+
+func (r FileRequest) ToModel(_ *gin.Context) File { return File{} }
+
+func (r FileResponse) FillFromModel(m File) crud.Response[File] {
+	return FileResponse{
+		ID:          m.ID,
+		Path:        m.Key,
+		Prefix:      m.Prefix,
+		Size:        m.Size,
+		ContentType: m.ContentType,
+		Checksum:    m.Checksum,
+		Visibility:  m.Visibility,
+		Variants:    m.Variants,
+		ScanStatus:  m.ScanStatus,
+		CreatedAt:   m.CreatedAt,
+	}
+}
+
+func (r FileResponse) ItemID() int { return r.ID }
+
+func newFile(key string, ownerID int, info ObjectInfo) File {
+	return File{
+		Key:         key,
+		OwnerID:     ownerID,
+		Prefix:      keyPrefix(key),
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		Checksum:    info.ETag,
+		Visibility:  visibility(key),
+		Confirmed:   true,
+	}
+}
+
+func visibility(key string) string {
+	if isPublicKey(key) {
+		return VisibilityPublic
+	}
+	return VisibilityPrivate
+}
@@ -0,0 +1,155 @@
+package filestorage
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+	"github.com/krsoninikhil/go-rest-kit/crud"
+	"github.com/krsoninikhil/go-rest-kit/sqldb"
+	"gorm.io/gorm"
+)
+
+// FileRegistry records uploaded files, GetByKey returns apperrors.NotFoundError for
+// unknown or unconfirmed keys.
+type FileRegistry interface {
+	Record(ctx context.Context, f File) error
+	GetByKey(ctx context.Context, key string) (*File, error)
+}
+
+// FileDao is the GORM backed FileRegistry and UploadStore, it can also be used as
+// crud.Service for the File crud.Controller, see NewFileService.
+type FileDao struct {
+	crud.Dao[File]
+}
+
+func NewFileDao(db *sqldb.PGDB) *FileDao {
+	return &FileDao{crud.Dao[File]{PGDB: db}}
+}
+
+func (d *FileDao) Record(ctx context.Context, f File) error {
+	f.Confirmed = true
+	_, err := d.Create(ctx, f)
+	return err
+}
+
+func (d *FileDao) GetByKey(ctx context.Context, key string) (*File, error) {
+	f, err := d.getByKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	if !f.Confirmed {
+		return nil, apperrors.NewNotFoundError("file")
+	}
+	return f, nil
+}
+
+// List returns the confirmed files of creatorID
+func (d *FileDao) List(ctx context.Context, page sqldb.Page, creatorID int) (res []File, total int64, err error) {
+	q := d.DB(ctx).Model(&File{}).Where("owner_id = ? AND confirmed = ?", creatorID, true)
+	if err := q.Count(&total).Error; err != nil {
+		return nil, total, apperrors.NewServerError(err)
+	}
+	if err := q.Scopes(sqldb.Paginate(page, "id")).Find(&res).Error; err != nil {
+		return nil, total, apperrors.NewServerError(err)
+	}
+	return res, total, nil
+}
+
+func (d *FileDao) CreatePending(ctx context.Context, u PendingUpload) error {
+	_, err := d.Create(ctx, File{
+		Key:         u.Key,
+		OwnerID:     u.UserID,
+		Prefix:      keyPrefix(u.Key),
+		Size:        u.MaxBytes,
+		ContentType: u.ContentType,
+		Visibility:  visibility(u.Key),
+		ExpiresAt:   &u.ExpiresAt,
+	})
+	return err
+}
+
+func (d *FileDao) GetPending(ctx context.Context, key string) (*PendingUpload, error) {
+	f, err := d.getByKey(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return f.pendingUpload(), nil
+}
+
+func (d *FileDao) Confirm(ctx context.Context, key string, info ObjectInfo) error {
+	res := d.DB(ctx).Model(&File{}).Where("key = ?", key).Updates(map[string]any{
+		"confirmed":    true,
+		"size":         info.Size,
+		"content_type": info.ContentType,
+		"checksum":     info.ETag,
+	})
+	if res.Error != nil {
+		return apperrors.NewServerError(res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return apperrors.NewNotFoundError("file")
+	}
+	return nil
+}
+
+func (d *FileDao) ListExpired(ctx context.Context, before time.Time, limit int) ([]PendingUpload, error) {
+	var files []File
+	err := d.DB(ctx).Where("confirmed = ? AND expires_at < ?", false, before).
+		Order("expires_at").Limit(limit).Find(&files).Error
+	if err != nil {
+		return nil, apperrors.NewServerError(err)
+	}
+	res := make([]PendingUpload, len(files))
+	for i := range files {
+		res[i] = *files[i].pendingUpload()
+	}
+	return res, nil
+}
+
+func (d *FileDao) SetScanStatus(ctx context.Context, key, status, signature string) error {
+	err := d.DB(ctx).Model(&File{}).Where("key = ?", key).
+		Updates(map[string]any{"scan_status": status, "scan_signature": signature}).Error
+	if err != nil {
+		return apperrors.NewServerError(err)
+	}
+	return nil
+}
+
+// DeletePending hard deletes the record so the key can't be confirmed later
+func (d *FileDao) DeletePending(ctx context.Context, key string) error {
+	if err := d.DB(ctx).Unscoped().Where("key = ?", key).Delete(&File{}).Error; err != nil {
+		return apperrors.NewServerError(err)
+	}
+	return nil
+}
+
+func (d *FileDao) getByKey(ctx context.Context, key string) (*File, error) {
+	var f File
+	if err := d.DB(ctx).Where("key = ?", key).First(&f).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, apperrors.NewNotFoundError("file")
+		}
+		return nil, apperrors.NewServerError(err)
+	}
+	return &f, nil
+}
+
+func (f *File) pendingUpload() *PendingUpload {
+	u := &PendingUpload{
+		Key:         f.Key,
+		UserID:      f.OwnerID,
+		ContentType: f.ContentType,
+		MaxBytes:    f.Size,
+		Confirmed:   f.Confirmed,
+	}
+	if f.Confirmed {
+		u.MaxBytes = 0
+		u.Size = f.Size
+	}
+	if f.ExpiresAt != nil {
+		u.ExpiresAt = *f.ExpiresAt
+	}
+	return u
+}
@@ -0,0 +1,35 @@
+package filestorage
+
+import (
+	"context"
+
+	"github.com/krsoninikhil/go-rest-kit/crud"
+)
+
+type fileService struct {
+	*FileDao
+	backend Backend
+}
+
+// NewFileService returns the crud.Service for File that also deletes the stored object and its variants
+// on Delete, use it with crud.Controller[File, FileResponse, FileRequest] to route
+// List, Retrieve and Delete of the user's files.
+func NewFileService(dao *FileDao, backend Backend) crud.Service[File] {
+	return &fileService{FileDao: dao, backend: backend}
+}
+
+func (s *fileService) Delete(ctx context.Context, id int) error {
+	f, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	for _, key := range f.Variants {
+		if err := s.backend.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	if err := s.backend.Delete(ctx, f.Key); err != nil {
+		return err
+	}
+	return s.FileDao.Delete(ctx, id)
+}
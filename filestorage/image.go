@@ -0,0 +1,334 @@
+package filestorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"io"
+
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+	"golang.org/x/image/draw"
+)
+
+const (
+	defaultJPEGQuality = 85
+	defaultMaxPixels   = 40_000_000
+	// bytesPerPixel bounds the default MaxBytes, encoded images are rarely larger than raw RGBA
+	bytesPerPixel = 4
+)
+
+var (
+	errImageTooLarge     = errors.New("image dimensions too large")
+	errImageFileTooLarge = errors.New("image file too large")
+)
+
+// ImageOptions configures NewImageProcessor. Variants are resized to fit in MaxWidth x MaxHeight
+// keeping the aspect ratio, images are never upscaled.
+type ImageOptions struct {
+	// Prefixes the processor applies to, all if empty
+	Prefixes []string
+	// StripMetadata removes EXIF, XMP and text chunks, orientation is applied to the pixels first
+	StripMetadata bool
+	Variants      []ImageVariant
+	JPEGQuality   int   // default 85
+	MaxPixels     int   // rejects larger images to limit memory use, default 40M
+	MaxBytes      int64 // rejects larger files before decoding, default 4 bytes per MaxPixels
+}
+
+type ImageVariant struct {
+	Name      string
+	MaxWidth  int
+	MaxHeight int
+}
+
+type imageProcessor struct {
+	ImageOptions
+	prefixes map[string]struct{}
+}
+
+// NewImageProcessor returns a Processor for jpeg, png and gif uploads that strips metadata and
+// generates resized variants, other types are passed through.
+func NewImageProcessor(opts ImageOptions) Processor {
+	if opts.JPEGQuality <= 0 {
+		opts.JPEGQuality = defaultJPEGQuality
+	}
+	if opts.MaxPixels <= 0 {
+		opts.MaxPixels = defaultMaxPixels
+	}
+	if opts.MaxBytes <= 0 {
+		opts.MaxBytes = int64(opts.MaxPixels) * bytesPerPixel
+	}
+	p := &imageProcessor{ImageOptions: opts}
+	if len(opts.Prefixes) > 0 {
+		p.prefixes = make(map[string]struct{}, len(opts.Prefixes))
+		for _, prefix := range opts.Prefixes {
+			p.prefixes[prefix] = struct{}{}
+		}
+	}
+	return p
+}
+
+func (p *imageProcessor) Process(ctx context.Context, f *UploadFile) error {
+	if _, ok := extByType[f.ContentType]; !ok {
+		return nil
+	}
+	if _, ok := p.prefixes[f.Prefix]; p.prefixes != nil && !ok {
+		return nil
+	}
+	data, err := io.ReadAll(io.LimitReader(f.Body, p.MaxBytes+1))
+	if err != nil {
+		return err
+	}
+	if int64(len(data)) > p.MaxBytes {
+		return apperrors.NewInvalidParamsError("filestorage", errImageFileTooLarge)
+	}
+	f.Body = bytes.NewReader(data)
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return apperrors.NewInvalidParamsError("filestorage", fmt.Errorf("invalid image: %w", err))
+	}
+	if cfg.Width*cfg.Height > p.MaxPixels {
+		return apperrors.NewInvalidParamsError("filestorage", errImageTooLarge)
+	}
+
+	orientation := 1
+	if f.ContentType == "image/jpeg" {
+		orientation = jpegOrientation(data)
+	}
+	var img image.Image
+	decode := func() (image.Image, error) {
+		if img != nil {
+			return img, nil
+		}
+		decoded, _, err := image.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, apperrors.NewInvalidParamsError("filestorage", fmt.Errorf("invalid image: %w", err))
+		}
+		img = orient(decoded, orientation)
+		return img, nil
+	}
+
+	if p.StripMetadata {
+		stripped, err := p.strip(f.ContentType, data, orientation, decode)
+		if err != nil {
+			return err
+		}
+		f.Body = bytes.NewReader(stripped)
+	}
+	for _, v := range p.Variants {
+		src, err := decode()
+		if err != nil {
+			return err
+		}
+		variant, err := p.resize(src, f.ContentType, v)
+		if err != nil {
+			return err
+		}
+		f.Variants = append(f.Variants, *variant)
+	}
+	return nil
+}
+
+// strip removes metadata without re-encoding, except for rotated jpegs whose pixels need the
+// orientation applied as it's lost with the EXIF.
+func (p *imageProcessor) strip(contentType string, data []byte, orientation int, decode func() (image.Image, error)) ([]byte, error) {
+	switch {
+	case contentType == "image/jpeg" && orientation != 1:
+		img, err := decode()
+		if err != nil {
+			return nil, err
+		}
+		return p.encode(img, contentType)
+	case contentType == "image/jpeg":
+		return stripJPEG(data)
+	case contentType == "image/png":
+		return stripPNG(data)
+	}
+	return data, nil
+}
+
+func (p *imageProcessor) resize(src image.Image, contentType string, v ImageVariant) (*Variant, error) {
+	b := src.Bounds()
+	w, h := fitSize(b.Dx(), b.Dy(), v.MaxWidth, v.MaxHeight)
+	dst := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Src, nil)
+	if contentType == "image/gif" {
+		contentType = "image/png" // animation is lost on decode
+	}
+	data, err := p.encode(dst, contentType)
+	if err != nil {
+		return nil, err
+	}
+	return &Variant{Name: v.Name, ContentType: contentType, Data: data}, nil
+}
+
+func (p *imageProcessor) encode(img image.Image, contentType string) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	switch contentType {
+	case "image/jpeg":
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: p.JPEGQuality})
+	case "image/gif":
+		err = gif.Encode(&buf, img, nil)
+	default:
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, apperrors.NewServerError(err)
+	}
+	return buf.Bytes(), nil
+}
+
+// fitSize scales w x h down to fit in maxW x maxH, a zero max doesn't limit that side
+func fitSize(w, h, maxW, maxH int) (int, int) {
+	scale := 1.0
+	if maxW > 0 && w > maxW {
+		scale = float64(maxW) / float64(w)
+	}
+	if maxH > 0 && float64(h)*scale > float64(maxH) {
+		scale = float64(maxH) / float64(h)
+	}
+	return max(1, int(float64(w)*scale+0.5)), max(1, int(float64(h)*scale+0.5))
+}
+
+// orient applies an EXIF orientation (1-8) so the image displays correctly without it
+func orient(img image.Image, orientation int) image.Image {
+	if orientation < 2 || orientation > 8 {
+		return img
+	}
+	b := img.Bounds()
+	w, h := b.Dx(), b.Dy()
+	dw, dh := w, h
+	if orientation >= 5 {
+		dw, dh = h, w
+	}
+	dst := image.NewNRGBA(image.Rect(0, 0, dw, dh))
+	for y := 0; y < dh; y++ {
+		for x := 0; x < dw; x++ {
+			var sx, sy int
+			switch orientation {
+			case 2:
+				sx, sy = w-1-x, y
+			case 3:
+				sx, sy = w-1-x, h-1-y
+			case 4:
+				sx, sy = x, h-1-y
+			case 5:
+				sx, sy = y, x
+			case 6:
+				sx, sy = y, h-1-x
+			case 7:
+				sx, sy = w-1-y, h-1-x
+			case 8:
+				sx, sy = w-1-y, x
+			}
+			dst.Set(x, y, img.At(b.Min.X+sx, b.Min.Y+sy))
+		}
+	}
+	return dst
+}
+
+var (
+	errInvalidJPEG = errors.New("invalid jpeg")
+	errInvalidPNG  = errors.New("invalid png")
+)
+
+// stripJPEG drops APP1 (EXIF, XMP), APP13 (IPTC) and comment segments before the image data
+func stripJPEG(data []byte) ([]byte, error) {
+	if len(data) < 4 || data[0] != 0xFF || data[1] != 0xD8 {
+		return nil, apperrors.NewInvalidParamsError("filestorage", errInvalidJPEG)
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:2])
+	for i := 2; ; {
+		if i+4 > len(data) || data[i] != 0xFF {
+			return nil, apperrors.NewInvalidParamsError("filestorage", errInvalidJPEG)
+		}
+		marker := data[i+1]
+		if marker == 0xDA { // start of scan, rest is image data
+			out.Write(data[i:])
+			return out.Bytes(), nil
+		}
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) {
+			return nil, apperrors.NewInvalidParamsError("filestorage", errInvalidJPEG)
+		}
+		if marker != 0xE1 && marker != 0xED && marker != 0xFE {
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+}
+
+// jpegOrientation returns the EXIF orientation of a jpeg or 1 if it has none
+func jpegOrientation(data []byte) int {
+	for i := 2; i+4 <= len(data) && data[i] == 0xFF && data[i+1] != 0xDA; {
+		end := i + 2 + int(binary.BigEndian.Uint16(data[i+2:]))
+		if end > len(data) {
+			break
+		}
+		if seg := data[i+4 : end]; data[i+1] == 0xE1 && bytes.HasPrefix(seg, []byte("Exif\x00\x00")) {
+			return tiffOrientation(seg[6:])
+		}
+		i = end
+	}
+	return 1
+}
+
+func tiffOrientation(tiff []byte) int {
+	if len(tiff) < 8 {
+		return 1
+	}
+	var order binary.ByteOrder = binary.BigEndian
+	if string(tiff[:2]) == "II" {
+		order = binary.LittleEndian
+	}
+	ifd := int(order.Uint32(tiff[4:]))
+	if ifd+2 > len(tiff) {
+		return 1
+	}
+	n := int(order.Uint16(tiff[ifd:]))
+	for i := 0; i < n; i++ {
+		entry := ifd + 2 + i*12
+		if entry+12 > len(tiff) {
+			break
+		}
+		if order.Uint16(tiff[entry:]) == 0x0112 {
+			return int(order.Uint16(tiff[entry+8:]))
+		}
+	}
+	return 1
+}
+
+// stripPNG drops the eXIf, text and time chunks
+func stripPNG(data []byte) ([]byte, error) {
+	const sigLen = 8
+	if len(data) < sigLen {
+		return nil, apperrors.NewInvalidParamsError("filestorage", errInvalidPNG)
+	}
+	out := bytes.NewBuffer(make([]byte, 0, len(data)))
+	out.Write(data[:sigLen])
+	for i := sigLen; i < len(data); {
+		if i+12 > len(data) {
+			return nil, apperrors.NewInvalidParamsError("filestorage", errInvalidPNG)
+		}
+		end := i + 12 + int(binary.BigEndian.Uint32(data[i:]))
+		if end > len(data) {
+			return nil, apperrors.NewInvalidParamsError("filestorage", errInvalidPNG)
+		}
+		switch string(data[i+4 : i+8]) {
+		case "eXIf", "tEXt", "zTXt", "iTXt", "tIME":
+		default:
+			out.Write(data[i:end])
+		}
+		i = end
+	}
+	return out.Bytes(), nil
+}
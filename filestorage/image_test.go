@@ -0,0 +1,127 @@
+package filestorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krsoninikhil/go-rest-kit/auth"
+)
+
+// jpegWithOrientation encodes a w x h jpeg with an EXIF APP1 segment holding orientation
+func jpegWithOrientation(t *testing.T, w, h int, orientation byte) []byte {
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, w, h)), nil); err != nil {
+		t.Fatal(err)
+	}
+	tiff := []byte{'M', 'M', 0, 42, 0, 0, 0, 8, 0, 1, 0x01, 0x12, 0, 3, 0, 0, 0, 1, 0, orientation, 0, 0, 0, 0, 0, 0}
+	seg := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := append([]byte{0xFF, 0xE1, byte((len(seg) + 2) >> 8), byte(len(seg) + 2)}, seg...)
+	data := buf.Bytes()
+	return append(append(append([]byte{}, data[:2]...), app1...), data[2:]...)
+}
+
+func TestImageProcessor(t *testing.T) {
+	ctx := context.Background()
+	data := jpegWithOrientation(t, 40, 20, 6)
+	if o := jpegOrientation(data); o != 6 {
+		t.Fatalf("expected orientation 6, got %d", o)
+	}
+
+	p := NewImageProcessor(ImageOptions{
+		StripMetadata: true,
+		Variants:      []ImageVariant{{Name: "thumb", MaxWidth: 5}},
+	})
+	f := &UploadFile{Key: "docs/7/1_a.jpg", ContentType: "image/jpeg", Body: bytes.NewReader(data)}
+	if err := p.Process(ctx, f); err != nil {
+		t.Fatal(err)
+	}
+	out, _ := io.ReadAll(f.Body)
+	if bytes.Contains(out, []byte("Exif")) {
+		t.Errorf("exif not stripped")
+	}
+	if cfg, _ := jpeg.DecodeConfig(bytes.NewReader(out)); cfg.Width != 20 || cfg.Height != 40 {
+		t.Errorf("expected rotated 20x40 image, got %dx%d", cfg.Width, cfg.Height)
+	}
+	if len(f.Variants) != 1 || f.Variants[0].Name != "thumb" {
+		t.Fatalf("unexpected variants %+v", f.Variants)
+	}
+	if cfg, _ := jpeg.DecodeConfig(bytes.NewReader(f.Variants[0].Data)); cfg.Width != 5 || cfg.Height != 10 {
+		t.Errorf("expected 5x10 thumb, got %dx%d", cfg.Width, cfg.Height)
+	}
+
+	f = &UploadFile{Key: "docs/7/1_a.jpg", ContentType: "image/jpeg", Body: bytes.NewReader(data)}
+	if err := NewImageProcessor(ImageOptions{MaxBytes: 64}).Process(ctx, f); err == nil || err.Error() != errImageFileTooLarge.Error() {
+		t.Errorf("expected file over MaxBytes to be rejected, got %v", err)
+	}
+
+	// unrotated jpegs are stripped without re-encoding
+	data = jpegWithOrientation(t, 4, 4, 1)
+	stripped, err := stripJPEG(data)
+	if err != nil || bytes.Contains(stripped, []byte("Exif")) || len(data)-len(stripped) != 4+6+26 {
+		t.Errorf("unexpected stripped jpeg, err %v", err)
+	}
+}
+
+func TestController_UploadPipeline(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	backend := NewMemoryBackend()
+	controller := NewControllerWithBackend(backend, &ControllerOpts{
+		AllowedTypesByPrefix: map[string][]string{"avatars": {"image/*"}},
+		Processors:           []Processor{NewImageProcessor(ImageOptions{Variants: []ImageVariant{{Name: "small", MaxWidth: 2, MaxHeight: 2}}})},
+	})
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(auth.CtxKeyUserID, 7) })
+	router.POST("/upload", controller.Upload)
+	upload := func(filename, contentType string, content []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		fw, _ := mw.CreatePart(map[string][]string{
+			"Content-Disposition": {`form-data; name="file"; filename="` + filename + `"`},
+			"Content-Type":        {contentType},
+		})
+		fw.Write(content)
+		mw.Close()
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/upload?prefix=avatars", &body)
+		req.Header.Set("Content-Type", mw.FormDataContentType())
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	if w := upload("a.png", "image/png", []byte("<html><script>alert(1)</script></html>")); w.Code != http.StatusBadRequest {
+		t.Errorf("html disguised as png: expected 400, got %d", w.Code)
+	}
+
+	img := image.NewNRGBA(image.Rect(0, 0, 8, 4))
+	img.Set(0, 0, color.White)
+	var buf bytes.Buffer
+	png.Encode(&buf, img)
+	w := upload("a.bin", "application/octet-stream", buf.Bytes())
+	if w.Code != http.StatusCreated {
+		t.Fatalf("upload: expected 201, got %d %s", w.Code, w.Body.String())
+	}
+	var res UploadResponse
+	json.Unmarshal(w.Body.Bytes(), &res)
+	obj, err := backend.Get(context.Background(), res.Path)
+	if err != nil || obj.ContentType != "image/png" {
+		t.Fatalf("expected stored png, got %+v %v", obj, err)
+	}
+	small, err := backend.Stat(context.Background(), variantKey(res.Path, "small", "image/png"))
+	if err != nil || res.Variants["small"] == "" {
+		t.Fatalf("expected small variant, got %v %v", res.Variants, err)
+	}
+	if small.ContentType != "image/png" {
+		t.Errorf("unexpected variant type %s", small.ContentType)
+	}
+}
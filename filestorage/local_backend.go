@@ -0,0 +1,228 @@
+package filestorage
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+)
+
+const metaDir = ".meta"
+
+// LocalConfig configures LocalBackend. BaseURL is the public url Handler is mounted at,
+// e.g. http://localhost:8080/files for `router.GET("/files/*key", backend.Handler())`.
+type LocalConfig struct {
+	Root       string `validate:"required"`
+	BaseURL    string `validate:"required"`
+	SigningKey string `validate:"required" log:"-"`
+}
+
+// LocalBackend stores objects on the local disk under Root, for local development.
+// Content type is kept in a sidecar file under Root/.meta.
+type LocalBackend struct {
+	config LocalConfig
+}
+
+type localMeta struct {
+	ContentType string `json:"content_type"`
+	ETag        string `json:"etag"`
+}
+
+var (
+	errInvalidKey       = errors.New("invalid key")
+	errInvalidSignature = errors.New("invalid or expired signature")
+)
+
+func NewLocalBackend(config LocalConfig) (*LocalBackend, error) {
+	if config.Root == "" || config.SigningKey == "" {
+		return nil, apperrors.NewInvalidParamsError("filestorage", errors.New("root and signing key are required"))
+	}
+	if err := os.MkdirAll(filepath.Join(config.Root, metaDir), 0o755); err != nil {
+		return nil, apperrors.NewServerError(err)
+	}
+	config.BaseURL = strings.TrimRight(config.BaseURL, "/")
+	return &LocalBackend{config: config}, nil
+}
+
+func (b *LocalBackend) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	filePath, metaPath, err := b.paths(key)
+	if err != nil {
+		return apperrors.NewInvalidParamsError("filestorage", err)
+	}
+	for _, dir := range []string{filepath.Dir(filePath), filepath.Dir(metaPath)} {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return apperrors.NewServerError(err)
+		}
+	}
+
+	// write to a temp file and rename so readers never see a partial object
+	tmp, err := os.CreateTemp(filepath.Dir(filePath), ".upload-*")
+	if err != nil {
+		return apperrors.NewServerError(err)
+	}
+	defer os.Remove(tmp.Name())
+	hash := md5.New()
+	if _, err := io.Copy(io.MultiWriter(tmp, hash), body); err != nil {
+		tmp.Close()
+		return apperrors.NewServerError(err)
+	}
+	if err := tmp.Close(); err != nil {
+		return apperrors.NewServerError(err)
+	}
+
+	meta, _ := json.Marshal(localMeta{ContentType: contentType, ETag: hex.EncodeToString(hash.Sum(nil))})
+	if err := os.WriteFile(metaPath, meta, 0o644); err != nil {
+		return apperrors.NewServerError(err)
+	}
+	if err := os.Rename(tmp.Name(), filePath); err != nil {
+		return apperrors.NewServerError(err)
+	}
+	return nil
+}
+
+func (b *LocalBackend) Get(ctx context.Context, key string) (*Object, error) {
+	info, err := b.Stat(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	filePath, _, _ := b.paths(key)
+	f, err := os.Open(filePath)
+	if err != nil {
+		return nil, mapFSError(err)
+	}
+	return &Object{ObjectInfo: *info, Body: f}, nil
+}
+
+func (b *LocalBackend) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	filePath, metaPath, err := b.paths(key)
+	if err != nil {
+		return nil, apperrors.NewNotFoundError("file")
+	}
+	stat, err := os.Stat(filePath)
+	if err != nil {
+		return nil, mapFSError(err)
+	}
+	var meta localMeta
+	if data, err := os.ReadFile(metaPath); err == nil {
+		_ = json.Unmarshal(data, &meta)
+	}
+	return &ObjectInfo{
+		Key:          key,
+		Size:         stat.Size(),
+		ContentType:  meta.ContentType,
+		ETag:         meta.ETag,
+		LastModified: stat.ModTime(),
+	}, nil
+}
+
+func (b *LocalBackend) Delete(ctx context.Context, key string) error {
+	filePath, metaPath, err := b.paths(key)
+	if err != nil {
+		return apperrors.NewInvalidParamsError("filestorage", err)
+	}
+	for _, p := range []string{filePath, metaPath} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return apperrors.NewServerError(err)
+		}
+	}
+	return nil
+}
+
+// Presign returns a Handler url with expiry and HMAC signature of the key
+func (b *LocalBackend) Presign(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	if _, _, err := b.paths(key); err != nil {
+		return "", apperrors.NewInvalidParamsError("filestorage", err)
+	}
+	expires := strconv.FormatInt(time.Now().Add(expiry).Unix(), 10)
+	q := url.Values{"expires": {expires}, "signature": {b.sign(key, expires)}}
+	return b.PublicURL(key) + "?" + q.Encode(), nil
+}
+
+func (b *LocalBackend) PublicURL(key string) string {
+	return b.config.BaseURL + (&url.URL{Path: "/" + key}).EscapedPath()
+}
+
+// Handler serves objects for a route with a *key wildcard param. Keys with "public/" prefix
+// are served without signature, others require a valid url from Presign.
+func (b *LocalBackend) Handler() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := strings.TrimPrefix(c.Param("key"), "/")
+		if !strings.HasPrefix(key, "public/") && !b.validSignature(key, c.Query("expires"), c.Query("signature")) {
+			c.AbortWithStatusJSON(http.StatusForbidden, apperrors.NewPermissionError("file").HTTPResponse())
+			return
+		}
+
+		obj, err := b.Get(c.Request.Context(), key)
+		if err != nil {
+			var appErr apperrors.AppError
+			if errors.As(err, &appErr) {
+				c.AbortWithStatusJSON(appErr.HTTPCode(), appErr.HTTPResponse())
+			} else {
+				c.AbortWithStatus(http.StatusInternalServerError)
+			}
+			return
+		}
+		f := obj.Body.(*os.File)
+		defer f.Close()
+		if obj.ContentType != "" {
+			c.Header("Content-Type", obj.ContentType)
+		}
+		if obj.ETag != "" {
+			c.Header("ETag", `"`+obj.ETag+`"`)
+		}
+		http.ServeContent(c.Writer, c.Request, path.Base(key), obj.LastModified, f)
+	}
+}
+
+func (b *LocalBackend) validSignature(key, expires, signature string) bool {
+	exp, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil || time.Now().Unix() > exp {
+		return false
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	actual, _ := hex.DecodeString(b.sign(key, expires))
+	return hmac.Equal(expected, actual)
+}
+
+func (b *LocalBackend) sign(key, expires string) string {
+	mac := hmac.New(sha256.New, []byte(b.config.SigningKey))
+	mac.Write([]byte(key + "\n" + expires))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// paths returns the object and metadata file paths, rejecting keys that escape Root
+func (b *LocalBackend) paths(key string) (string, string, error) {
+	cleaned := path.Clean("/" + key)
+	if key == "" || cleaned != "/"+key || strings.HasPrefix(key, metaDir+"/") {
+		return "", "", fmt.Errorf("%w: %q", errInvalidKey, key)
+	}
+	rel := filepath.FromSlash(strings.TrimPrefix(cleaned, "/"))
+	return filepath.Join(b.config.Root, rel), filepath.Join(b.config.Root, metaDir, rel+".json"), nil
+}
+
+func mapFSError(err error) error {
+	if errors.Is(err, fs.ErrNotExist) {
+		return apperrors.NewNotFoundError("file")
+	}
+	return apperrors.NewServerError(err)
+}
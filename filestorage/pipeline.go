@@ -0,0 +1,208 @@
+package filestorage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+)
+
+const sniffLen = 512
+
+// Processor transforms an upload before it's stored, e.g. to strip metadata or add resized
+// variants. Processors run in order on every upload of Controller.Upload, ContentType is
+// already sniffed and validated when they run.
+type Processor interface {
+	Process(ctx context.Context, f *UploadFile) error
+}
+
+type ProcessorFunc func(ctx context.Context, f *UploadFile) error
+
+func (fn ProcessorFunc) Process(ctx context.Context, f *UploadFile) error { return fn(ctx, f) }
+
+// UploadFile is an upload passing through processors, a processor replacing Body must also
+// update ContentType if it changes.
+type UploadFile struct {
+	Key         string
+	Prefix      string
+	ContentType string
+	Body        io.Reader
+	Variants    []Variant
+
+	// seal encrypts Body as it's stored and unseal decrypts the stored object, they are set by
+	// the encrypt processor so that scanners read the plain content
+	seal   func(io.Reader) io.ReadCloser
+	unseal func(io.Reader) (io.Reader, error)
+}
+
+// sealed returns body as it must be stored, close it once stored
+func (f *UploadFile) sealed(body io.Reader) io.ReadCloser {
+	if f.seal == nil {
+		return io.NopCloser(body)
+	}
+	return f.seal(body)
+}
+
+// Variant is a derived file stored alongside the original, e.g. a thumbnail
+type Variant struct {
+	Name        string
+	ContentType string
+	Data        []byte
+}
+
+var errTypeNotAllowed = errors.New("file type not allowed")
+
+// sniff replaces the declared content type of f with the one detected from its first bytes
+func sniff(f *UploadFile, declared string) error {
+	br := bufio.NewReaderSize(f.Body, sniffLen)
+	head, err := br.Peek(sniffLen)
+	if err != nil && err != io.EOF {
+		return err
+	}
+	f.Body = br
+	f.ContentType = detectContentType(head, declared)
+	return nil
+}
+
+// detectContentType returns the declared type if the content is compatible with it and the type
+// detected by http.DetectContentType otherwise. Text can't be told apart by content, e.g. csv or
+// json, and formats the detection doesn't know, e.g. HEIC or docx, sniff as octet-stream or zip.
+func detectContentType(head []byte, declared string) string {
+	sniffed, _, _ := mime.ParseMediaType(http.DetectContentType(head))
+	declared, _, _ = mime.ParseMediaType(declared)
+	if declared != "" && compatibleType(sniffed, declared) {
+		return declared
+	}
+	return sniffed
+}
+
+// sniffedTypes are the types http.DetectContentType identifies from their signature, content of
+// these types must sniff as them
+var sniffedTypes = map[string]struct{}{
+	"text/html": {}, "text/xml": {}, "application/pdf": {}, "application/postscript": {},
+	"image/x-icon": {}, "image/bmp": {}, "image/gif": {}, "image/webp": {}, "image/png": {},
+	"image/jpeg": {}, "audio/basic": {}, "audio/aiff": {}, "audio/mpeg": {}, "application/ogg": {},
+	"audio/midi": {}, "video/avi": {}, "audio/wave": {}, "video/mp4": {}, "video/webm": {},
+	"application/vnd.ms-fontobject": {}, "font/ttf": {}, "font/otf": {}, "font/collection": {},
+	"font/woff": {}, "font/woff2": {}, "application/x-gzip": {}, "application/zip": {},
+	"application/x-rar-compressed": {}, "application/wasm": {},
+}
+
+// compatibleType reports whether content sniffed as sniffed can be of the declared type.
+// Unrecognized content, sniffed as octet-stream, is only accepted for types the detection
+// doesn't know, e.g. HEIC, and never for text or the types in sniffedTypes.
+func compatibleType(sniffed, declared string) bool {
+	switch sniffed {
+	case declared:
+		return true
+	case "application/octet-stream":
+		_, known := sniffedTypes[declared]
+		return !known && !isTextType(declared)
+	case "text/plain":
+		return isTextType(declared)
+	case "application/zip":
+		return isZipType(declared)
+	}
+	return false
+}
+
+// isZipType reports whether contentType is a zip container, e.g. docx, xlsx, pptx, odt or epub
+func isZipType(contentType string) bool {
+	return strings.HasPrefix(contentType, "application/vnd.openxmlformats-officedocument.") ||
+		strings.HasPrefix(contentType, "application/vnd.oasis.opendocument.") ||
+		strings.HasSuffix(contentType, "+zip")
+}
+
+func isTextType(contentType string) bool {
+	switch contentType {
+	case "text/html", "image/svg+xml":
+		return false
+	case "application/json", "application/xml":
+		return true
+	}
+	return strings.HasPrefix(contentType, "text/")
+}
+
+// typeAllowed matches contentType against allowed types that may use wildcards like "image/*"
+func typeAllowed(allowed []string, contentType string) bool {
+	if allowed == nil {
+		return true
+	}
+	for _, t := range allowed {
+		if ok, _ := path.Match(t, contentType); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *Controller) checkType(prefix, contentType string) error {
+	if !typeAllowed(c.allowedTypesByPrefix[prefix], contentType) {
+		return apperrors.NewInvalidParamsError("filestorage", fmt.Errorf("%w: %s", errTypeNotAllowed, contentType))
+	}
+	return nil
+}
+
+// process sniffs the type of the upload, validates it and runs the processors
+func (c *Controller) process(ctx context.Context, f *UploadFile, declared string) error {
+	if err := sniff(f, declared); err != nil {
+		return err
+	}
+	if err := c.checkType(f.Prefix, f.ContentType); err != nil {
+		return err
+	}
+	for _, p := range c.processors {
+		if err := p.Process(ctx, f); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// putVariants stores variants of key and returns their keys by name, stored variants are
+// deleted if any fails.
+func (c *Controller) putVariants(ctx context.Context, key string, variants []Variant) (map[string]string, error) {
+	if len(variants) == 0 {
+		return nil, nil
+	}
+	keys := make(map[string]string, len(variants))
+	for _, v := range variants {
+		vkey := variantKey(key, v.Name, v.ContentType)
+		if err := c.backend.Put(ctx, vkey, bytes.NewReader(v.Data), v.ContentType); err != nil {
+			c.deleteKeys(ctx, keys)
+			return nil, err
+		}
+		keys[v.Name] = vkey
+	}
+	return keys, nil
+}
+
+func (c *Controller) deleteKeys(ctx context.Context, keys map[string]string) {
+	for _, key := range keys {
+		c.backend.Delete(ctx, key)
+	}
+}
+
+// variantKey returns the key of a variant, e.g. docs/7/1_a_thumb.jpg for docs/7/1_a.png
+func variantKey(key, name, contentType string) string {
+	ext := path.Ext(key)
+	base := strings.TrimSuffix(key, ext)
+	if e, ok := extByType[contentType]; ok {
+		ext = e
+	}
+	return base + "_" + name + ext
+}
+
+var extByType = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+	"image/gif":  ".gif",
+}
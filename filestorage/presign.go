@@ -0,0 +1,235 @@
+package filestorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"mime"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+	"github.com/krsoninikhil/go-rest-kit/auth"
+)
+
+const (
+	UploadMethodPut  = "put"
+	UploadMethodPost = "post"
+
+	presignUploadExpiry = 15 * time.Minute
+)
+
+var (
+	errDirectUploadUnsupported = errors.New("backend doesn't support direct uploads")
+	errUploadStoreRequired     = errors.New("upload store is not configured")
+)
+
+type (
+	// PresignUploadRequest asks for a direct upload url, Method is put (default) or post
+	PresignUploadRequest struct {
+		Filename    string `json:"filename" binding:"required"`
+		ContentType string `json:"content_type" binding:"required"`
+		Size        int64  `json:"size" binding:"required,gt=0"`
+		Prefix      string `json:"prefix"`
+		Method      string `json:"method" binding:"omitempty,oneof=put post"`
+	}
+
+	// PresignUploadResponse tells the client how to upload, for put send Headers with the
+	// file as body to URL, for post send Fields and then the file as multipart form to URL.
+	PresignUploadResponse struct {
+		Path      string            `json:"path"`
+		Method    string            `json:"method"`
+		URL       string            `json:"url"`
+		Headers   map[string]string `json:"headers,omitempty"`
+		Fields    map[string]string `json:"fields,omitempty"`
+		ExpiresAt time.Time         `json:"expires_at"`
+	}
+
+	ConfirmUploadRequest struct {
+		Path string `json:"path" binding:"required"`
+	}
+)
+
+// WithUploadStore enables direct uploads, PresignUpload and ConfirmUpload require it.
+func (c *Controller) WithUploadStore(store UploadStore) *Controller {
+	c.uploadStore = store
+	return c
+}
+
+// PresignUpload issues a url for the client to upload directly to the backend, bypassing the
+// API. The upload is recorded as pending until ConfirmUpload, unconfirmed uploads are deleted
+// by UploadSweeper. Use with request.BindCreate.
+func (c *Controller) PresignUpload(ctx *gin.Context, req PresignUploadRequest) (*PresignUploadResponse, error) {
+	userID := auth.UserID(ctx)
+	if userID == 0 {
+		return nil, apperrors.NewPermissionError("user_id")
+	}
+	uploader, ok := c.backend.(DirectUploader)
+	if !ok {
+		return nil, apperrors.NewServerError(errDirectUploadUnsupported)
+	}
+	if c.uploadStore == nil {
+		return nil, apperrors.NewServerError(errUploadStoreRequired)
+	}
+	if err := c.checkPrefix(req.Prefix); err != nil {
+		return nil, err
+	}
+	maxBytes := c.maxBytesByPrefix[req.Prefix]
+	if isOverMaxBytes(maxBytes, req.Size, 0) {
+		return nil, apperrors.NewInvalidParamsError("filestorage", fileTooLargeErr(req.Prefix, maxBytes))
+	}
+	if maxBytes <= 0 {
+		maxBytes = req.Size
+	}
+	contentType, _, err := mime.ParseMediaType(req.ContentType)
+	if err != nil {
+		return nil, apperrors.NewInvalidParamsError("filestorage", fmt.Errorf("invalid content type: %w", err))
+	}
+
+	if err := c.checkType(req.Prefix, contentType); err != nil {
+		return nil, err
+	}
+
+	key := buildKey(req.Filename, req.Prefix, userID)
+	res := &PresignUploadResponse{Path: key, Method: req.Method, ExpiresAt: time.Now().Add(presignUploadExpiry)}
+	if req.Method == UploadMethodPost {
+		post, err := uploader.PresignPost(ctx, key, contentType, maxBytes, presignUploadExpiry)
+		if err != nil {
+			return nil, err
+		}
+		res.URL, res.Fields = post.URL, post.Fields
+	} else {
+		res.Method = UploadMethodPut
+		res.URL, err = uploader.PresignPut(ctx, key, contentType, presignUploadExpiry)
+		if err != nil {
+			return nil, err
+		}
+		res.Headers = map[string]string{"Content-Type": contentType}
+	}
+
+	err = c.uploadStore.CreatePending(ctx, PendingUpload{
+		Key:         key,
+		UserID:      userID,
+		ContentType: contentType,
+		MaxBytes:    maxBytes,
+		ExpiresAt:   res.ExpiresAt,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// ConfirmUpload verifies that a presigned upload of the user exists with the allowed size
+// and content type, and marks it confirmed. Invalid uploads are deleted.
+func (c *Controller) ConfirmUpload(ctx *gin.Context, req ConfirmUploadRequest) (*UploadResponse, error) {
+	userID := auth.UserID(ctx)
+	if userID == 0 {
+		return nil, apperrors.NewPermissionError("user_id")
+	}
+	if c.uploadStore == nil {
+		return nil, apperrors.NewServerError(errUploadStoreRequired)
+	}
+	pending, err := c.uploadStore.GetPending(ctx, req.Path)
+	if err != nil {
+		return nil, err
+	}
+	if pending.UserID != userID {
+		return nil, apperrors.NewNotFoundError("upload")
+	}
+	if pending.Confirmed {
+		return c.uploadResponse(ctx, pending.Key), nil
+	}
+
+	info, err := c.backend.Stat(ctx, pending.Key)
+	if err != nil {
+		return nil, err
+	}
+	sniffed, err := c.sniffStored(ctx, pending)
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyUpload(pending, info, sniffed); err != nil {
+		if err := c.backend.Delete(ctx, pending.Key); err != nil {
+			return nil, err
+		}
+		if err := c.uploadStore.DeletePending(ctx, pending.Key); err != nil {
+			return nil, err
+		}
+		return nil, apperrors.NewInvalidParamsError("filestorage", err)
+	}
+	var scan *ScanResult
+	if c.scanner != nil && !c.scanAsync {
+		if scan, err = c.scanStored(ctx, pending.Key, nil); err != nil {
+			return nil, err
+		}
+		if scan.Infected {
+			if err := c.uploadStore.DeletePending(ctx, pending.Key); err != nil {
+				return nil, err
+			}
+			return nil, apperrors.NewInvalidParamsError("filestorage", fmt.Errorf("%w: %s", errInfected, scan.Signature))
+		}
+	}
+
+	if err := c.uploadStore.Confirm(ctx, pending.Key, *info); err != nil {
+		return nil, err
+	}
+	status := c.scanStatus(scan)
+	if c.registry != nil && !c.storeIsRegistry() {
+		f := newFile(pending.Key, userID, *info)
+		f.ScanStatus = status
+		if err := c.registry.Record(ctx, f); err != nil {
+			return nil, err
+		}
+	} else if status != "" {
+		if err := c.setScanStatus(ctx, pending.Key, status, ""); err != nil {
+			return nil, err
+		}
+	}
+	if c.scanner != nil && c.scanAsync {
+		c.scanInBackground(pending.Key, nil, nil)
+		return &UploadResponse{Path: pending.Key, ScanStatus: status}, nil
+	}
+	res := c.uploadResponse(ctx, pending.Key)
+	res.ScanStatus = status
+	return res, nil
+}
+
+func (c *Controller) uploadResponse(ctx *gin.Context, key string) *UploadResponse {
+	res := &UploadResponse{Path: key}
+	if isPublicKey(key) {
+		res.URL = c.backend.PublicURL(key)
+	} else if signed, err := c.backend.Presign(ctx, key, time.Hour); err == nil {
+		res.URL = signed
+	}
+	return res
+}
+
+// sniffStored detects the type of a direct upload as the stored content type is set by the client
+func (c *Controller) sniffStored(ctx context.Context, pending *PendingUpload) (string, error) {
+	obj, err := c.backend.Get(ctx, pending.Key)
+	if err != nil {
+		return "", err
+	}
+	defer obj.Body.Close()
+	f := &UploadFile{Body: obj.Body}
+	if err := sniff(f, pending.ContentType); err != nil {
+		return "", apperrors.NewServerError(err)
+	}
+	return f.ContentType, nil
+}
+
+func verifyUpload(pending *PendingUpload, info *ObjectInfo, sniffed string) error {
+	if isOverMaxBytes(pending.MaxBytes, info.Size, 0) {
+		return fmt.Errorf("file too large: max %d bytes", pending.MaxBytes)
+	}
+	contentType, _, _ := mime.ParseMediaType(info.ContentType)
+	if !strings.EqualFold(contentType, pending.ContentType) {
+		return fmt.Errorf("content type %q doesn't match %q", contentType, pending.ContentType)
+	}
+	if sniffed != pending.ContentType {
+		return fmt.Errorf("file content is %q, not %q", sniffed, pending.ContentType)
+	}
+	return nil
+}
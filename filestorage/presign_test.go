@@ -0,0 +1,137 @@
+package filestorage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krsoninikhil/go-rest-kit/auth"
+	"github.com/krsoninikhil/go-rest-kit/request"
+)
+
+func TestController_DirectUpload(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	backend := NewMemoryBackend()
+	store := NewMemoryUploadStore()
+	controller := NewControllerWithBackend(backend, &ControllerOpts{
+		MaxBytesByPrefix: map[string]int64{"docs": 10},
+	}).WithUploadStore(store)
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(auth.CtxKeyUserID, 7) })
+	router.POST("/uploads/presign", request.BindCreate(controller.PresignUpload))
+	router.POST("/uploads/confirm", request.BindCreate(controller.ConfirmUpload))
+	post := func(path string, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+	presign := func(contentType string, size int64) PresignUploadResponse {
+		w := post("/uploads/presign", PresignUploadRequest{Filename: "a.pdf", ContentType: contentType, Size: size, Prefix: "docs"})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("presign: expected 201, got %d %s", w.Code, w.Body.String())
+		}
+		var res PresignUploadResponse
+		json.Unmarshal(w.Body.Bytes(), &res)
+		return res
+	}
+
+	if w := post("/uploads/presign", PresignUploadRequest{Filename: "a.pdf", ContentType: "application/pdf", Size: 11, Prefix: "docs"}); w.Code != http.StatusBadRequest {
+		t.Errorf("over max size: expected 400, got %d", w.Code)
+	}
+
+	res := presign("application/pdf", 5)
+	if res.Method != UploadMethodPut || res.Headers["Content-Type"] != "application/pdf" || !strings.HasPrefix(res.Path, "docs/7/") {
+		t.Errorf("unexpected presign response %+v", res)
+	}
+	if w := post("/uploads/confirm", ConfirmUploadRequest{Path: res.Path}); w.Code != http.StatusNotFound {
+		t.Errorf("confirm before upload: expected 404, got %d", w.Code)
+	}
+	backend.Put(ctx, res.Path, strings.NewReader("%PDF-"), "application/pdf")
+	if w := post("/uploads/confirm", ConfirmUploadRequest{Path: res.Path}); w.Code != http.StatusCreated {
+		t.Fatalf("confirm: expected 201, got %d %s", w.Code, w.Body.String())
+	}
+	if pending, _ := store.GetPending(ctx, res.Path); !pending.Confirmed || pending.Size != 5 {
+		t.Errorf("expected confirmed upload, got %+v", pending)
+	}
+
+	// uploaded with a different type than presigned, it's rejected and deleted
+	res = presign("image/png", 5)
+	backend.Put(ctx, res.Path, strings.NewReader("<svg>"), "image/svg+xml")
+	if w := post("/uploads/confirm", ConfirmUploadRequest{Path: res.Path}); w.Code != http.StatusBadRequest {
+		t.Errorf("type mismatch: expected 400, got %d", w.Code)
+	}
+	if _, err := backend.Stat(ctx, res.Path); err == nil {
+		t.Errorf("rejected upload must be deleted")
+	}
+
+	// unconfirmed uploads are swept after expiry
+	res = presign("application/pdf", 5)
+	backend.Put(ctx, res.Path, strings.NewReader("%PDF-"), "application/pdf")
+	store.uploads[res.Path] = PendingUpload{Key: res.Path, UserID: 7, ExpiresAt: time.Now().Add(-2 * time.Hour)}
+	swept, err := NewUploadSweeper(backend, store, SweeperConfig{}).Sweep(ctx)
+	if err != nil || swept != 1 {
+		t.Fatalf("sweep: expected 1 swept, got %d err=%v", swept, err)
+	}
+	if keys := backend.Keys(); len(keys) != 1 {
+		t.Errorf("expected only the confirmed upload to remain, got %v", keys)
+	}
+}
+
+func TestController_ConfirmUploadTypes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ctx := context.Background()
+	backend := NewMemoryBackend()
+	controller := NewControllerWithBackend(backend, nil).WithUploadStore(NewMemoryUploadStore())
+
+	router := gin.New()
+	router.Use(func(c *gin.Context) { c.Set(auth.CtxKeyUserID, 7) })
+	router.POST("/uploads/presign", request.BindCreate(controller.PresignUpload))
+	router.POST("/uploads/confirm", request.BindCreate(controller.ConfirmUpload))
+	post := func(path string, body any) *httptest.ResponseRecorder {
+		data, _ := json.Marshal(body)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(data))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	cases := []struct {
+		name, contentType, content string
+		code                       int
+	}{
+		// sniffed as application/zip
+		{"a.docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", "PK\x03\x04\x14\x00\x06\x00", http.StatusCreated},
+		// sniffed as application/octet-stream
+		{"a.heic", "image/heic", "\x00\x00\x00\x18ftypheic\x00\x00\x00\x00", http.StatusCreated},
+		{"a.pdf", "application/pdf", "PK\x03\x04\x14\x00\x06\x00", http.StatusBadRequest},
+		// unrecognized binaries can't pass as types the detection knows
+		{"a.png", "image/png", "\x7fELF\x02\x01\x01\x00\x00\x00", http.StatusBadRequest},
+		{"a.csv", "text/csv", "MZ\x90\x00\x03\x00\x00\x00", http.StatusBadRequest},
+		{"a.jpg", "image/jpeg", "\x89PNG\x0d\x0a\x1a\x0a", http.StatusBadRequest},
+		{"a.docx", "application/vnd.openxmlformats-officedocument.wordprocessingml.document", "<html><body>", http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		w := post("/uploads/presign", PresignUploadRequest{Filename: tc.name, ContentType: tc.contentType, Size: int64(len(tc.content)), Prefix: "media"})
+		if w.Code != http.StatusCreated {
+			t.Fatalf("presign %s: expected 201, got %d %s", tc.name, w.Code, w.Body.String())
+		}
+		var res PresignUploadResponse
+		json.Unmarshal(w.Body.Bytes(), &res)
+		backend.Put(ctx, res.Path, strings.NewReader(tc.content), tc.contentType)
+		if w := post("/uploads/confirm", ConfirmUploadRequest{Path: res.Path}); w.Code != tc.code {
+			t.Errorf("confirm %s with %q: expected %d, got %d %s", tc.contentType, tc.content, tc.code, w.Code, w.Body.String())
+		}
+	}
+}
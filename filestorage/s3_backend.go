@@ -0,0 +1,63 @@
+package filestorage
+
+import (
+	"context"
+	"io"
+	"time"
+
+	"github.com/krsoninikhil/go-rest-kit/integrations/aws"
+)
+
+type s3Backend struct {
+	s3 *aws.S3
+}
+
+// NewS3Backend returns a Backend storing objects in the S3 bucket
+func NewS3Backend(s3 *aws.S3) Backend {
+	return &s3Backend{s3: s3}
+}
+
+func (b *s3Backend) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	return b.s3.Upload(ctx, key, body, contentType, aws.UploadOptions{})
+}
+
+func (b *s3Backend) Get(ctx context.Context, key string) (*Object, error) {
+	obj, err := b.s3.GetObject(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	return &Object{ObjectInfo: ObjectInfo(obj.ObjectInfo), Body: obj.Body}, nil
+}
+
+func (b *s3Backend) Stat(ctx context.Context, key string) (*ObjectInfo, error) {
+	info, err := b.s3.HeadObject(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	res := ObjectInfo(*info)
+	return &res, nil
+}
+
+func (b *s3Backend) Delete(ctx context.Context, key string) error {
+	return b.s3.DeleteObject(ctx, key)
+}
+
+func (b *s3Backend) Presign(ctx context.Context, key string, expiry time.Duration) (string, error) {
+	return b.s3.PresignGet(ctx, key, expiry)
+}
+
+func (b *s3Backend) PublicURL(key string) string {
+	return b.s3.PublicURL(key)
+}
+
+func (b *s3Backend) PresignPut(ctx context.Context, key, contentType string, expiry time.Duration) (string, error) {
+	return b.s3.PresignPut(ctx, key, contentType, expiry)
+}
+
+func (b *s3Backend) PresignPost(ctx context.Context, key, contentType string, maxBytes int64, expiry time.Duration) (*PresignedPost, error) {
+	post, err := b.s3.PresignPost(ctx, key, contentType, maxBytes, expiry)
+	if err != nil {
+		return nil, err
+	}
+	return &PresignedPost{URL: post.URL, Fields: post.Fields}, nil
+}
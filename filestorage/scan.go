@@ -0,0 +1,139 @@
+package filestorage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path"
+
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+)
+
+const defaultQuarantinePrefix = "quarantine"
+
+var (
+	errInfected    = errors.New("file is infected")
+	errScanPending = errors.New("file scan is pending")
+	errScanFailed  = errors.New("file scan failed")
+)
+
+// putScanned stores the upload while streaming it to the scanner before it's sealed, an
+// infected object is quarantined and a failed scan deletes it.
+func (c *Controller) putScanned(ctx context.Context, file *UploadFile, body io.Reader) (*ScanResult, error) {
+	key := file.Key
+	pr, pw := io.Pipe()
+	type outcome struct {
+		result *ScanResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := c.scanner.Scan(ctx, pr)
+		pr.CloseWithError(errScanFailed) // fails the upload instead of blocking if scanner stopped reading
+		done <- outcome{result, err}
+	}()
+
+	sealed := file.sealed(io.TeeReader(body, pw))
+	err := c.backend.Put(ctx, key, sealed, file.ContentType)
+	sealed.Close()
+	pw.CloseWithError(err)
+	scan := <-done
+	if err != nil {
+		return nil, err
+	}
+	if scan.result == nil {
+		c.backend.Delete(ctx, key)
+		return nil, apperrors.NewServerError(fmt.Errorf("error scanning upload: %w", scan.err))
+	}
+	if scan.result.Infected {
+		if err := c.quarantine(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+	return scan.result, nil
+}
+
+// scanStored scans an already stored object, decrypted with unseal if not nil, and quarantines
+// it if infected
+func (c *Controller) scanStored(ctx context.Context, key string, unseal func(io.Reader) (io.Reader, error)) (*ScanResult, error) {
+	obj, err := c.backend.Get(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Body.Close()
+	var content io.Reader = obj.Body
+	if unseal != nil {
+		if content, err = unseal(obj.Body); err != nil {
+			return nil, apperrors.NewServerError(fmt.Errorf("error decrypting upload: %w", err))
+		}
+	}
+	result, err := c.scanner.Scan(ctx, content)
+	if err != nil {
+		return nil, apperrors.NewServerError(fmt.Errorf("error scanning upload: %w", err))
+	}
+	if result.Infected {
+		if err := c.quarantine(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// scanInBackground scans a stored upload in the background and records the result in the registry
+func (c *Controller) scanInBackground(key string, variants map[string]string, unseal func(io.Reader) (io.Reader, error)) {
+	go func() {
+		ctx := context.Background()
+		status, signature := ScanFailed, ""
+		result, err := c.scanStored(ctx, key, unseal)
+		if err != nil {
+			log.Printf("filestorage: error scanning upload key=%s err=%v", key, err)
+		} else {
+			status, signature = result.Status(), result.Signature
+		}
+		if status != ScanClean {
+			c.deleteKeys(ctx, variants)
+		}
+		if err := c.setScanStatus(ctx, key, status, signature); err != nil {
+			log.Printf("filestorage: error recording scan status key=%s err=%v", key, err)
+		}
+	}()
+}
+
+func (c *Controller) setScanStatus(ctx context.Context, key, status, signature string) error {
+	if recorder, ok := c.registry.(ScanRecorder); ok {
+		return recorder.SetScanStatus(ctx, key, status, signature)
+	}
+	return nil
+}
+
+// quarantine moves key under the quarantine prefix so it can't be served anymore
+func (c *Controller) quarantine(ctx context.Context, key string) error {
+	obj, err := c.backend.Get(ctx, key)
+	if err != nil {
+		return err
+	}
+	defer obj.Body.Close()
+	if err := c.backend.Put(ctx, quarantineKey(c.quarantinePrefix, key), obj.Body, obj.ContentType); err != nil {
+		return err
+	}
+	return c.backend.Delete(ctx, key)
+}
+
+func quarantineKey(prefix, key string) string {
+	return path.Join(prefix, key)
+}
+
+// scanError returns the error for serving a file with the given scan status, nil if allowed
+func scanError(status string) error {
+	switch status {
+	case ScanPending:
+		return apperrors.NewConflictError("file", errScanPending)
+	case ScanFailed:
+		return apperrors.NewConflictError("file", errScanFailed)
+	case ScanInfected:
+		return apperrors.NewNotFoundError("file")
+	}
+	return nil
+}
@@ -0,0 +1,36 @@
+package filestorage
+
+import (
+	"context"
+	"io"
+)
+
+const (
+	ScanPending  = "pending"
+	ScanClean    = "clean"
+	ScanInfected = "infected"
+	ScanFailed   = "failed"
+)
+
+// Scanner checks uploads for malware, see NewClamdScanner
+type Scanner interface {
+	Scan(ctx context.Context, r io.Reader) (*ScanResult, error)
+}
+
+// ScanResult is the verdict of a scan, Signature names the detected malware
+type ScanResult struct {
+	Infected  bool
+	Signature string
+}
+
+func (r *ScanResult) Status() string {
+	if r.Infected {
+		return ScanInfected
+	}
+	return ScanClean
+}
+
+// ScanRecorder is implemented by registries that store scan results, e.g. FileDao
+type ScanRecorder interface {
+	SetScanStatus(ctx context.Context, key, status, signature string) error
+}
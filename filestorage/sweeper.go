@@ -0,0 +1,72 @@
+package filestorage
+
+import (
+	"context"
+	"log"
+	"time"
+)
+
+const sweepBatchSize = 100
+
+// SweeperConfig configures UploadSweeper. GracePeriod is added to the presign expiry
+// before an unconfirmed upload is deleted, to let in flight uploads finish.
+type SweeperConfig struct {
+	Interval    time.Duration // default 10m
+	GracePeriod time.Duration // default 1h
+}
+
+// UploadSweeper deletes presigned uploads that were never confirmed along with their objects.
+type UploadSweeper struct {
+	backend Backend
+	store   UploadStore
+	config  SweeperConfig
+}
+
+func NewUploadSweeper(backend Backend, store UploadStore, config SweeperConfig) *UploadSweeper {
+	if config.Interval == 0 {
+		config.Interval = 10 * time.Minute
+	}
+	if config.GracePeriod == 0 {
+		config.GracePeriod = time.Hour
+	}
+	return &UploadSweeper{backend: backend, store: store, config: config}
+}
+
+// Run sweeps every Interval until ctx is done, run it in a goroutine.
+func (s *UploadSweeper) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.config.Interval)
+	defer ticker.Stop()
+	for {
+		if n, err := s.Sweep(ctx); err != nil {
+			log.Printf("filestorage: error sweeping uploads, swept=%d err=%v", n, err)
+		} else if n > 0 {
+			log.Printf("filestorage: swept %d unconfirmed uploads", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Sweep deletes all expired unconfirmed uploads and returns the number deleted.
+func (s *UploadSweeper) Sweep(ctx context.Context) (int, error) {
+	before := time.Now().Add(-s.config.GracePeriod)
+	swept := 0
+	for {
+		expired, err := s.store.ListExpired(ctx, before, sweepBatchSize)
+		if err != nil || len(expired) == 0 {
+			return swept, err
+		}
+		for _, u := range expired {
+			if err := s.backend.Delete(ctx, u.Key); err != nil {
+				return swept, err
+			}
+			if err := s.store.DeletePending(ctx, u.Key); err != nil {
+				return swept, err
+			}
+			swept++
+		}
+	}
+}
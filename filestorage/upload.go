@@ -0,0 +1,65 @@
+package filestorage
+
+import (
+	"errors"
+	"hash"
+	"io"
+	"mime/multipart"
+	"net/http"
+)
+
+var errFileTooLarge = errors.New("file too large")
+
+// filePart returns the "file" part of a multipart request without buffering the request
+func filePart(r *http.Request) (*multipart.Part, error) {
+	reader, err := r.MultipartReader()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		part, err := reader.NextPart()
+		if err == io.EOF {
+			return nil, errFileHeaderRequired
+		}
+		if err != nil {
+			return nil, err
+		}
+		if part.FormName() == "file" && part.FileName() != "" {
+			return part, nil
+		}
+		part.Close()
+	}
+}
+
+// uploadReader fails the read once more than maxBytes are read, so the backend aborts the upload
+type uploadReader struct {
+	r          io.Reader
+	maxBytes   int64
+	read       int64
+	tooLarge   bool
+	onProgress func(read int64)
+}
+
+func (u *uploadReader) Read(p []byte) (int, error) {
+	n, err := u.r.Read(p)
+	u.read += int64(n)
+	if isOverMaxBytes(u.maxBytes, 0, u.read) {
+		u.tooLarge = true
+		return 0, errFileTooLarge
+	}
+	if n > 0 && u.onProgress != nil {
+		u.onProgress(u.read)
+	}
+	return n, err
+}
+
+// checksumWriter hashes and counts the bytes of the stored upload
+type checksumWriter struct {
+	hash hash.Hash
+	n    int64
+}
+
+func (w *checksumWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return w.hash.Write(p)
+}
@@ -0,0 +1,98 @@
+package filestorage
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+)
+
+// PendingUpload is a presigned direct upload, it's swept if not confirmed before ExpiresAt
+type PendingUpload struct {
+	Key         string
+	UserID      int
+	ContentType string
+	MaxBytes    int64
+	ExpiresAt   time.Time
+	Confirmed   bool
+	Size        int64
+}
+
+// UploadStore records presigned uploads, GetPending returns apperrors.NotFoundError for
+// unknown keys.
+type UploadStore interface {
+	CreatePending(ctx context.Context, u PendingUpload) error
+	GetPending(ctx context.Context, key string) (*PendingUpload, error)
+	// Confirm marks the upload done with the stored object's metadata
+	Confirm(ctx context.Context, key string, info ObjectInfo) error
+	// ListExpired returns up to limit unconfirmed uploads that expired before the given time
+	ListExpired(ctx context.Context, before time.Time, limit int) ([]PendingUpload, error)
+	DeletePending(ctx context.Context, key string) error
+}
+
+// MemoryUploadStore keeps uploads in memory, meant for tests and single instance setups.
+type MemoryUploadStore struct {
+	mu      sync.Mutex
+	uploads map[string]PendingUpload
+}
+
+func NewMemoryUploadStore() *MemoryUploadStore {
+	return &MemoryUploadStore{uploads: map[string]PendingUpload{}}
+}
+
+func (s *MemoryUploadStore) CreatePending(ctx context.Context, u PendingUpload) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.uploads[u.Key]; ok {
+		return apperrors.NewConflictError("upload", nil)
+	}
+	s.uploads[u.Key] = u
+	return nil
+}
+
+func (s *MemoryUploadStore) GetPending(ctx context.Context, key string) (*PendingUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[key]
+	if !ok {
+		return nil, apperrors.NewNotFoundError("upload")
+	}
+	return &u, nil
+}
+
+func (s *MemoryUploadStore) Confirm(ctx context.Context, key string, info ObjectInfo) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.uploads[key]
+	if !ok {
+		return apperrors.NewNotFoundError("upload")
+	}
+	u.Confirmed, u.Size = true, info.Size
+	s.uploads[key] = u
+	return nil
+}
+
+func (s *MemoryUploadStore) ListExpired(ctx context.Context, before time.Time, limit int) ([]PendingUpload, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var expired []PendingUpload
+	for _, u := range s.uploads {
+		if !u.Confirmed && u.ExpiresAt.Before(before) {
+			expired = append(expired, u)
+		}
+	}
+	sort.Slice(expired, func(i, j int) bool { return expired[i].ExpiresAt.Before(expired[j].ExpiresAt) })
+	if len(expired) > limit {
+		expired = expired[:limit]
+	}
+	return expired, nil
+}
+
+func (s *MemoryUploadStore) DeletePending(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.uploads, key)
+	return nil
+}
@@ -0,0 +1,130 @@
+package flags
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	ReasonDisabled = "disabled"  // flag is disabled
+	ReasonUnknown  = "unknown"   // flag is not defined
+	ReasonTargeted = "targeted"  // user is listed in UserIDs
+	ReasonExcluded = "excluded"  // subject doesn't match Countries or Attributes
+	ReasonMatched  = "matched"   // boolean flag is on for the subject
+	ReasonRollout  = "rollout"   // subject's bucket decided the percentage or variant
+	ReasonNoBucket = "no_bucket" // subject has neither a user id nor a key to bucket on
+)
+
+// Subject is who flags are evaluated for. Rollouts hash the UserID, or Key for anonymous subjects,
+// so a subject keeps getting the same result as long as the flag is unchanged.
+type Subject struct {
+	UserID     int
+	Key        string // stable id of anonymous subjects, e.g. a device id
+	Country    string // alpha2 code, e.g. SigupInfo.Country of the user
+	Attributes map[string]string
+}
+
+func (s Subject) bucketID() string {
+	if s.UserID != 0 {
+		return strconv.Itoa(s.UserID)
+	}
+	return s.Key
+}
+
+type Evaluation struct {
+	Key     string `json:"key"`
+	Enabled bool   `json:"enabled"`
+	Variant string `json:"variant,omitempty"`
+	Reason  string `json:"reason"`
+}
+
+// Evaluate returns the value of flag f for subject s
+func Evaluate(f Flag, s Subject) Evaluation {
+	off := Evaluation{Key: f.Key, Variant: f.DefaultVariant}
+	if !f.Enabled {
+		off.Reason = ReasonDisabled
+		return off
+	}
+
+	if s.UserID != 0 && slices.Contains(f.UserIDs, s.UserID) {
+		res := Evaluation{Key: f.Key, Enabled: true, Variant: f.DefaultVariant, Reason: ReasonTargeted}
+		if f.Type == TypeVariant {
+			res.Variant = f.pickVariant(s.bucketID())
+		}
+		return res
+	}
+	if !f.matches(s) {
+		off.Reason = ReasonExcluded
+		return off
+	}
+
+	on := Evaluation{Key: f.Key, Enabled: true, Variant: f.DefaultVariant, Reason: ReasonMatched}
+	if f.Type == TypeBoolean {
+		return on
+	}
+
+	id := s.bucketID()
+	if id == "" {
+		off.Reason = ReasonNoBucket
+		return off
+	}
+	switch f.Type {
+	case TypePercentage:
+		off.Reason, on.Reason = ReasonRollout, ReasonRollout
+		if bucket(f.Key, id, 100) < uint64(f.Percentage) {
+			return on
+		}
+		return off
+	case TypeVariant:
+		on.Reason = ReasonRollout
+		on.Variant = f.pickVariant(id)
+		return on
+	}
+	off.Reason = ReasonUnknown
+	return off
+}
+
+func (f Flag) matches(s Subject) bool {
+	if len(f.Countries) > 0 && !slices.ContainsFunc(f.Countries, func(c string) bool {
+		return strings.EqualFold(c, s.Country)
+	}) {
+		return false
+	}
+	for attr, values := range f.Attributes {
+		v, ok := s.Attributes[attr]
+		if !ok || !slices.Contains(values, v) {
+			return false
+		}
+	}
+	return true
+}
+
+// pickVariant maps the subject's bucket onto the cumulative variant weights
+func (f Flag) pickVariant(id string) string {
+	var total uint64
+	for _, weight := range f.Variants {
+		total += uint64(weight)
+	}
+	if total == 0 {
+		return f.DefaultVariant
+	}
+
+	b := bucket(f.Key, id, total)
+	var acc uint64
+	for _, name := range f.variantNames() {
+		acc += uint64(f.Variants[name])
+		if b < acc {
+			return name
+		}
+	}
+	return f.DefaultVariant
+}
+
+// bucket deterministically maps id into [0, n) independently for every flag
+func bucket(flagKey, id string, n uint64) uint64 {
+	sum := sha256.Sum256([]byte(flagKey + ":" + id))
+	return binary.BigEndian.Uint64(sum[:8]) % n
+}
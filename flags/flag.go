@@ -0,0 +1,134 @@
+package flags
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krsoninikhil/go-rest-kit/crud"
+	"github.com/krsoninikhil/go-rest-kit/sqldb"
+)
+
+const (
+	TypeBoolean    = "boolean"
+	TypePercentage = "percentage"
+	TypeVariant    = "variant"
+)
+
+// Flag is the definition of a feature flag, loaded from config sources or migrated and stored with Dao.
+// Disabled flags evaluate off for everyone, flags are always on for UserIDs and otherwise limited to
+// subjects matching Countries and Attributes if set.
+type Flag struct {
+	Key            string         `gorm:"uniqueIndex" mapstructure:"key"`
+	Type           string         `mapstructure:"type"`
+	Description    string         `mapstructure:"description"`
+	Enabled        bool           `mapstructure:"enabled"`
+	Percentage     int            `mapstructure:"percentage"`                       // 0-100, for percentage flags
+	Variants       map[string]int `gorm:"serializer:json" mapstructure:"variants"`  // variant name to weight
+	DefaultVariant string         `mapstructure:"default_variant"`                  // variant of subjects the flag is off for
+	Countries      []string       `gorm:"serializer:json" mapstructure:"countries"` // alpha2 codes
+	UserIDs        []int          `gorm:"serializer:json" mapstructure:"user_ids"`
+	// Attributes limits the flag to subjects having one of the listed values of every attribute, e.g. "aud"
+	Attributes      map[string][]string `gorm:"serializer:json" mapstructure:"attributes"`
+	sqldb.BaseModel `mapstructure:",squash"`
+}
+
+func (f Flag) ResourceName() string { return "flag" }
+
+func (f Flag) validate() error {
+	if f.Key == "" {
+		return fmt.Errorf("flag key is required")
+	}
+	switch f.Type {
+	case TypeBoolean:
+	case TypePercentage:
+		if f.Percentage < 0 || f.Percentage > 100 {
+			return fmt.Errorf("percentage of %s must be between 0 and 100", f.Key)
+		}
+	case TypeVariant:
+		if len(f.Variants) == 0 {
+			return fmt.Errorf("variant flag %s has no variants", f.Key)
+		}
+		for name, weight := range f.Variants {
+			if weight < 0 {
+				return fmt.Errorf("weight of variant %s of %s is negative", name, f.Key)
+			}
+		}
+	default:
+		return fmt.Errorf("unknown type %q of flag %s", f.Type, f.Key)
+	}
+	return nil
+}
+
+// variantNames returns the variant names sorted, so that buckets map to the same variant
+// irrespective of the map order.
+func (f Flag) variantNames() []string {
+	names := make([]string, 0, len(f.Variants))
+	for name := range f.Variants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+type (
+	FlagRequest struct {
+		Key            string              `json:"key" binding:"required"`
+		Type           string              `json:"type" binding:"required,oneof=boolean percentage variant"`
+		Description    string              `json:"description"`
+		Enabled        bool                `json:"enabled"`
+		Percentage     int                 `json:"percentage" binding:"min=0,max=100"`
+		Variants       map[string]int      `json:"variants"`
+		DefaultVariant string              `json:"default_variant"`
+		Countries      []string            `json:"countries"`
+		UserIDs        []int               `json:"user_ids"`
+		Attributes     map[string][]string `json:"attributes"`
+	}
+
+	FlagResponse struct {
+		ID             int                 `json:"id"`
+		Key            string              `json:"key"`
+		Type           string              `json:"type"`
+		Description    string              `json:"description,omitempty"`
+		Enabled        bool                `json:"enabled"`
+		Percentage     int                 `json:"percentage,omitempty"`
+		Variants       map[string]int      `json:"variants,omitempty"`
+		DefaultVariant string              `json:"default_variant,omitempty"`
+		Countries      []string            `json:"countries,omitempty"`
+		UserIDs        []int               `json:"user_ids,omitempty"`
+		Attributes     map[string][]string `json:"attributes,omitempty"`
+	}
+)
+
+func (r FlagRequest) ToModel(_ *gin.Context) Flag {
+	return Flag{
+		Key:            r.Key,
+		Type:           r.Type,
+		Description:    r.Description,
+		Enabled:        r.Enabled,
+		Percentage:     r.Percentage,
+		Variants:       r.Variants,
+		DefaultVariant: r.DefaultVariant,
+		Countries:      r.Countries,
+		UserIDs:        r.UserIDs,
+		Attributes:     r.Attributes,
+	}
+}
+
+func (r FlagResponse) FillFromModel(m Flag) crud.Response[Flag] {
+	return FlagResponse{
+		ID:             m.ID,
+		Key:            m.Key,
+		Type:           m.Type,
+		Description:    m.Description,
+		Enabled:        m.Enabled,
+		Percentage:     m.Percentage,
+		Variants:       m.Variants,
+		DefaultVariant: m.DefaultVariant,
+		Countries:      m.Countries,
+		UserIDs:        m.UserIDs,
+		Attributes:     m.Attributes,
+	}
+}
+
+func (r FlagResponse) ItemID() int { return r.ID }
@@ -0,0 +1,141 @@
+package flags
+
+import (
+	"context"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/krsoninikhil/go-rest-kit/auth"
+	"github.com/krsoninikhil/go-rest-kit/cache"
+)
+
+func TestEvaluate(t *testing.T) {
+	rollout := Flag{Key: "new-checkout", Type: TypePercentage, Enabled: true, Percentage: 30}
+	var on int
+	for id := 1; id <= 10000; id++ {
+		res := Evaluate(rollout, Subject{UserID: id})
+		if res != Evaluate(rollout, Subject{UserID: id}) {
+			t.Fatalf("evaluation of user %d is not deterministic", id)
+		}
+		if res.Enabled {
+			on++
+		}
+	}
+	if on < 2800 || on > 3200 {
+		t.Errorf("expected ~30%% of users in rollout, got %d of 10000", on)
+	}
+	if res := Evaluate(rollout, Subject{}); res.Enabled || res.Reason != ReasonNoBucket {
+		t.Errorf("expected rollout off without a subject id, got %+v", res)
+	}
+
+	variants := Flag{
+		Key: "pricing", Type: TypeVariant, Enabled: true, DefaultVariant: "control",
+		Variants: map[string]int{"control": 1, "annual": 1},
+	}
+	counts := map[string]int{}
+	for id := 1; id <= 1000; id++ {
+		counts[Evaluate(variants, Subject{Key: "device-" + strconv.Itoa(id)}).Variant]++
+	}
+	if counts["control"] < 400 || counts["annual"] < 400 {
+		t.Errorf("expected even variant split, got %v", counts)
+	}
+
+	targeted := Flag{
+		Key: "beta", Type: TypeBoolean, Enabled: true, Countries: []string{"IN"},
+		Attributes: map[string][]string{"aud": {"login"}}, UserIDs: []int{7},
+	}
+	cases := []struct {
+		subject Subject
+		enabled bool
+		reason  string
+	}{
+		{Subject{UserID: 1, Country: "in", Attributes: map[string]string{"aud": "login"}}, true, ReasonMatched},
+		{Subject{UserID: 1, Country: "US", Attributes: map[string]string{"aud": "login"}}, false, ReasonExcluded},
+		{Subject{UserID: 1, Country: "IN"}, false, ReasonExcluded},
+		{Subject{UserID: 7, Country: "US"}, true, ReasonTargeted},
+	}
+	for _, tc := range cases {
+		if res := Evaluate(targeted, tc.subject); res.Enabled != tc.enabled || res.Reason != tc.reason {
+			t.Errorf("Evaluate(%+v) = %+v, expected enabled=%v reason=%s", tc.subject, res, tc.enabled, tc.reason)
+		}
+	}
+
+	targeted.Enabled = false
+	if res := Evaluate(targeted, Subject{UserID: 7}); res.Enabled || res.Reason != ReasonDisabled {
+		t.Errorf("expected disabled flag to be off, got %+v", res)
+	}
+}
+
+func TestService_CachesAndInvalidates(t *testing.T) {
+	var (
+		loads int
+		flags = []Flag{{Key: "beta", Type: TypeBoolean, Enabled: true}, {Key: "broken", Type: "unknown"}}
+	)
+	store := StoreFunc(func(context.Context) ([]Flag, error) {
+		loads++
+		return flags, nil
+	})
+	memCache := cache.NewInMemory()
+	svc := NewService(store, memCache, 0)
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		if res, err := svc.Evaluate(ctx, "beta", Subject{UserID: i}); err != nil || !res.Enabled {
+			t.Fatalf("expected beta on, got %+v, %v", res, err)
+		}
+	}
+	if res, _ := svc.Evaluate(ctx, "broken", Subject{}); res.Reason != ReasonUnknown {
+		t.Errorf("expected invalid flag to be skipped, got %+v", res)
+	}
+	if loads != 1 {
+		t.Errorf("expected flags to be loaded once, loaded %d times", loads)
+	}
+
+	flags = []Flag{{Key: "beta", Type: TypeBoolean}}
+	if res, _ := svc.Evaluate(ctx, "beta", Subject{UserID: 1}); !res.Enabled {
+		t.Errorf("expected cached flags before invalidation, got %+v", res)
+	}
+	svc.Invalidate()
+	if _, err := memCache.Get(defsCacheKey); err == nil {
+		t.Error("expected invalidation to delete the cached flags")
+	}
+	if res, _ := svc.Evaluate(ctx, "beta", Subject{UserID: 1}); res.Enabled || loads != 2 {
+		t.Errorf("expected reloaded flag to be off, got %+v after %d loads", res, loads)
+	}
+}
+
+func TestGinMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	svc := NewService(Static([]Flag{
+		{Key: "beta", Type: TypeBoolean, Enabled: true, Countries: []string{"IN"}, Attributes: map[string][]string{"aud": {"login"}}},
+		{Key: "theme", Type: TypeVariant, Enabled: true, Variants: map[string]int{"dark": 1}, DefaultVariant: "light"},
+	}), cache.NewInMemory(), 0)
+
+	c, _ := gin.CreateTestContext(httptest.NewRecorder())
+	c.Request = httptest.NewRequest("GET", "/", nil)
+	if Enabled(c, "beta") {
+		t.Error("expected flags off without the middleware")
+	}
+
+	c.Set(auth.CtxKeyUserID, "42")
+	c.Set(auth.CtxKeyTokenClaims, jwt.StandardClaims{Subject: "42", Audience: "login"})
+	GinMiddleware(svc, &MiddlewareOpts{
+		Country: func(_ *gin.Context, userID int) string { return "IN" },
+	})(c)
+
+	if s := CurrentSubject(c); s.UserID != 42 || s.Country != "IN" || s.Attributes["aud"] != "login" {
+		t.Fatalf("unexpected subject %+v", s)
+	}
+	if !Enabled(c, "beta") {
+		t.Error("expected beta on for the request")
+	}
+	if v := Variant(c, "theme"); v != "dark" {
+		t.Errorf("expected dark theme, got %q", v)
+	}
+	if Enabled(c, "missing") {
+		t.Error("expected unknown flag off")
+	}
+}
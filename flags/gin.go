@@ -0,0 +1,118 @@
+package flags
+
+import (
+	"log"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt"
+	"github.com/krsoninikhil/go-rest-kit/auth"
+)
+
+const (
+	CtxKeyService = "flagService"
+	CtxKeySubject = "flagSubject"
+)
+
+type MiddlewareOpts struct {
+	// Country returns the country of the user, e.g. the SigupInfo.Country saved on the user model.
+	// It's called for authenticated requests only.
+	Country func(c *gin.Context, userID int) string
+	// AnonymousKey returns a stable key of unauthenticated clients to bucket them in rollouts,
+	// e.g. a device id header. Without it, rollouts are off for unauthenticated requests.
+	AnonymousKey func(c *gin.Context) string
+}
+
+// GinMiddleware makes svc and the request's Subject available to Enabled, Variant and Get.
+// Use it after the auth middleware, the subject gets the user id and the "sub", "aud" and
+// "iss" claims as attributes from it.
+func GinMiddleware(svc *Service, opts *MiddlewareOpts) gin.HandlerFunc {
+	if opts == nil {
+		opts = &MiddlewareOpts{}
+	}
+	return func(c *gin.Context) {
+		subject := Subject{UserID: auth.UserID(c), Attributes: claimAttributes(c)}
+		if subject.UserID != 0 && opts.Country != nil {
+			subject.Country = opts.Country(c, subject.UserID)
+		}
+		if subject.UserID == 0 && opts.AnonymousKey != nil {
+			subject.Key = opts.AnonymousKey(c)
+		}
+		c.Set(CtxKeyService, svc)
+		c.Set(CtxKeySubject, subject)
+		c.Next()
+	}
+}
+
+func claimAttributes(c *gin.Context) map[string]string {
+	val, _ := c.Get(auth.CtxKeyTokenClaims)
+	attrs := map[string]string{}
+	switch claims := val.(type) {
+	case jwt.StandardClaims:
+		attrs["sub"], attrs["aud"], attrs["iss"] = claims.Subject, claims.Audience, claims.Issuer
+	case *jwt.StandardClaims:
+		attrs["sub"], attrs["aud"], attrs["iss"] = claims.Subject, claims.Audience, claims.Issuer
+	case jwt.MapClaims:
+		for _, k := range []string{"sub", "aud", "iss"} {
+			if v, ok := claims[k].(string); ok {
+				attrs[k] = v
+			}
+		}
+	}
+	for k, v := range attrs {
+		if v == "" {
+			delete(attrs, k)
+		}
+	}
+	return attrs
+}
+
+// CurrentSubject returns the subject set by GinMiddleware
+func CurrentSubject(c *gin.Context) Subject {
+	val, _ := c.Get(CtxKeySubject)
+	subject, _ := val.(Subject)
+	return subject
+}
+
+// Get evaluates flag key for the request, flags are off if GinMiddleware is not used or
+// evaluation fails.
+func Get(c *gin.Context, key string) Evaluation {
+	val, _ := c.Get(CtxKeyService)
+	svc, ok := val.(*Service)
+	if !ok {
+		log.Printf("flags: GinMiddleware is not used, %s is off", key)
+		return Evaluation{Key: key, Reason: ReasonUnknown}
+	}
+	res, err := svc.Evaluate(c, key, CurrentSubject(c))
+	if err != nil {
+		log.Printf("flags: error evaluating %s, it's off: %v", key, err)
+	}
+	return res
+}
+
+// Enabled reports whether flag key is on for the request
+func Enabled(c *gin.Context, key string) bool { return Get(c, key).Enabled }
+
+// Variant returns the variant of flag key for the request, the default variant if it's off
+func Variant(c *gin.Context, key string) string { return Get(c, key).Variant }
+
+type Controller struct {
+	svc *Service
+}
+
+func NewController(svc *Service) *Controller {
+	return &Controller{svc: svc}
+}
+
+type EvaluationsResponse struct {
+	Flags map[string]Evaluation `json:"flags"`
+}
+
+// Evaluations returns all flags for the request's subject, e.g. for clients to toggle features.
+// Route it with request.BindGet behind GinMiddleware.
+func (ctrl *Controller) Evaluations(c *gin.Context, _ struct{}) (*EvaluationsResponse, error) {
+	res, err := ctrl.svc.EvaluateAll(c, CurrentSubject(c))
+	if err != nil {
+		return nil, err
+	}
+	return &EvaluationsResponse{Flags: res}, nil
+}
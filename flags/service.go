@@ -0,0 +1,151 @@
+package flags
+
+import (
+	"context"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+	"github.com/krsoninikhil/go-rest-kit/crud"
+)
+
+const (
+	defaultCacheTTL = time.Minute
+	defsCacheKey    = "flags:defs"
+)
+
+type cacheClient interface {
+	Set(key string, value any, ttl time.Duration) error
+	Get(key string) (any, error)
+	Delete(key string) error
+}
+
+// Service evaluates flags of a Store, caching the definitions for ttl. Evaluation is cheap
+// and done on every call, so the cache holds a single entry.
+type Service struct {
+	store      Store
+	cache      cacheClient
+	ttl        time.Duration
+	generation atomic.Int64
+}
+
+// NewService returns a Service caching in cache, e.g. cache.NewInMemory(), for ttl or a
+// minute if ttl is zero.
+func NewService(store Store, cache cacheClient, ttl time.Duration) *Service {
+	if ttl == 0 {
+		ttl = defaultCacheTTL
+	}
+	s := &Service{store: store, cache: cache, ttl: ttl}
+	if n, ok := store.(interface{ onChange(func()) }); ok {
+		n.onChange(s.Invalidate)
+	}
+	return s
+}
+
+// Invalidate drops the cached flags, it's called on changes by admin routes and config reloads.
+func (s *Service) Invalidate() {
+	s.generation.Add(1)
+	if err := s.cache.Delete(defsCacheKey); err != nil {
+		log.Printf("flags: error invalidating flags: %v", err)
+	}
+}
+
+// Evaluate returns the value of flag key for subject, unknown flags are off.
+func (s *Service) Evaluate(ctx context.Context, key string, subject Subject) (Evaluation, error) {
+	flags, err := s.flags(ctx)
+	if err != nil {
+		return Evaluation{Key: key}, err
+	}
+	f, ok := flags[key]
+	if !ok {
+		return Evaluation{Key: key, Reason: ReasonUnknown}, nil
+	}
+	return Evaluate(f, subject), nil
+}
+
+// EvaluateAll returns the values of all flags for subject by flag key
+func (s *Service) EvaluateAll(ctx context.Context, subject Subject) (map[string]Evaluation, error) {
+	flags, err := s.flags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	res := make(map[string]Evaluation, len(flags))
+	for key, f := range flags {
+		res[key] = Evaluate(f, subject)
+	}
+	return res, nil
+}
+
+func (s *Service) flags(ctx context.Context) (map[string]Flag, error) {
+	if v, err := s.cache.Get(defsCacheKey); err == nil {
+		if flags, ok := v.(map[string]Flag); ok {
+			return flags, nil
+		}
+	}
+
+	generation := s.generation.Load()
+	list, err := s.store.Flags(ctx)
+	if err != nil {
+		return nil, err
+	}
+	flags := make(map[string]Flag, len(list))
+	for _, f := range list {
+		if err := f.validate(); err != nil {
+			log.Printf("flags: skipping invalid flag: %v", err)
+			continue
+		}
+		flags[f.Key] = f
+	}
+	// flags loaded before an Invalidate may be stale, they're used but not cached
+	if s.generation.Load() != generation {
+		return flags, nil
+	}
+	if err := s.cache.Set(defsCacheKey, flags, s.ttl); err != nil {
+		log.Printf("flags: error caching flags: %v", err)
+	}
+	return flags, nil
+}
+
+type adminService struct {
+	*Dao
+	svc *Service
+}
+
+// NewAdminService returns the crud.Service for Flag that validates flags and invalidates the
+// cache of svc on changes, use it with crud.Controller[Flag, FlagResponse, FlagRequest] to
+// route the admin routes. Changes are visible to other instances once their cache expires.
+func NewAdminService(dao *Dao, svc *Service) crud.Service[Flag] {
+	return &adminService{Dao: dao, svc: svc}
+}
+
+func (s *adminService) Create(ctx context.Context, m Flag) (*Flag, error) {
+	if err := m.validate(); err != nil {
+		return nil, apperrors.NewInvalidParamsError(m.ResourceName(), err)
+	}
+	defer s.svc.Invalidate()
+	return s.Dao.Create(ctx, m)
+}
+
+func (s *adminService) Update(ctx context.Context, id int, m Flag) (*Flag, error) {
+	if err := m.validate(); err != nil {
+		return nil, apperrors.NewInvalidParamsError(m.ResourceName(), err)
+	}
+	defer s.svc.Invalidate()
+	return s.Dao.Update(ctx, id, m)
+}
+
+func (s *adminService) Delete(ctx context.Context, id int) error {
+	defer s.svc.Invalidate()
+	return s.Dao.Delete(ctx, id)
+}
+
+func (s *adminService) BulkCreate(ctx context.Context, m []Flag) error {
+	for _, f := range m {
+		if err := f.validate(); err != nil {
+			return apperrors.NewInvalidParamsError(f.ResourceName(), err)
+		}
+	}
+	defer s.svc.Invalidate()
+	return s.Dao.BulkCreate(ctx, m)
+}
@@ -0,0 +1,75 @@
+package flags
+
+import (
+	"context"
+	"errors"
+
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+	"github.com/krsoninikhil/go-rest-kit/config"
+	"github.com/krsoninikhil/go-rest-kit/crud"
+	"github.com/krsoninikhil/go-rest-kit/sqldb"
+	"gorm.io/gorm"
+)
+
+// Store provides the flag definitions, Service caches them so stores are not expected to.
+type Store interface {
+	Flags(ctx context.Context) ([]Flag, error)
+}
+
+type StoreFunc func(ctx context.Context) ([]Flag, error)
+
+func (f StoreFunc) Flags(ctx context.Context) ([]Flag, error) { return f(ctx) }
+
+// Static returns a Store of flags, e.g. a `flags` list of a config loaded with config.Load
+func Static(flags []Flag) Store {
+	return StoreFunc(func(context.Context) ([]Flag, error) { return flags, nil })
+}
+
+type configStore[T any] struct {
+	value *config.Value[T]
+	flags func(*T) []Flag
+}
+
+// FromConfig returns a Store of the flags of a config reloaded with config.Watch, a Service
+// using it drops cached flags on every reload.
+func FromConfig[T any](value *config.Value[T], flags func(*T) []Flag) Store {
+	return &configStore[T]{value: value, flags: flags}
+}
+
+func (s *configStore[T]) Flags(context.Context) ([]Flag, error) {
+	return s.flags(s.value.Get()), nil
+}
+
+func (s *configStore[T]) onChange(fn func()) {
+	s.value.Subscribe(func(_, _ *T) { fn() })
+}
+
+// Dao stores flags in the database, it's a crud.Dao that also persists zero values on Update
+// so that flags can be disabled.
+type Dao struct {
+	crud.Dao[Flag]
+}
+
+func NewDao(db *sqldb.PGDB) *Dao {
+	return &Dao{crud.Dao[Flag]{PGDB: db}}
+}
+
+func (d *Dao) Flags(ctx context.Context) ([]Flag, error) {
+	var res []Flag
+	if err := d.DB(ctx).Find(&res).Error; err != nil {
+		return nil, apperrors.NewServerError(err)
+	}
+	return res, nil
+}
+
+func (d *Dao) Update(ctx context.Context, id int, m Flag) (*Flag, error) {
+	res := d.DB(ctx).Model(&m).Where("id = ?", id).Select("*").Omit("id", "created_at", "deleted_at").Updates(m)
+	if errors.Is(res.Error, gorm.ErrDuplicatedKey) {
+		return nil, apperrors.NewConflictError(m.ResourceName(), res.Error)
+	} else if res.Error != nil {
+		return nil, apperrors.NewServerError(res.Error)
+	} else if res.RowsAffected == 0 {
+		return nil, apperrors.NewNotFoundError(m.ResourceName())
+	}
+	return &m, nil
+}
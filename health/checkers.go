@@ -0,0 +1,100 @@
+package health
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type (
+	// gormDB is implemented by sqldb.PGDB and sqldb.SQLiteDB
+	gormDB interface {
+		DB(ctx context.Context) *gorm.DB
+	}
+	cacheClient interface {
+		Set(key string, value any, ttl time.Duration) error
+		Get(key string) (any, error)
+	}
+	// bucket is implemented by aws.S3
+	bucket interface {
+		HeadBucket(ctx context.Context) error
+	}
+	// Pinger is implemented by clients of services with a ping, e.g. filestorage.ClamdScanner
+	Pinger interface {
+		Ping(ctx context.Context) error
+	}
+)
+
+// DB checks that a connection to the database can be made, e.g. DB(pgdb) or DB(sqliteDB)
+func DB(db gormDB) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		sqlDB, err := db.DB(ctx).DB()
+		if err != nil {
+			return err
+		}
+		return sqlDB.PingContext(ctx)
+	})
+}
+
+// Cache checks that a value set in the cache can be read back
+func Cache(cache cacheClient) Checker {
+	return CheckerFunc(func(ctx context.Context) error {
+		b := make([]byte, 8)
+		if _, err := rand.Read(b); err != nil {
+			return err
+		}
+		key, want := "health:"+hex.EncodeToString(b), hex.EncodeToString(b)
+		if err := cache.Set(key, want, time.Minute); err != nil {
+			return fmt.Errorf("cache set: %w", err)
+		}
+		got, err := cache.Get(key)
+		if err != nil {
+			return fmt.Errorf("cache get: %w", err)
+		}
+		if got != want {
+			return fmt.Errorf("cache returned %v for %s", got, key)
+		}
+		if d, ok := cache.(interface{ Delete(string) error }); ok {
+			return d.Delete(key)
+		}
+		return nil
+	})
+}
+
+// S3 checks that the bucket is accessible, e.g. S3(s3) with an aws.S3
+func S3(s3 bucket) Checker {
+	return CheckerFunc(s3.HeadBucket)
+}
+
+// Ping checks an integration with a Ping method
+func Ping(p Pinger) Checker {
+	return CheckerFunc(p.Ping)
+}
+
+// HTTP checks that a GET of url responds without a server error, e.g. for integrations
+// exposing a status endpoint. Use an Optional check for third party services.
+func HTTP(client *http.Client, url string) Checker {
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return CheckerFunc(func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+		res, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer res.Body.Close()
+		if res.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("GET %s: %s", url, res.Status)
+		}
+		return nil
+	})
+}
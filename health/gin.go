@@ -0,0 +1,28 @@
+package health
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// LivezHandler responds with the liveness report, 503 if a liveness check fails. Route it at /livez
+// before any auth middleware.
+func (r *Registry) LivezHandler() gin.HandlerFunc {
+	return func(c *gin.Context) { respond(c, r.Live(c.Request.Context())) }
+}
+
+// ReadyzHandler responds with the readiness report, 503 if a required check fails or the app is
+// shutting down. Route it at /readyz before any auth middleware.
+func (r *Registry) ReadyzHandler() gin.HandlerFunc {
+	return func(c *gin.Context) { respond(c, r.Ready(c.Request.Context())) }
+}
+
+func respond(c *gin.Context, report Report) {
+	status := http.StatusOK
+	if !report.Healthy() {
+		status = http.StatusServiceUnavailable
+	}
+	c.Header("Cache-Control", "no-store")
+	c.JSON(status, report)
+}
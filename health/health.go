@@ -0,0 +1,208 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	StatusOK           = "ok"
+	StatusFailing      = "failing"
+	StatusDegraded     = "degraded"      // only optional checks are failing
+	StatusShuttingDown = "shutting_down" // readiness after Shutdown is called
+)
+
+type Config struct {
+	Timeout    time.Duration `mapstructure:"timeout"`     // per check, defaults to 2s
+	CacheTTL   time.Duration `mapstructure:"cache_ttl"`   // how long results are reused, defaults to 5s
+	DrainDelay time.Duration `mapstructure:"drain_delay"` // wait after failing readiness on Shutdown, defaults to 5s
+}
+
+type Checker interface {
+	Check(ctx context.Context) error
+}
+
+type CheckerFunc func(ctx context.Context) error
+
+func (f CheckerFunc) Check(ctx context.Context) error { return f(ctx) }
+
+type CheckOption func(*check)
+
+// WithTimeout overrides Config.Timeout for the check
+func WithTimeout(d time.Duration) CheckOption { return func(c *check) { c.timeout = d } }
+
+// Optional makes the check's failure degrade instead of fail readiness, e.g. for integrations
+// the app can serve without.
+func Optional() CheckOption { return func(c *check) { c.optional = true } }
+
+// Liveness runs the check for /livez too. Liveness failure restarts the app, so only use it for
+// checks that a restart can fix, never for shared dependencies like the database.
+func Liveness() CheckOption { return func(c *check) { c.liveness = true } }
+
+type Result struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	Optional  bool      `json:"optional,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Healthy reports whether the probe should pass
+func (r Report) Healthy() bool { return r.Status == StatusOK || r.Status == StatusDegraded }
+
+type check struct {
+	name     string
+	checker  Checker
+	timeout  time.Duration
+	optional bool
+	liveness bool
+
+	mu     sync.Mutex // serializes runs so concurrent probes share a result
+	result Result
+}
+
+// Registry holds named checks and reports liveness and readiness from their cached results.
+type Registry struct {
+	config       Config
+	mu           sync.RWMutex
+	checks       []*check
+	shuttingDown atomic.Bool
+}
+
+func NewRegistry(config Config) *Registry {
+	if config.Timeout == 0 {
+		config.Timeout = 2 * time.Second
+	}
+	if config.CacheTTL == 0 {
+		config.CacheTTL = 5 * time.Second
+	}
+	if config.DrainDelay == 0 {
+		config.DrainDelay = 5 * time.Second
+	}
+	return &Registry{config: config}
+}
+
+// Register adds a readiness check, registering a name again replaces the check.
+func (r *Registry) Register(name string, checker Checker, opts ...CheckOption) {
+	c := &check{name: name, checker: checker, timeout: r.config.Timeout}
+	for _, opt := range opts {
+		opt(c)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, existing := range r.checks {
+		if existing.name == name {
+			r.checks[i] = c
+			return
+		}
+	}
+	r.checks = append(r.checks, c)
+}
+
+// Live runs the liveness checks, it doesn't depend on readiness so a draining app isn't restarted.
+func (r *Registry) Live(ctx context.Context) Report {
+	return r.run(ctx, func(c *check) bool { return c.liveness })
+}
+
+// Ready runs all checks, it fails once Shutdown is called.
+func (r *Registry) Ready(ctx context.Context) Report {
+	if r.shuttingDown.Load() {
+		return Report{Status: StatusShuttingDown}
+	}
+	return r.run(ctx, func(*check) bool { return true })
+}
+
+func (r *Registry) run(ctx context.Context, include func(*check) bool) Report {
+	r.mu.RLock()
+	var checks []*check
+	for _, c := range r.checks {
+		if include(c) {
+			checks = append(checks, c)
+		}
+	}
+	r.mu.RUnlock()
+
+	var (
+		wg      sync.WaitGroup
+		results = make([]Result, len(checks))
+	)
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = c.run(ctx, r.config.CacheTTL)
+		}()
+	}
+	wg.Wait()
+
+	report := Report{Status: StatusOK, Checks: make(map[string]Result, len(checks))}
+	for i, c := range checks {
+		report.Checks[c.name] = results[i]
+		if results[i].Status == StatusOK {
+			continue
+		}
+		if !c.optional {
+			report.Status = StatusFailing
+		} else if report.Status == StatusOK {
+			report.Status = StatusDegraded
+		}
+	}
+	return report
+}
+
+func (c *check) run(ctx context.Context, ttl time.Duration) Result {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.result.CheckedAt.IsZero() && time.Since(c.result.CheckedAt) < ttl {
+		return c.result
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+	start := time.Now()
+	err := c.safeCheck(ctx)
+
+	res := Result{Status: StatusOK, Optional: c.optional, Duration: time.Since(start).String(), CheckedAt: start}
+	if err != nil {
+		res.Status = StatusFailing
+		res.Error = err.Error()
+		if errors.Is(err, context.DeadlineExceeded) {
+			res.Error = fmt.Sprintf("timed out after %s", c.timeout)
+		}
+	}
+	if ctx.Err() == nil || errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		// cancelled probes are not cached, they say nothing about the dependency
+		c.result = res
+	}
+	return res
+}
+
+// safeCheck runs the checker in a goroutine so a checker ignoring ctx can't block the probe
+// past its timeout.
+func (c *check) safeCheck(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				done <- fmt.Errorf("check panicked: %v", p)
+			}
+		}()
+		done <- c.checker.Check(ctx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,90 @@
+package health
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krsoninikhil/go-rest-kit/cache"
+)
+
+func TestRegistry_Ready(t *testing.T) {
+	var calls atomic.Int32
+	r := NewRegistry(Config{Timeout: 50 * time.Millisecond, CacheTTL: time.Minute, DrainDelay: time.Millisecond})
+	r.Register("cache", Cache(cache.NewInMemory()))
+	r.Register("counted", CheckerFunc(func(context.Context) error {
+		calls.Add(1)
+		return nil
+	}))
+	r.Register("mailgun", CheckerFunc(func(context.Context) error { return errors.New("unreachable") }), Optional())
+	ctx := context.Background()
+
+	report := r.Ready(ctx)
+	if report.Status != StatusDegraded || !report.Healthy() || report.Checks["mailgun"].Error != "unreachable" {
+		t.Fatalf("expected degraded report, got %+v", report)
+	}
+	r.Ready(ctx)
+	if calls.Load() != 1 {
+		t.Errorf("expected cached result to be reused, check ran %d times", calls.Load())
+	}
+
+	r.Register("db", CheckerFunc(func(ctx context.Context) error {
+		time.Sleep(time.Second) // ignores ctx
+		return nil
+	}), WithTimeout(10*time.Millisecond))
+	start := time.Now()
+	report = r.Ready(ctx)
+	if report.Status != StatusFailing || report.Checks["db"].Error != "timed out after 10ms" {
+		t.Errorf("expected timed out db to fail readiness, got %+v", report)
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Errorf("expected probe to return on timeout, took %s", time.Since(start))
+	}
+	if live := r.Live(ctx); live.Status != StatusOK || len(live.Checks) != 0 {
+		t.Errorf("expected readiness checks to be excluded from liveness, got %+v", live)
+	}
+}
+
+func TestRegistry_Handlers(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := NewRegistry(Config{DrainDelay: time.Millisecond})
+	r.Register("db", CheckerFunc(func(context.Context) error { return nil }))
+	router := gin.New()
+	router.GET("/livez", r.LivezHandler())
+	router.GET("/readyz", r.ReadyzHandler())
+
+	get := func(path string) (int, Report) {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		var report Report
+		if err := json.Unmarshal(w.Body.Bytes(), &report); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+		return w.Code, report
+	}
+
+	if code, report := get("/readyz"); code != http.StatusOK || report.Checks["db"].Status != StatusOK {
+		t.Errorf("expected ready, got %d %+v", code, report)
+	}
+
+	var drained bool
+	err := r.Shutdown(context.Background(), func(context.Context) error {
+		drained = true
+		if code, _ := get("/readyz"); code != http.StatusServiceUnavailable {
+			t.Errorf("expected readiness to fail before draining, got %d", code)
+		}
+		return nil
+	})
+	if err != nil || !drained {
+		t.Fatalf("expected drain to be called, got %v", err)
+	}
+	if code, report := get("/livez"); code != http.StatusOK || report.Status != StatusOK {
+		t.Errorf("expected app to stay live while draining, got %d %+v", code, report)
+	}
+}
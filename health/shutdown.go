@@ -0,0 +1,51 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"time"
+)
+
+// Shutdown fails readiness, waits for Config.DrainDelay so load balancers stop routing new
+// requests to the app and then calls drain, e.g. http.Server.Shutdown, to finish in-flight ones.
+// The wait ends early if ctx is done.
+func (r *Registry) Shutdown(ctx context.Context, drain func(ctx context.Context) error) error {
+	r.shuttingDown.Store(true)
+	log.Printf("health: readiness failing, draining in %s", r.config.DrainDelay)
+
+	timer := time.NewTimer(r.config.DrainDelay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+	if drain == nil {
+		return nil
+	}
+	return drain(ctx)
+}
+
+// ListenAndServe serves srv until ctx is done, e.g. signal.NotifyContext(ctx, syscall.SIGTERM),
+// and then shuts it down with Shutdown, giving in-flight requests up to timeout to finish.
+func (r *Registry) ListenAndServe(ctx context.Context, srv *http.Server, timeout time.Duration) error {
+	errc := make(chan error, 1)
+	go func() { errc <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), r.config.DrainDelay+timeout)
+	defer cancel()
+	if err := r.Shutdown(shutdownCtx, srv.Shutdown); err != nil {
+		return err
+	}
+	if err := <-errc; !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return nil
+}
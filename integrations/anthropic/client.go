@@ -4,9 +4,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/pkg/errors"
 )
@@ -15,13 +15,18 @@ const (
 	defaultBaseURL          = "https://api.anthropic.com"
 	defaultAnthropicVersion = "2023-06-01"
 	defaultMaxTokens        = 4096
+	defaultTimeoutSeconds   = 60
 )
 
 // Config holds Anthropic client configuration.
 type Config struct {
-	APIKey  string `json:"-"` // sensitive
-	Model   string // e.g. claude-3-5-haiku-20241022
-	BaseURL string // optional; default https://api.anthropic.com
+	APIKey    string `json:"-"` // sensitive
+	Model     string // e.g. claude-3-5-haiku-20241022
+	BaseURL   string // optional; default https://api.anthropic.com
+	MaxTokens int    // optional; default 4096, used when the request doesn't set it
+	// TimeoutSeconds is the max wait for response headers, optional; default 60.
+	// It doesn't limit reading a stream, use ctx for that.
+	TimeoutSeconds int
 }
 
 // Client calls the Anthropic Messages API.
@@ -32,71 +37,100 @@ type Client struct {
 
 // NewClient creates an Anthropic client. If BaseURL is empty, default is used.
 func NewClient(config Config) *Client {
-	baseURL := config.BaseURL
-	if baseURL == "" {
-		baseURL = defaultBaseURL
-	}
-	return &Client{
-		config: Config{
-			APIKey:  config.APIKey,
-			Model:   config.Model,
-			BaseURL: baseURL,
-		},
-		http: &http.Client{},
+	if config.BaseURL == "" {
+		config.BaseURL = defaultBaseURL
 	}
+	if config.MaxTokens == 0 {
+		config.MaxTokens = defaultMaxTokens
+	}
+	if config.TimeoutSeconds == 0 {
+		config.TimeoutSeconds = defaultTimeoutSeconds
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = time.Duration(config.TimeoutSeconds) * time.Second
+	return &Client{config: config, http: &http.Client{Transport: transport}}
 }
 
 // Chat sends system and user messages to Anthropic and returns the assistant's text content.
 func (c *Client) Chat(ctx context.Context, system, user string) (content string, err error) {
-	if c.config.APIKey == "" {
-		return "", errors.New("anthropic: API key is required")
+	out, err := c.Messages(ctx, MessagesRequest{
+		System:   system,
+		Messages: []Message{UserMessage(TextBlock(user))},
+	})
+	if err != nil {
+		return "", err
 	}
-	if c.config.Model == "" {
-		return "", errors.New("anthropic: model is required")
+	if content = out.Text(); content == "" {
+		return "", errors.New("anthropic: no text content in response")
 	}
+	return content, nil
+}
 
-	body := MessagesRequest{
-		Model:     c.config.Model,
-		MaxTokens: defaultMaxTokens,
-		System:    system,
-		Messages:  []Message{{Role: "user", Content: user}},
+// Messages sends a Messages API request and returns the complete response.
+func (c *Client) Messages(ctx context.Context, body MessagesRequest) (*MessagesResponse, error) {
+	body.Stream = false
+	resp, err := c.do(ctx, body)
+	if err != nil {
+		return nil, err
 	}
+	defer resp.Body.Close()
+
+	var out MessagesResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, errors.Wrap(err, "anthropic: decode response")
+	}
+	return &out, nil
+}
+
+// Stream sends a streaming Messages API request, caller must Close the returned stream.
+func (c *Client) Stream(ctx context.Context, body MessagesRequest) (*Stream, error) {
+	body.Stream = true
+	resp, err := c.do(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	return newStream(resp.Body), nil
+}
+
+func (c *Client) do(ctx context.Context, body MessagesRequest) (*http.Response, error) {
+	if c.config.APIKey == "" {
+		return nil, errors.New("anthropic: API key is required")
+	}
+	if body.Model == "" {
+		body.Model = c.config.Model
+	}
+	if body.Model == "" {
+		return nil, errors.New("anthropic: model is required")
+	}
+	if body.MaxTokens == 0 {
+		body.MaxTokens = c.config.MaxTokens
+	}
+
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(body); err != nil {
-		return "", errors.Wrap(err, "anthropic: encode request")
+		return nil, errors.Wrap(err, "anthropic: encode request")
 	}
 
 	url := c.config.BaseURL + "/v1/messages"
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &buf)
 	if err != nil {
-		return "", errors.Wrap(err, "anthropic: create request")
+		return nil, errors.Wrap(err, "anthropic: create request")
 	}
 	req.Header.Set("x-api-key", c.config.APIKey)
 	req.Header.Set("anthropic-version", defaultAnthropicVersion)
 	req.Header.Set("Content-Type", "application/json")
+	if body.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
 
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return "", errors.Wrap(err, "anthropic: request failed")
+		return nil, errors.Wrap(err, "anthropic: request failed")
 	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		return "", errors.Wrap(
-			fmt.Errorf("anthropic: HTTP %d: %s", resp.StatusCode, string(respBody)),
-			"anthropic",
-		)
-	}
-
-	var out MessagesResponse
-	if err := json.Unmarshal(respBody, &out); err != nil {
-		return "", errors.Wrap(err, "anthropic: decode response")
-	}
-	for _, block := range out.Content {
-		if block.Type == "text" && block.Text != "" {
-			return block.Text, nil
-		}
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(resp, respBody)
 	}
-	return "", errors.New("anthropic: no text content in response")
+	return resp, nil
 }
@@ -0,0 +1,146 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestClient_Messages(t *testing.T) {
+	var got MessagesRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-api-key") != "key" {
+			t.Errorf("missing api key header")
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{
+			"id": "msg_1", "role": "assistant", "stop_reason": "tool_use",
+			"content": [
+				{"type": "text", "text": "Checking."},
+				{"type": "tool_use", "id": "tu_1", "name": "get_weather", "input": {"city": "Pune"}}
+			],
+			"usage": {"input_tokens": 12, "output_tokens": 7}
+		}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(Config{APIKey: "key", Model: "claude-test", BaseURL: srv.URL, MaxTokens: 256})
+	temperature := 0.2
+	res, err := client.Messages(context.Background(), MessagesRequest{
+		Messages: []Message{
+			UserMessage(TextBlock("weather?")),
+			AssistantMessage(TextBlock("Which city?")),
+			UserMessage(TextBlock("Pune")),
+		},
+		Tools:         []Tool{{Name: "get_weather", InputSchema: map[string]any{"type": "object"}}},
+		Temperature:   &temperature,
+		StopSequences: []string{"END"},
+	})
+	if err != nil {
+		t.Fatalf("messages: %v", err)
+	}
+	if got.Model != "claude-test" || got.MaxTokens != 256 || len(got.Messages) != 3 || len(got.Tools) != 1 {
+		t.Errorf("unexpected request %+v", got)
+	}
+	if res.StopReason != StopToolUse || res.Usage == nil || res.Usage.OutputTokens != 7 {
+		t.Errorf("unexpected response %+v", res)
+	}
+	uses := res.ToolUses()
+	if len(uses) != 1 || uses[0].Name != "get_weather" || string(uses[0].Input) != `{"city": "Pune"}` {
+		t.Errorf("unexpected tool uses %+v", uses)
+	}
+}
+
+func TestClient_MessagesError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "3")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(Config{APIKey: "key", Model: "claude-test", BaseURL: srv.URL})
+	_, err := client.Chat(context.Background(), "", "hi")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected APIError, got %v", err)
+	}
+	if apiErr.Type != "rate_limit_error" || apiErr.RetryAfter != 3*time.Second || !apiErr.Temporary() {
+		t.Errorf("unexpected error %+v", apiErr)
+	}
+}
+
+func TestClient_Stream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(`event: message_start
+data: {"type":"message_start","message":{"id":"msg_1","role":"assistant","content":[],"usage":{"input_tokens":5}}}
+
+event: content_block_start
+data: {"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}
+
+event: ping
+data: {"type":"ping"}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hel"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"lo"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":0}
+
+event: content_block_start
+data: {"type":"content_block_start","index":1,"content_block":{"type":"tool_use","id":"tu_1","name":"lookup","input":{}}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"{\"q\":"}}
+
+event: content_block_delta
+data: {"type":"content_block_delta","index":1,"delta":{"type":"input_json_delta","partial_json":"\"go\"}"}}
+
+event: content_block_stop
+data: {"type":"content_block_stop","index":1}
+
+event: message_delta
+data: {"type":"message_delta","delta":{"stop_reason":"tool_use"},"usage":{"output_tokens":9}}
+
+event: message_stop
+data: {"type":"message_stop"}
+
+`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(Config{APIKey: "key", Model: "claude-test", BaseURL: srv.URL})
+	stream, err := client.Stream(context.Background(), MessagesRequest{Messages: []Message{UserMessage(TextBlock("hi"))}})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	defer stream.Close()
+
+	var text string
+	for stream.Next() {
+		if e := stream.Event(); e.Type == EventContentBlockDelta && e.Delta.Type == DeltaText {
+			text += e.Delta.Text
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("stream err: %v", err)
+	}
+	msg := stream.Message()
+	if text != "Hello" || msg.Text() != "Hello" {
+		t.Errorf("expected Hello, got %q / %q", text, msg.Text())
+	}
+	if msg.StopReason != StopToolUse || msg.Usage.InputTokens != 5 || msg.Usage.OutputTokens != 9 {
+		t.Errorf("unexpected message %+v", msg)
+	}
+	if uses := msg.ToolUses(); len(uses) != 1 || string(uses[0].Input) != `{"q":"go"}` {
+		t.Errorf("unexpected tool uses %+v", uses)
+	}
+}
@@ -0,0 +1,54 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// APIError is returned for non 2xx responses and error stream events.
+// RetryAfter is set from the Retry-After header, if any.
+type APIError struct {
+	StatusCode int
+	Type       string // e.g. rate_limit_error, overloaded_error
+	Message    string
+	RetryAfter time.Duration
+}
+
+func (e *APIError) Error() string {
+	if e.StatusCode == 0 {
+		return fmt.Sprintf("anthropic: %s: %s", e.Type, e.Message)
+	}
+	return fmt.Sprintf("anthropic: HTTP %d: %s: %s", e.StatusCode, e.Type, e.Message)
+}
+
+// Temporary returns true if the request can be retried, i.e. rate limited or overloaded
+func (e *APIError) Temporary() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError ||
+		e.Type == "overloaded_error" || e.Type == "rate_limit_error"
+}
+
+type errorBody struct {
+	Type  string      `json:"type"`
+	Error errorDetail `json:"error"`
+}
+
+type errorDetail struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+func newAPIError(resp *http.Response, body []byte) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode, Message: string(body)}
+	var parsed errorBody
+	if err := json.Unmarshal(body, &parsed); err == nil && parsed.Error.Message != "" {
+		apiErr.Type = parsed.Error.Type
+		apiErr.Message = parsed.Error.Message
+	}
+	if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil {
+		apiErr.RetryAfter = time.Duration(secs) * time.Second
+	}
+	return apiErr
+}
@@ -0,0 +1,188 @@
+package anthropic
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	EventMessageStart      = "message_start"
+	EventContentBlockStart = "content_block_start"
+	EventContentBlockDelta = "content_block_delta"
+	EventContentBlockStop  = "content_block_stop"
+	EventMessageDelta      = "message_delta"
+	EventMessageStop       = "message_stop"
+	EventPing              = "ping"
+	EventError             = "error"
+
+	DeltaText      = "text_delta"
+	DeltaInputJSON = "input_json_delta"
+)
+
+// StreamEvent is a server sent event of a streaming response, fields set depend on Type.
+type StreamEvent struct {
+	Type         string            `json:"type"`
+	Message      *MessagesResponse `json:"message,omitempty"`
+	Index        int               `json:"index"`
+	ContentBlock *ContentBlock     `json:"content_block,omitempty"`
+	Delta        *Delta            `json:"delta,omitempty"`
+	Usage        *Usage            `json:"usage,omitempty"`
+	Error        *errorDetail      `json:"error,omitempty"`
+}
+
+// Delta is an incremental update of a content block or, for message_delta, the message.
+type Delta struct {
+	Type         string `json:"type,omitempty"`
+	Text         string `json:"text,omitempty"`
+	PartialJSON  string `json:"partial_json,omitempty"`
+	StopReason   string `json:"stop_reason,omitempty"`
+	StopSequence string `json:"stop_sequence,omitempty"`
+}
+
+// Stream iterates over the events of a streaming response:
+//
+//	for stream.Next() {
+//		event := stream.Event()
+//	}
+//	if err := stream.Err(); err != nil {...}
+//
+// Message returns the response accumulated so far, complete once Next returns false.
+type Stream struct {
+	body    io.ReadCloser
+	reader  *bufio.Reader
+	event   StreamEvent
+	err     error
+	message MessagesResponse
+	inputs  map[int]*strings.Builder
+	done    bool
+}
+
+func newStream(body io.ReadCloser) *Stream {
+	return &Stream{body: body, reader: bufio.NewReader(body), inputs: map[int]*strings.Builder{}}
+}
+
+// Next advances to the next event, it returns false at the end of the stream or on error.
+func (s *Stream) Next() bool {
+	for !s.done && s.err == nil {
+		data, err := s.readEvent()
+		if err != nil {
+			if err != io.EOF {
+				s.err = errors.Wrap(err, "anthropic: read stream")
+			}
+			s.done = true
+			return false
+		}
+		if len(data) == 0 {
+			continue
+		}
+
+		var event StreamEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			s.err = errors.Wrap(err, "anthropic: decode stream event")
+			return false
+		}
+		if event.Type == EventPing {
+			continue
+		}
+		s.event = event
+		s.accumulate(event)
+		return s.err == nil
+	}
+	return false
+}
+
+// Event returns the current event
+func (s *Stream) Event() StreamEvent { return s.event }
+
+// Err returns the error that stopped the stream, if any, error events are returned as *APIError
+func (s *Stream) Err() error { return s.err }
+
+// Message returns the response accumulated from the events so far
+func (s *Stream) Message() *MessagesResponse { return &s.message }
+
+func (s *Stream) Close() error { return s.body.Close() }
+
+// readEvent returns the data of the next event, multiple data lines are joined by new line
+func (s *Stream) readEvent() ([]byte, error) {
+	var data bytes.Buffer
+	for {
+		line, err := s.reader.ReadBytes('\n')
+		if err != nil && (err != io.EOF || len(line) == 0) {
+			if err == io.EOF && data.Len() > 0 {
+				return data.Bytes(), nil
+			}
+			return nil, err
+		}
+		line = bytes.TrimRight(line, "\r\n")
+		if len(line) == 0 {
+			if data.Len() > 0 {
+				return data.Bytes(), nil
+			}
+			continue
+		}
+		if value, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			if data.Len() > 0 {
+				data.WriteByte('\n')
+			}
+			data.Write(bytes.TrimPrefix(value, []byte(" ")))
+		}
+		// event, id and comment lines are ignored, type is part of the data
+	}
+}
+
+func (s *Stream) accumulate(event StreamEvent) {
+	switch event.Type {
+	case EventMessageStart:
+		if event.Message != nil {
+			s.message = *event.Message
+		}
+	case EventContentBlockStart:
+		for len(s.message.Content) <= event.Index {
+			s.message.Content = append(s.message.Content, ContentBlock{})
+		}
+		if event.ContentBlock != nil {
+			s.message.Content[event.Index] = *event.ContentBlock
+		}
+	case EventContentBlockDelta:
+		if event.Delta == nil || event.Index >= len(s.message.Content) {
+			return
+		}
+		switch event.Delta.Type {
+		case DeltaText:
+			s.message.Content[event.Index].Text += event.Delta.Text
+		case DeltaInputJSON:
+			if s.inputs[event.Index] == nil {
+				s.inputs[event.Index] = &strings.Builder{}
+			}
+			s.inputs[event.Index].WriteString(event.Delta.PartialJSON)
+		}
+	case EventContentBlockStop:
+		if input, ok := s.inputs[event.Index]; ok && event.Index < len(s.message.Content) {
+			s.message.Content[event.Index].Input = json.RawMessage(input.String())
+		}
+	case EventMessageDelta:
+		if event.Delta != nil {
+			s.message.StopReason = event.Delta.StopReason
+			s.message.StopSequence = event.Delta.StopSequence
+		}
+		if event.Usage != nil {
+			if s.message.Usage == nil {
+				s.message.Usage = &Usage{}
+			}
+			s.message.Usage.OutputTokens = event.Usage.OutputTokens
+		}
+	case EventMessageStop:
+		s.done = true
+	case EventError:
+		apiErr := &APIError{Type: "error"}
+		if event.Error != nil {
+			apiErr.Type, apiErr.Message = event.Error.Type, event.Error.Message
+		}
+		s.err = apiErr
+	}
+}
@@ -1,37 +1,174 @@
 package anthropic
 
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+)
+
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+
+	BlockText       = "text"
+	BlockImage      = "image"
+	BlockToolUse    = "tool_use"
+	BlockToolResult = "tool_result"
+
+	StopEndTurn   = "end_turn"
+	StopMaxTokens = "max_tokens"
+	StopSequence  = "stop_sequence"
+	StopToolUse   = "tool_use"
+	StopPauseTurn = "pause_turn"
+	StopRefusal   = "refusal"
+)
+
 // MessagesRequest is the request body for Anthropic Messages API.
+// Model and MaxTokens default to the client's config when empty.
 type MessagesRequest struct {
-	Model      string    `json:"model"`
-	MaxTokens  int       `json:"max_tokens"`
-	System     string    `json:"system,omitempty"`
-	Messages   []Message `json:"messages"`
+	Model         string      `json:"model"`
+	MaxTokens     int         `json:"max_tokens"`
+	System        string      `json:"system,omitempty"`
+	Messages      []Message   `json:"messages"`
+	Tools         []Tool      `json:"tools,omitempty"`
+	ToolChoice    *ToolChoice `json:"tool_choice,omitempty"`
+	Temperature   *float64    `json:"temperature,omitempty"`
+	TopP          *float64    `json:"top_p,omitempty"`
+	StopSequences []string    `json:"stop_sequences,omitempty"`
+	Metadata      *Metadata   `json:"metadata,omitempty"`
+	Stream        bool        `json:"stream,omitempty"`
 }
 
-// Message represents a single message (user or assistant).
+// Message represents a single turn (user or assistant) of the conversation.
 type Message struct {
-	Role    string `json:"role"`
-	Content string `json:"content"`
-}
-
-// MessagesResponse is the response from Anthropic Messages API.
-type MessagesResponse struct {
-	ID         string        `json:"id,omitempty"`
-	Type       string        `json:"type,omitempty"`
-	Role       string        `json:"role,omitempty"`
-	Content    []ContentBlock `json:"content,omitempty"`
-	StopReason string        `json:"stop_reason,omitempty"`
-	Usage      *Usage        `json:"usage,omitempty"`
+	Role    string         `json:"role"`
+	Content []ContentBlock `json:"content"`
 }
 
-// ContentBlock is a single content block (e.g. text).
+// ContentBlock is a single content block, fields used depend on Type.
 type ContentBlock struct {
 	Type string `json:"type"`
+	// text
 	Text string `json:"text,omitempty"`
+	// image
+	Source *ImageSource `json:"source,omitempty"`
+	// tool_use
+	ID    string          `json:"id,omitempty"`
+	Name  string          `json:"name,omitempty"`
+	Input json.RawMessage `json:"input,omitempty"`
+	// tool_result
+	ToolUseID string         `json:"tool_use_id,omitempty"`
+	Content   []ContentBlock `json:"content,omitempty"`
+	IsError   bool           `json:"is_error,omitempty"`
+}
+
+// ImageSource is either base64 encoded data or a url.
+type ImageSource struct {
+	Type      string `json:"type"` // base64 or url
+	MediaType string `json:"media_type,omitempty"`
+	Data      string `json:"data,omitempty"`
+	URL       string `json:"url,omitempty"`
+}
+
+// Tool describes a tool the model may call, InputSchema is a JSON schema object.
+type Tool struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	InputSchema any    `json:"input_schema"`
+}
+
+// ToolChoice controls tool use, Type is one of auto, any, tool or none.
+// Name is required for tool.
+type ToolChoice struct {
+	Type                   string `json:"type"`
+	Name                   string `json:"name,omitempty"`
+	DisableParallelToolUse bool   `json:"disable_parallel_tool_use,omitempty"`
+}
+
+type Metadata struct {
+	UserID string `json:"user_id,omitempty"`
+}
+
+// MessagesResponse is the response from Anthropic Messages API.
+type MessagesResponse struct {
+	ID           string         `json:"id,omitempty"`
+	Type         string         `json:"type,omitempty"`
+	Role         string         `json:"role,omitempty"`
+	Model        string         `json:"model,omitempty"`
+	Content      []ContentBlock `json:"content,omitempty"`
+	StopReason   string         `json:"stop_reason,omitempty"`
+	StopSequence string         `json:"stop_sequence,omitempty"`
+	Usage        *Usage         `json:"usage,omitempty"`
 }
 
 // Usage holds token usage stats.
 type Usage struct {
-	InputTokens  int `json:"input_tokens,omitempty"`
-	OutputTokens int `json:"output_tokens,omitempty"`
+	InputTokens              int `json:"input_tokens,omitempty"`
+	OutputTokens             int `json:"output_tokens,omitempty"`
+	CacheCreationInputTokens int `json:"cache_creation_input_tokens,omitempty"`
+	CacheReadInputTokens     int `json:"cache_read_input_tokens,omitempty"`
+}
+
+// Text returns the concatenated text blocks of the response.
+func (r *MessagesResponse) Text() string {
+	var b strings.Builder
+	for _, block := range r.Content {
+		if block.Type == BlockText {
+			b.WriteString(block.Text)
+		}
+	}
+	return b.String()
+}
+
+// ToolUses returns the tool_use blocks of the response.
+func (r *MessagesResponse) ToolUses() []ContentBlock {
+	var uses []ContentBlock
+	for _, block := range r.Content {
+		if block.Type == BlockToolUse {
+			uses = append(uses, block)
+		}
+	}
+	return uses
+}
+
+// Message returns the response as an assistant message to continue the conversation.
+func (r *MessagesResponse) Message() Message {
+	return Message{Role: RoleAssistant, Content: r.Content}
+}
+
+// UserMessage creates a user message from the given blocks, see TextBlock etc.
+func UserMessage(blocks ...ContentBlock) Message {
+	return Message{Role: RoleUser, Content: blocks}
+}
+
+// AssistantMessage creates an assistant message from the given blocks.
+func AssistantMessage(blocks ...ContentBlock) Message {
+	return Message{Role: RoleAssistant, Content: blocks}
+}
+
+func TextBlock(text string) ContentBlock {
+	return ContentBlock{Type: BlockText, Text: text}
+}
+
+// ImageBlock creates an image block from raw image bytes, e.g. image/png
+func ImageBlock(mediaType string, data []byte) ContentBlock {
+	return ContentBlock{Type: BlockImage, Source: &ImageSource{
+		Type:      "base64",
+		MediaType: mediaType,
+		Data:      base64.StdEncoding.EncodeToString(data),
+	}}
+}
+
+func ImageURLBlock(url string) ContentBlock {
+	return ContentBlock{Type: BlockImage, Source: &ImageSource{Type: "url", URL: url}}
+}
+
+// ToolResultBlock creates the result of the tool call with id toolUseID
+func ToolResultBlock(toolUseID, result string, isError bool) ContentBlock {
+	return ContentBlock{
+		Type:      BlockToolResult,
+		ToolUseID: toolUseID,
+		Content:   []ContentBlock{TextBlock(result)},
+		IsError:   isError,
+	}
 }
@@ -2,29 +2,77 @@ package aws
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"net/http"
+	"net/url"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
 	"github.com/krsoninikhil/go-rest-kit/apperrors"
 )
 
+const maxDeleteBatch = 1000
+
 // Config holds S3 configuration (bucket, region).
 // Credentials are loaded from the environment (AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY)
-// or the default credential chain (e.g. IAM role).
+// or the default credential chain (e.g. IAM role), unless AccessKeyID is set.
+// Set Endpoint and UsePathStyle for S3 compatible stores like MinIO.
 type Config struct {
-	Bucket string `mapstructure:"bucket"`
-	Region string `mapstructure:"region"`
+	Bucket          string `mapstructure:"bucket"`
+	Region          string `mapstructure:"region"`
+	Endpoint        string `mapstructure:"endpoint"`
+	UsePathStyle    bool   `mapstructure:"use_path_style"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key" log:"-"`
+	// PartSize and UploadConcurrency configure multipart uploads, see Upload.
+	// Optional; default 8MB parts, min 5MB, and 4 parts in parallel.
+	PartSize          int64 `mapstructure:"part_size"`
+	UploadConcurrency int   `mapstructure:"upload_concurrency"`
 }
 
-// S3 provides PutObject, PresignGet, and PublicURL for a bucket.
+// S3 provides object operations and presigned URLs for a bucket.
 type S3 struct {
 	client *s3.Client
-	bucket string
-	region string
+	config Config
+}
+
+// ObjectInfo is the metadata of an object
+type ObjectInfo struct {
+	Key          string
+	Size         int64
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// Object is a streamed object, caller must close Body
+type Object struct {
+	ObjectInfo
+	Body io.ReadCloser
+}
+
+// ListInput filters ListObjects, set ContinuationToken to NextToken of the previous page
+type ListInput struct {
+	Prefix            string
+	Delimiter         string
+	MaxKeys           int32
+	ContinuationToken string
+}
+
+// ListResult is a page of objects, NextToken is empty on the last page
+type ListResult struct {
+	Objects        []ObjectInfo
+	CommonPrefixes []string
+	NextToken      string
 }
 
 // NewS3 creates an S3 client using the default credential chain.
@@ -35,12 +83,23 @@ func NewS3(ctx context.Context, cfg Config) (*S3, error) {
 	if cfg.Region == "" {
 		cfg.Region = "us-east-1"
 	}
-	awsCfg, err := config.LoadDefaultConfig(ctx, config.WithRegion(cfg.Region))
+	opts := []func(*config.LoadOptions) error{config.WithRegion(cfg.Region)}
+	if cfg.AccessKeyID != "" {
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		))
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
 	if err != nil {
 		return nil, apperrors.NewServerError(fmt.Errorf("load aws config: %w", err))
 	}
-	client := s3.NewFromConfig(awsCfg)
-	return &S3{client: client, bucket: cfg.Bucket, region: cfg.Region}, nil
+	client := s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+		o.UsePathStyle = cfg.UsePathStyle
+	})
+	return &S3{client: client, config: cfg}, nil
 }
 
 // PutObject uploads body to the given key.
@@ -51,7 +110,7 @@ func (s *S3) PutObject(ctx context.Context, key string, body io.Reader, contentT
 		contentType = "application/octet-stream"
 	}
 	input := &s3.PutObjectInput{
-		Bucket:      aws.String(s.bucket),
+		Bucket:      aws.String(s.config.Bucket),
 		Key:         aws.String(key),
 		Body:        body,
 		ContentType: aws.String(contentType),
@@ -63,6 +122,146 @@ func (s *S3) PutObject(ctx context.Context, key string, body io.Reader, contentT
 	return nil
 }
 
+// GetObject streams the object, it returns apperrors.NotFoundError if key doesn't exist.
+func (s *S3) GetObject(ctx context.Context, key string) (*Object, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, s.mapError("get object", err)
+	}
+	return &Object{
+		ObjectInfo: ObjectInfo{
+			Key:          key,
+			Size:         aws.ToInt64(out.ContentLength),
+			ContentType:  aws.ToString(out.ContentType),
+			ETag:         aws.ToString(out.ETag),
+			LastModified: aws.ToTime(out.LastModified),
+		},
+		Body: out.Body,
+	}, nil
+}
+
+// HeadObject returns the object's metadata, it returns apperrors.NotFoundError if key doesn't exist.
+func (s *S3) HeadObject(ctx context.Context, key string) (*ObjectInfo, error) {
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return nil, s.mapError("head object", err)
+	}
+	return &ObjectInfo{
+		Key:          key,
+		Size:         aws.ToInt64(out.ContentLength),
+		ContentType:  aws.ToString(out.ContentType),
+		ETag:         aws.ToString(out.ETag),
+		LastModified: aws.ToTime(out.LastModified),
+	}, nil
+}
+
+// HeadBucket checks that the bucket exists and is accessible with the credentials, e.g. for health checks.
+func (s *S3) HeadBucket(ctx context.Context) error {
+	_, err := s.client.HeadBucket(ctx, &s3.HeadBucketInput{Bucket: aws.String(s.config.Bucket)})
+	if err != nil {
+		return apperrors.NewServerError(fmt.Errorf("s3 head bucket %s: %w", s.config.Bucket, err))
+	}
+	return nil
+}
+
+// DeleteObject deletes the key, deleting a missing key is not an error.
+func (s *S3) DeleteObject(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return s.mapError("delete object", err)
+	}
+	return nil
+}
+
+// DeleteObjects deletes keys in batches of 1000, the error lists the keys that failed.
+func (s *S3) DeleteObjects(ctx context.Context, keys []string) error {
+	var failed []string
+	for start := 0; start < len(keys); start += maxDeleteBatch {
+		batch := keys[start:min(start+maxDeleteBatch, len(keys))]
+		objects := make([]types.ObjectIdentifier, len(batch))
+		for i, key := range batch {
+			objects[i] = types.ObjectIdentifier{Key: aws.String(key)}
+		}
+		out, err := s.client.DeleteObjects(ctx, &s3.DeleteObjectsInput{
+			Bucket: aws.String(s.config.Bucket),
+			Delete: &types.Delete{Objects: objects, Quiet: aws.Bool(true)},
+		})
+		if err != nil {
+			return s.mapError("delete objects", err)
+		}
+		for _, e := range out.Errors {
+			failed = append(failed, fmt.Sprintf("%s: %s", aws.ToString(e.Key), aws.ToString(e.Message)))
+		}
+	}
+	if len(failed) > 0 {
+		return apperrors.NewServerError(fmt.Errorf("s3 delete objects failed: %s", strings.Join(failed, ", ")))
+	}
+	return nil
+}
+
+// ListObjects returns a page of objects, up to 1000 by default.
+func (s *S3) ListObjects(ctx context.Context, in ListInput) (*ListResult, error) {
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.config.Bucket),
+	}
+	if in.Prefix != "" {
+		input.Prefix = aws.String(in.Prefix)
+	}
+	if in.Delimiter != "" {
+		input.Delimiter = aws.String(in.Delimiter)
+	}
+	if in.MaxKeys > 0 {
+		input.MaxKeys = aws.Int32(in.MaxKeys)
+	}
+	if in.ContinuationToken != "" {
+		input.ContinuationToken = aws.String(in.ContinuationToken)
+	}
+	out, err := s.client.ListObjectsV2(ctx, input)
+	if err != nil {
+		return nil, s.mapError("list objects", err)
+	}
+
+	res := &ListResult{Objects: make([]ObjectInfo, 0, len(out.Contents))}
+	for _, obj := range out.Contents {
+		res.Objects = append(res.Objects, ObjectInfo{
+			Key:          aws.ToString(obj.Key),
+			Size:         aws.ToInt64(obj.Size),
+			ETag:         aws.ToString(obj.ETag),
+			LastModified: aws.ToTime(obj.LastModified),
+		})
+	}
+	for _, prefix := range out.CommonPrefixes {
+		res.CommonPrefixes = append(res.CommonPrefixes, aws.ToString(prefix.Prefix))
+	}
+	if aws.ToBool(out.IsTruncated) {
+		res.NextToken = aws.ToString(out.NextContinuationToken)
+	}
+	return res, nil
+}
+
+// CopyObject copies srcKey to dstKey within the bucket, it returns apperrors.NotFoundError
+// if srcKey doesn't exist.
+func (s *S3) CopyObject(ctx context.Context, srcKey, dstKey string) error {
+	_, err := s.client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(s.config.Bucket),
+		Key:        aws.String(dstKey),
+		CopySource: aws.String(url.PathEscape(s.config.Bucket) + "/" + escapeKey(srcKey)),
+	})
+	if err != nil {
+		return s.mapError("copy object", err)
+	}
+	return nil
+}
+
 // PresignGet returns a signed URL for GET on the given key.
 func (s *S3) PresignGet(ctx context.Context, key string, expiry time.Duration) (string, error) {
 	if key == "" {
@@ -70,7 +269,7 @@ func (s *S3) PresignGet(ctx context.Context, key string, expiry time.Duration) (
 	}
 	presigner := s3.NewPresignClient(s.client)
 	req, err := presigner.PresignGetObject(ctx, &s3.GetObjectInput{
-		Bucket: aws.String(s.bucket),
+		Bucket: aws.String(s.config.Bucket),
 		Key:    aws.String(key),
 	}, s3.WithPresignExpires(expiry))
 	if err != nil {
@@ -79,7 +278,94 @@ func (s *S3) PresignGet(ctx context.Context, key string, expiry time.Duration) (
 	return req.URL, nil
 }
 
-// PublicURL returns the permanent public URL for the key (virtual-hosted style).
+// PresignPut returns a signed URL to upload the key with a PUT request. If contentType is
+// set, the upload must send the same Content-Type header.
+func (s *S3) PresignPut(ctx context.Context, key, contentType string, expiry time.Duration) (string, error) {
+	if key == "" {
+		return "", apperrors.NewInvalidParamsError("s3", fmt.Errorf("key is required"))
+	}
+	input := &s3.PutObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	}
+	if contentType != "" {
+		input.ContentType = aws.String(contentType)
+	}
+	presigner := s3.NewPresignClient(s.client)
+	req, err := presigner.PresignPutObject(ctx, input, s3.WithPresignExpires(expiry))
+	if err != nil {
+		return "", apperrors.NewServerError(fmt.Errorf("presign put object: %w", err))
+	}
+	return req.URL, nil
+}
+
+// PresignedPost is a browser form upload, Fields must be sent as form fields before the file field.
+type PresignedPost struct {
+	URL    string
+	Fields map[string]string
+}
+
+// PresignPost returns a POST policy to upload the key from a browser form, limited to
+// contentType, if set, and maxBytes, if positive.
+func (s *S3) PresignPost(ctx context.Context, key, contentType string, maxBytes int64, expiry time.Duration) (*PresignedPost, error) {
+	if key == "" {
+		return nil, apperrors.NewInvalidParamsError("s3", fmt.Errorf("key is required"))
+	}
+	var conditions []any
+	if contentType != "" {
+		conditions = append(conditions, map[string]string{"Content-Type": contentType})
+	}
+	if maxBytes > 0 {
+		conditions = append(conditions, []any{"content-length-range", 0, maxBytes})
+	}
+	presigner := s3.NewPresignClient(s.client)
+	req, err := presigner.PresignPostObject(ctx, &s3.PutObjectInput{
+		Bucket: aws.String(s.config.Bucket),
+		Key:    aws.String(key),
+	}, func(o *s3.PresignPostOptions) {
+		o.Expires = expiry
+		o.Conditions = conditions
+	})
+	if err != nil {
+		return nil, apperrors.NewServerError(fmt.Errorf("presign post object: %w", err))
+	}
+	fields := req.Values
+	if contentType != "" {
+		fields["Content-Type"] = contentType
+	}
+	return &PresignedPost{URL: req.URL, Fields: fields}, nil
+}
+
+// PublicURL returns the permanent public URL for the key (virtual-hosted style),
+// or path style under Endpoint if it's set.
 func (s *S3) PublicURL(key string) string {
-	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.bucket, s.region, key)
+	if s.config.Endpoint != "" {
+		return strings.TrimRight(s.config.Endpoint, "/") + "/" + s.config.Bucket + "/" + escapeKey(key)
+	}
+	return fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", s.config.Bucket, s.config.Region, key)
+}
+
+func (s *S3) mapError(op string, err error) error {
+	var (
+		noSuchKey *types.NoSuchKey
+		notFound  *types.NotFound
+		apiErr    smithy.APIError
+		respErr   *awshttp.ResponseError
+	)
+	switch {
+	case errors.As(err, &noSuchKey), errors.As(err, &notFound),
+		errors.As(err, &apiErr) && (apiErr.ErrorCode() == "NoSuchKey" || apiErr.ErrorCode() == "NotFound"),
+		errors.As(err, &respErr) && respErr.HTTPStatusCode() == http.StatusNotFound:
+		return apperrors.NewNotFoundError("s3_object")
+	}
+	return apperrors.NewServerError(fmt.Errorf("s3 %s: %w", op, err))
+}
+
+// escapeKey escapes each path segment of key, keeping the slashes
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return strings.Join(segments, "/")
 }
@@ -0,0 +1,276 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+)
+
+// fakeS3 implements the subset of path style S3 API used by the client
+type fakeS3 struct {
+	mu       sync.Mutex
+	objects  map[string][]byte
+	types    map[string]string
+	parts    map[int][]byte
+	failPart int
+	aborted  bool
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	path, _ := url.PathUnescape(strings.TrimPrefix(r.URL.EscapedPath(), "/bucket"))
+	key := strings.TrimPrefix(path, "/")
+
+	q := r.URL.Query()
+	switch {
+	case r.Method == http.MethodPost && q.Has("uploads"):
+		f.parts = map[int][]byte{}
+		f.types[key] = r.Header.Get("Content-Type")
+		w.Write([]byte(`<InitiateMultipartUploadResult><UploadId>u1</UploadId></InitiateMultipartUploadResult>`))
+	case r.Method == http.MethodPut && q.Has("partNumber"):
+		var number int
+		fmt.Sscan(q.Get("partNumber"), &number)
+		if number == f.failPart {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`<Error><Code>InvalidPart</Code></Error>`))
+			return
+		}
+		f.parts[number], _ = io.ReadAll(r.Body)
+		w.Header().Set("ETag", fmt.Sprintf(`"part-%d"`, number))
+	case r.Method == http.MethodPost && q.Has("uploadId"):
+		var req struct {
+			Parts []struct{ PartNumber int } `xml:"Part"`
+		}
+		xml.NewDecoder(r.Body).Decode(&req)
+		var body []byte
+		for _, p := range req.Parts {
+			body = append(body, f.parts[p.PartNumber]...)
+		}
+		f.objects[key] = body
+		w.Write([]byte(`<CompleteMultipartUploadResult><Key>` + key + `</Key></CompleteMultipartUploadResult>`))
+	case r.Method == http.MethodDelete && q.Has("uploadId"):
+		f.aborted = true
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet && key == "":
+		f.list(w, r)
+	case r.Method == http.MethodHead && key == "":
+		w.WriteHeader(http.StatusOK)
+	case r.Method == http.MethodPost && r.URL.Query().Has("delete"):
+		var req struct {
+			Objects []struct{ Key string } `xml:"Object"`
+		}
+		xml.NewDecoder(r.Body).Decode(&req)
+		for _, o := range req.Objects {
+			delete(f.objects, o.Key)
+		}
+		w.Write([]byte(`<DeleteResult></DeleteResult>`))
+	case r.Method == http.MethodPut && r.Header.Get("x-amz-copy-source") != "":
+		src, _ := url.PathUnescape(r.Header.Get("x-amz-copy-source"))
+		body, ok := f.objects[strings.TrimPrefix(src, "bucket/")]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<Error><Code>NoSuchKey</Code></Error>`))
+			return
+		}
+		f.objects[key] = body
+		w.Write([]byte(`<CopyObjectResult><ETag>"e"</ETag></CopyObjectResult>`))
+	case r.Method == http.MethodPut:
+		body, _ := io.ReadAll(r.Body)
+		f.objects[key] = body
+		f.types[key] = r.Header.Get("Content-Type")
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodGet || r.Method == http.MethodHead:
+		body, ok := f.objects[key]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			if r.Method == http.MethodGet {
+				w.Write([]byte(`<Error><Code>NoSuchKey</Code></Error>`))
+			}
+			return
+		}
+		w.Header().Set("Content-Type", f.types[key])
+		w.Header().Set("Content-Length", fmt.Sprint(len(body)))
+		w.Header().Set("ETag", `"etag-`+key+`"`)
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeS3) list(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	keys := []string{}
+	for key := range f.objects {
+		if strings.HasPrefix(key, q.Get("prefix")) && key > q.Get("continuation-token") {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	var maxKeys int
+	fmt.Sscan(q.Get("max-keys"), &maxKeys)
+	truncated := maxKeys > 0 && len(keys) > maxKeys
+	if truncated {
+		keys = keys[:maxKeys]
+	}
+
+	var b strings.Builder
+	b.WriteString(`<ListBucketResult>`)
+	for _, key := range keys {
+		fmt.Fprintf(&b, `<Contents><Key>%s</Key><Size>%d</Size></Contents>`, key, len(f.objects[key]))
+	}
+	if truncated {
+		fmt.Fprintf(&b, `<IsTruncated>true</IsTruncated><NextContinuationToken>%s</NextContinuationToken>`, keys[len(keys)-1])
+	} else {
+		b.WriteString(`<IsTruncated>false</IsTruncated>`)
+	}
+	b.WriteString(`</ListBucketResult>`)
+	w.Write([]byte(b.String()))
+}
+
+func TestS3_ObjectLifecycle(t *testing.T) {
+	s, srv := newTestS3(t, &fakeS3{})
+	defer srv.Close()
+	ctx := context.Background()
+
+	if err := s.HeadBucket(ctx); err != nil {
+		t.Fatalf("head bucket: %v", err)
+	}
+	for _, key := range []string{"docs/a.txt", "docs/b c.txt", "img/c.png"} {
+		if err := s.PutObject(ctx, key, strings.NewReader("hello "+key), "text/plain", false); err != nil {
+			t.Fatalf("put %s: %v", key, err)
+		}
+	}
+
+	obj, err := s.GetObject(ctx, "docs/b c.txt")
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	body, _ := io.ReadAll(obj.Body)
+	obj.Body.Close()
+	if string(body) != "hello docs/b c.txt" || obj.ContentType != "text/plain" {
+		t.Errorf("unexpected object %q %+v", body, obj.ObjectInfo)
+	}
+
+	info, err := s.HeadObject(ctx, "docs/a.txt")
+	if err != nil || info.Size != int64(len("hello docs/a.txt")) || info.ETag == "" {
+		t.Errorf("unexpected head %+v err=%v", info, err)
+	}
+
+	page, err := s.ListObjects(ctx, ListInput{Prefix: "docs/", MaxKeys: 1})
+	if err != nil || len(page.Objects) != 1 || page.NextToken == "" {
+		t.Fatalf("unexpected first page %+v err=%v", page, err)
+	}
+	page, err = s.ListObjects(ctx, ListInput{Prefix: "docs/", MaxKeys: 1, ContinuationToken: page.NextToken})
+	if err != nil || len(page.Objects) != 1 || page.Objects[0].Key != "docs/b c.txt" || page.NextToken != "" {
+		t.Fatalf("unexpected last page %+v err=%v", page, err)
+	}
+
+	if err := s.CopyObject(ctx, "docs/b c.txt", "archive/b.txt"); err != nil {
+		t.Fatalf("copy: %v", err)
+	}
+	if err := s.DeleteObject(ctx, "docs/a.txt"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if err := s.DeleteObjects(ctx, []string{"docs/b c.txt", "img/c.png"}); err != nil {
+		t.Fatalf("delete objects: %v", err)
+	}
+	page, err = s.ListObjects(ctx, ListInput{})
+	if err != nil || len(page.Objects) != 1 || page.Objects[0].Key != "archive/b.txt" {
+		t.Fatalf("expected only the copy to remain, got %+v err=%v", page, err)
+	}
+
+	_, err = s.GetObject(ctx, "docs/a.txt")
+	var notFound apperrors.NotFoundError
+	if !errors.As(err, &notFound) {
+		t.Errorf("expected not found for get, got %v", err)
+	}
+	if _, err := s.HeadObject(ctx, "docs/a.txt"); !errors.As(err, &notFound) {
+		t.Errorf("expected not found for head, got %v", err)
+	}
+
+	signed, err := s.PresignPut(ctx, "up/x.png", "image/png", 0)
+	if err != nil || !strings.HasPrefix(signed, srv.URL+"/bucket/up/x.png?") || !strings.Contains(signed, "X-Amz-Signature") {
+		t.Errorf("unexpected presigned put %q err=%v", signed, err)
+	}
+
+	post, err := s.PresignPost(ctx, "up/y.png", "image/png", 1024, time.Minute)
+	if err != nil || post.Fields["key"] != "up/y.png" || post.Fields["policy"] == "" || post.Fields["Content-Type"] != "image/png" {
+		t.Errorf("unexpected presigned post %+v err=%v", post, err)
+	}
+}
+
+func newTestS3(t *testing.T, fake *fakeS3) (*S3, *httptest.Server) {
+	fake.objects, fake.types = map[string][]byte{}, map[string]string{}
+	srv := httptest.NewServer(fake)
+	s, err := NewS3(context.Background(), Config{
+		Bucket: "bucket", Region: "us-east-1", Endpoint: srv.URL, UsePathStyle: true,
+		AccessKeyID: "key", SecretAccessKey: "secret", PartSize: minPartSize, UploadConcurrency: 2,
+	})
+	if err != nil {
+		t.Fatalf("new s3: %v", err)
+	}
+	return s, srv
+}
+
+func TestS3_Upload(t *testing.T) {
+	ctx := context.Background()
+	fake := &fakeS3{}
+	s, srv := newTestS3(t, fake)
+	defer srv.Close()
+
+	body := bytes.Repeat([]byte("0123456789"), (2*minPartSize+minPartSize/2)/10)
+	var progress []int64
+	err := s.Upload(ctx, "big.bin", io.NopCloser(bytes.NewReader(body)), "application/zip", UploadOptions{
+		OnProgress: func(uploaded int64) { progress = append(progress, uploaded) },
+	})
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+	if !bytes.Equal(fake.objects["big.bin"], body) || fake.types["big.bin"] != "application/zip" {
+		t.Fatalf("uploaded object doesn't match, got %d bytes", len(fake.objects["big.bin"]))
+	}
+	if len(fake.parts) != 3 || len(progress) != 3 || progress[2] != int64(len(body)) {
+		t.Errorf("expected 3 parts and progress events, got parts=%d progress=%v", len(fake.parts), progress)
+	}
+	for i := 1; i < len(progress); i++ {
+		if progress[i] <= progress[i-1] {
+			t.Errorf("expected increasing progress, got %v", progress)
+		}
+	}
+
+	if err := s.Upload(ctx, "small.txt", strings.NewReader("small"), "", UploadOptions{}); err != nil {
+		t.Fatalf("small upload: %v", err)
+	}
+	if string(fake.objects["small.txt"]) != "small" {
+		t.Errorf("small object not stored with put")
+	}
+
+	fake.failPart = 2
+	if err := s.Upload(ctx, "failed.bin", bytes.NewReader(body), "", UploadOptions{}); err == nil {
+		t.Fatalf("expected upload error")
+	}
+	if !fake.aborted {
+		t.Errorf("expected multipart upload to be aborted")
+	}
+	if _, ok := fake.objects["failed.bin"]; ok {
+		t.Errorf("failed upload must not be stored")
+	}
+}
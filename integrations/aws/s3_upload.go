@@ -0,0 +1,189 @@
+package aws
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+)
+
+const (
+	minPartSize              = 5 << 20 // S3 minimum for all parts but the last
+	defaultPartSize          = 8 << 20
+	defaultUploadConcurrency = 4
+	maxParts                 = 10000
+)
+
+// UploadOptions configures Upload, zero values use Config.PartSize and Config.UploadConcurrency.
+// OnProgress is called with the total bytes uploaded after each part, calls are serialized.
+type UploadOptions struct {
+	PartSize    int64
+	Concurrency int
+	OnProgress  func(uploaded int64)
+}
+
+// Upload streams body to key. Bodies up to one part are sent with a single PutObject, larger
+// ones with a multipart upload of concurrently uploaded parts, which is aborted on failure.
+// Memory use is bounded by PartSize * (Concurrency + 1).
+func (s *S3) Upload(ctx context.Context, key string, body io.Reader, contentType string, opts UploadOptions) error {
+	opts = s.uploadOptions(opts)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	first, err := readPart(body, opts.PartSize)
+	if err != nil && err != io.EOF {
+		return apperrors.NewServerError(fmt.Errorf("s3 upload read: %w", err))
+	}
+	if err == io.EOF {
+		// fits in a single part, seekable body lets the sdk compute the checksum
+		if err := s.PutObject(ctx, key, bytes.NewReader(first), contentType, false); err != nil {
+			return err
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(int64(len(first)))
+		}
+		return nil
+	}
+	return s.multipartUpload(ctx, key, io.MultiReader(bytes.NewReader(first), body), contentType, opts)
+}
+
+func (s *S3) multipartUpload(ctx context.Context, key string, body io.Reader, contentType string, opts UploadOptions) error {
+	created, err := s.client.CreateMultipartUpload(ctx, &s3.CreateMultipartUploadInput{
+		Bucket:      aws.String(s.config.Bucket),
+		Key:         aws.String(key),
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return s.mapError("create multipart upload", err)
+	}
+	uploadID := created.UploadId
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		parts    []types.CompletedPart
+		uploaded int64
+		slots    = make(chan struct{}, opts.Concurrency)
+	)
+	uploadPart := func(number int32, data []byte) {
+		defer wg.Done()
+		defer func() { <-slots }()
+		out, err := s.client.UploadPart(ctx, &s3.UploadPartInput{
+			Bucket:     aws.String(s.config.Bucket),
+			Key:        aws.String(key),
+			UploadId:   uploadID,
+			PartNumber: aws.Int32(number),
+			Body:       bytes.NewReader(data),
+		})
+		if err != nil {
+			cancel(fmt.Errorf("upload part %d: %w", number, err))
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		parts = append(parts, types.CompletedPart{ETag: out.ETag, PartNumber: aws.Int32(number)})
+		// under mu so calls are serialized and totals only increase
+		uploaded += int64(len(data))
+		if opts.OnProgress != nil {
+			opts.OnProgress(uploaded)
+		}
+	}
+
+	var readErr error
+	for number := int32(1); ctx.Err() == nil; number++ {
+		if number > maxParts {
+			readErr = fmt.Errorf("body exceeds %d parts of %d bytes", maxParts, opts.PartSize)
+			break
+		}
+		select {
+		case slots <- struct{}{}:
+		case <-ctx.Done():
+		}
+		if ctx.Err() != nil {
+			break
+		}
+		data, err := readPart(body, opts.PartSize)
+		if len(data) > 0 {
+			wg.Add(1)
+			go uploadPart(number, data)
+		} else {
+			<-slots
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			readErr = err
+			break
+		}
+	}
+	wg.Wait()
+
+	if err := errors.Join(readErr, context.Cause(ctx)); err != nil {
+		s.abortUpload(ctx, key, uploadID)
+		return apperrors.NewServerError(fmt.Errorf("s3 multipart upload: %w", err))
+	}
+
+	sort.Slice(parts, func(i, j int) bool { return *parts[i].PartNumber < *parts[j].PartNumber })
+	_, err = s.client.CompleteMultipartUpload(ctx, &s3.CompleteMultipartUploadInput{
+		Bucket:          aws.String(s.config.Bucket),
+		Key:             aws.String(key),
+		UploadId:        uploadID,
+		MultipartUpload: &types.CompletedMultipartUpload{Parts: parts},
+	})
+	if err != nil {
+		s.abortUpload(ctx, key, uploadID)
+		return s.mapError("complete multipart upload", err)
+	}
+	return nil
+}
+
+// abortUpload frees the uploaded parts, it runs even if ctx is cancelled
+func (s *S3) abortUpload(ctx context.Context, key string, uploadID *string) {
+	_, _ = s.client.AbortMultipartUpload(context.WithoutCancel(ctx), &s3.AbortMultipartUploadInput{
+		Bucket:   aws.String(s.config.Bucket),
+		Key:      aws.String(key),
+		UploadId: uploadID,
+	})
+}
+
+func (s *S3) uploadOptions(opts UploadOptions) UploadOptions {
+	if opts.PartSize == 0 {
+		opts.PartSize = s.config.PartSize
+	}
+	if opts.PartSize == 0 {
+		opts.PartSize = defaultPartSize
+	}
+	opts.PartSize = max(opts.PartSize, minPartSize)
+	if opts.Concurrency == 0 {
+		opts.Concurrency = s.config.UploadConcurrency
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultUploadConcurrency
+	}
+	return opts
+}
+
+// readPart reads up to size bytes, it returns io.EOF if body ended within the part
+func readPart(body io.Reader, size int64) ([]byte, error) {
+	buf := make([]byte, size)
+	n, err := io.ReadFull(body, buf)
+	if err == io.ErrUnexpectedEOF || err == io.EOF {
+		return buf[:n], io.EOF
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf, nil
+}
@@ -0,0 +1,56 @@
+package delivery
+
+import (
+	"context"
+	"time"
+)
+
+type cacheClient interface {
+	Set(key string, value any, ttl time.Duration) error
+	Get(key string) (any, error)
+}
+
+// CacheSink keeps the latest event per message id so that apps can check delivery
+// status, e.g. to offer another channel when an otp sms failed.
+type CacheSink struct {
+	cache cacheClient
+	ttl   time.Duration
+}
+
+func NewCacheSink(cache cacheClient, ttl time.Duration) *CacheSink {
+	return &CacheSink{cache: cache, ttl: ttl}
+}
+
+// Publish keeps e unless the latest event is further along the message's lifecycle or, for
+// the same stage, more recent. Providers don't guarantee ordering of callbacks.
+func (s *CacheSink) Publish(ctx context.Context, e Event) error {
+	if last, err := s.Latest(e.MessageID); err == nil && supersedes(*last, e) {
+		return nil
+	}
+	return s.cache.Set(buildEventKey(e.MessageID), e, s.ttl)
+}
+
+func supersedes(last, e Event) bool {
+	if last.Status.rank() != e.Status.rank() {
+		return last.Status.rank() > e.Status.rank()
+	}
+	return last.Timestamp.After(e.Timestamp)
+}
+
+// Latest returns the latest event for messageID, the error is the cache's not found error
+// if no event was received yet.
+func (s *CacheSink) Latest(messageID string) (*Event, error) {
+	val, err := s.cache.Get(buildEventKey(messageID))
+	if err != nil {
+		return nil, err
+	}
+	e, ok := val.(Event)
+	if !ok {
+		return nil, errInvalidCachedEvent
+	}
+	return &e, nil
+}
+
+func buildEventKey(messageID string) string {
+	return "delivery:" + NormalizeMessageID(messageID)
+}
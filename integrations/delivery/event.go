@@ -0,0 +1,108 @@
+// Package delivery normalizes message delivery events from sms and email providers
+// and publishes them to a pluggable sink.
+package delivery
+
+import (
+	"context"
+	"errors"
+	"log"
+	"strings"
+	"time"
+)
+
+type Status string
+
+const (
+	StatusQueued       Status = "queued"
+	StatusSent         Status = "sent"
+	StatusDelivered    Status = "delivered"
+	StatusDeferred     Status = "deferred" // temporary failure, provider will retry
+	StatusFailed       Status = "failed"
+	StatusOpened       Status = "opened"
+	StatusClicked      Status = "clicked"
+	StatusComplained   Status = "complained"
+	StatusUnsubscribed Status = "unsubscribed"
+	StatusUnknown      Status = "unknown"
+
+	ChannelSMS   = "sms"
+	ChannelEmail = "email"
+)
+
+// rank orders statuses along a message's lifecycle, events of a lower rank arriving late
+// don't replace the latest status. Statuses of the same rank are ordered by Timestamp.
+func (s Status) rank() int {
+	switch s {
+	case StatusQueued:
+		return 1
+	case StatusSent, StatusDeferred:
+		return 2
+	case StatusDelivered, StatusFailed:
+		return 3
+	case StatusOpened:
+		return 4
+	case StatusClicked:
+		return 5
+	case StatusComplained, StatusUnsubscribed:
+		return 6
+	default:
+		return 0
+	}
+}
+
+// Event is a provider agnostic delivery status update. MessageID matches the id
+// returned by the provider client when the message was sent.
+type Event struct {
+	Provider  string
+	Channel   string
+	MessageID string
+	Recipient string
+	Status    Status
+	// Reason describes the failure, if any, e.g. provider error code and message
+	Reason    string
+	Timestamp time.Time
+	// Metadata holds custom variables attached to the message when sending
+	Metadata map[string]string
+}
+
+// Failed returns true if the message won't be delivered
+func (e Event) Failed() bool {
+	return e.Status == StatusFailed
+}
+
+type (
+	Sink interface {
+		Publish(ctx context.Context, e Event) error
+	}
+
+	// SinkFunc adapts a function to Sink
+	SinkFunc func(ctx context.Context, e Event) error
+
+	// MultiSink publishes to all sinks and returns the joined errors
+	MultiSink []Sink
+)
+
+func (f SinkFunc) Publish(ctx context.Context, e Event) error { return f(ctx, e) }
+
+func (m MultiSink) Publish(ctx context.Context, e Event) error {
+	var errs []error
+	for _, sink := range m {
+		if err := sink.Publish(ctx, e); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// LogSink logs every event
+var LogSink = SinkFunc(func(ctx context.Context, e Event) error {
+	log.Printf("delivery: provider=%s channel=%s id=%s to=%s status=%s reason=%s",
+		e.Provider, e.Channel, e.MessageID, e.Recipient, e.Status, e.Reason)
+	return nil
+})
+
+// NormalizeMessageID trims the angle brackets some providers wrap message ids in
+func NormalizeMessageID(id string) string {
+	return strings.TrimSuffix(strings.TrimPrefix(strings.TrimSpace(id), "<"), ">")
+}
+
+var errInvalidCachedEvent = errors.New("delivery: invalid cached event")
@@ -0,0 +1,145 @@
+// Package email holds the message types shared by the email integrations
+// (mailgun, smtp and ses) and a MIME encoder for providers that send raw messages.
+package email
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/mail"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidMessage = errors.New("email: invalid message")
+	ErrUnsupported    = errors.New("email: unsupported by provider")
+)
+
+type (
+	// Message is an email with optional html, multiple recipients and attachments
+	Message struct {
+		From    string // defaults to provider's FromEmail
+		To      []string
+		CC      []string
+		BCC     []string
+		ReplyTo string
+		Subject string
+		Text    string
+		HTML    string
+		// Attachments are sent as files, Inline are referenced from html as cid:<Filename>
+		Attachments []Attachment
+		Inline      []Attachment
+		Headers     map[string]string
+		Tags        []string
+		// Variables are attached to the message and returned in provider events
+		Variables map[string]string
+		// DeliveryTime schedules the message, zero sends immediately
+		DeliveryTime time.Time
+	}
+
+	Attachment struct {
+		Filename    string
+		ContentType string
+		Content     io.Reader
+	}
+
+	// SendResponse is returned for an accepted message, ID can be used to
+	// correlate delivery events
+	SendResponse struct {
+		ID      string `json:"id"`
+		Message string `json:"message"`
+	}
+
+	// Sender is implemented by all email integrations
+	Sender interface {
+		SendEmail(to, subject, message string) error
+		SendHTMLEmail(to, subject, text, html string) error
+		SendMessage(ctx context.Context, msg Message) (*SendResponse, error)
+	}
+)
+
+// Validate checks that the message has a recipient, subject and body, that addresses parse
+// and that tags, header keys and filenames can't inject headers.
+func (m Message) Validate() error {
+	if len(m.To) == 0 {
+		return InvalidMessage("at least one recipient is required")
+	}
+	if m.Subject == "" {
+		return InvalidMessage("subject is required")
+	}
+	if m.Text == "" && m.HTML == "" {
+		return InvalidMessage("text or html body is required")
+	}
+	for _, a := range append(append([]Attachment{}, m.Attachments...), m.Inline...) {
+		if a.Filename == "" || a.Content == nil {
+			return InvalidMessage("attachment filename and content are required")
+		}
+		if strings.ContainsAny(a.Filename, "\r\n") {
+			return InvalidMessage(fmt.Sprintf("invalid attachment filename %q", a.Filename))
+		}
+	}
+	if _, err := formatAddresses(append(append(append([]string{m.From, m.ReplyTo}, m.To...), m.CC...), m.BCC...)...); err != nil {
+		return err
+	}
+	return m.validateHeaders()
+}
+
+func (m Message) validateHeaders() error {
+	for key := range m.Headers {
+		if key == "" || strings.ContainsAny(key, "\r\n: ") {
+			return InvalidMessage(fmt.Sprintf("invalid header key %q", key))
+		}
+	}
+	for _, tag := range m.Tags {
+		if strings.ContainsAny(tag, "\r\n:") {
+			return InvalidMessage(fmt.Sprintf("invalid tag %q", tag))
+		}
+	}
+	return nil
+}
+
+// formatAddresses parses the address lists and formats them as a header value, empty lists are skipped
+func formatAddresses(lists ...string) (string, error) {
+	var res []string
+	for _, list := range lists {
+		if list == "" {
+			continue
+		}
+		addrs, err := mail.ParseAddressList(list)
+		if err != nil {
+			return "", InvalidMessage(fmt.Sprintf("invalid address %q: %v", list, err))
+		}
+		for _, addr := range addrs {
+			res = append(res, addr.String())
+		}
+	}
+	return strings.Join(res, ", "), nil
+}
+
+// Envelope returns the bare from and to, cc and bcc addresses, without display names, for
+// the SMTP envelope. Entries may be "Name <a@b>" or comma separated lists.
+func (m Message) Envelope() (string, []string, error) {
+	from, err := mail.ParseAddress(m.From)
+	if err != nil {
+		return "", nil, InvalidMessage(fmt.Sprintf("invalid address %q: %v", m.From, err))
+	}
+	var rcpts []string
+	for _, list := range [][]string{m.To, m.CC, m.BCC} {
+		for _, entry := range list {
+			addrs, err := mail.ParseAddressList(entry)
+			if err != nil {
+				return "", nil, InvalidMessage(fmt.Sprintf("invalid address %q: %v", entry, err))
+			}
+			for _, addr := range addrs {
+				rcpts = append(rcpts, addr.Address)
+			}
+		}
+	}
+	return from.Address, rcpts, nil
+}
+
+func InvalidMessage(reason string) error {
+	return fmt.Errorf("%w: %s", ErrInvalidMessage, reason)
+}
@@ -0,0 +1,39 @@
+package email
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestMessage_HeaderInjection(t *testing.T) {
+	valid := Message{From: "App <noreply@example.com>", To: []string{"a@example.com"}, Subject: "hi", Text: "hi"}
+	cases := map[string]func(m *Message){
+		"to":         func(m *Message) { m.To = []string{"a@example.com\r\nBcc: evil@example.com"} },
+		"from":       func(m *Message) { m.From = "noreply@example.com\r\nX-Evil: 1" },
+		"reply-to":   func(m *Message) { m.ReplyTo = "support@example.com\nBcc: evil@example.com" },
+		"header key": func(m *Message) { m.Headers = map[string]string{"X-A\r\nBcc": "evil@example.com"} },
+		"tag":        func(m *Message) { m.Tags = []string{"welcome\r\nBcc: evil@example.com"} },
+	}
+	for name, mutate := range cases {
+		m := valid
+		mutate(&m)
+		if err := m.Validate(); !errors.Is(err, ErrInvalidMessage) {
+			t.Errorf("%s: expected ErrInvalidMessage, got %v", name, err)
+		}
+		if err := m.WriteMIME(&strings.Builder{}, "<id@example.com>"); err == nil {
+			t.Errorf("%s: expected WriteMIME to fail", name)
+		}
+	}
+
+	var b strings.Builder
+	if err := valid.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := valid.WriteMIME(&b, "<id@example.com>"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(b.String(), "From: \"App\" <noreply@example.com>\r\n") || !strings.Contains(b.String(), "To: <a@example.com>\r\n") {
+		t.Errorf("unexpected headers:\n%s", b.String())
+	}
+}
@@ -0,0 +1,213 @@
+package email
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"sort"
+	"strings"
+	"time"
+)
+
+const base64LineLength = 76
+
+// NewMessageID returns a random Message-ID for the domain of from
+func NewMessageID(from string) string {
+	domain := "localhost"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if _, d, found := strings.Cut(addr.Address, "@"); found {
+			domain = d
+		}
+	}
+	b := make([]byte, 16)
+	rand.Read(b)
+	return "<" + hex.EncodeToString(b) + "@" + domain + ">"
+}
+
+// WriteMIME writes m as an RFC 5322 message. BCC recipients are not written to
+// the headers, they must be passed to the provider as envelope recipients. Addresses
+// are parsed and reformatted so they can't inject headers.
+func (m Message) WriteMIME(w io.Writer, messageID string) error {
+	if err := m.validateHeaders(); err != nil {
+		return err
+	}
+	var addrs [4]string
+	for i, list := range [][]string{{m.From}, m.To, m.CC, {m.ReplyTo}} {
+		formatted, err := formatAddresses(list...)
+		if err != nil {
+			return err
+		}
+		addrs[i] = formatted
+	}
+
+	bw := bufio.NewWriter(w)
+	header := []struct{ key, value string }{
+		{"From", addrs[0]},
+		{"To", addrs[1]},
+		{"Cc", addrs[2]},
+		{"Reply-To", addrs[3]},
+		{"Subject", mime.QEncoding.Encode("utf-8", m.Subject)},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", messageID},
+		{"MIME-Version", "1.0"},
+	}
+	if len(m.Tags) > 0 {
+		header = append(header, struct{ key, value string }{"X-Tags", strings.Join(m.Tags, ", ")})
+	}
+	keys := make([]string, 0, len(m.Headers))
+	for key := range m.Headers {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		header = append(header, struct{ key, value string }{key, mime.QEncoding.Encode("utf-8", m.Headers[key])})
+	}
+	for _, h := range header {
+		if h.value != "" {
+			fmt.Fprintf(bw, "%s: %s\r\n", h.key, h.value)
+		}
+	}
+
+	body := m.bodyPart()
+	for key, values := range body.header {
+		fmt.Fprintf(bw, "%s: %s\r\n", key, values[0])
+	}
+	bw.WriteString("\r\n")
+	if err := body.write(bw); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+type mimePart struct {
+	header textproto.MIMEHeader
+	write  func(io.Writer) error
+}
+
+// bodyPart returns multipart/mixed{multipart/related{multipart/alternative{text, html}, inline...}, attachments...},
+// omitting the levels that aren't needed.
+func (m Message) bodyPart() mimePart {
+	var body mimePart
+	switch {
+	case m.Text != "" && m.HTML != "":
+		body = multipartPart("alternative", textPart("text/plain", m.Text), textPart("text/html", m.HTML))
+	case m.HTML != "":
+		body = textPart("text/html", m.HTML)
+	default:
+		body = textPart("text/plain", m.Text)
+	}
+	if len(m.Inline) > 0 {
+		parts := []mimePart{body}
+		for _, a := range m.Inline {
+			parts = append(parts, attachmentPart(a, "inline"))
+		}
+		body = multipartPart("related", parts...)
+	}
+	if len(m.Attachments) > 0 {
+		parts := []mimePart{body}
+		for _, a := range m.Attachments {
+			parts = append(parts, attachmentPart(a, "attachment"))
+		}
+		body = multipartPart("mixed", parts...)
+	}
+	return body
+}
+
+func multipartPart(subtype string, parts ...mimePart) mimePart {
+	boundary := randomBoundary()
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", "multipart/"+subtype+"; boundary="+boundary)
+	return mimePart{header: header, write: func(w io.Writer) error {
+		mw := multipart.NewWriter(w)
+		if err := mw.SetBoundary(boundary); err != nil {
+			return err
+		}
+		for _, p := range parts {
+			pw, err := mw.CreatePart(p.header)
+			if err != nil {
+				return err
+			}
+			if err := p.write(pw); err != nil {
+				return err
+			}
+		}
+		return mw.Close()
+	}}
+}
+
+func textPart(contentType, body string) mimePart {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType+"; charset=utf-8")
+	header.Set("Content-Transfer-Encoding", "quoted-printable")
+	return mimePart{header: header, write: func(w io.Writer) error {
+		qw := quotedprintable.NewWriter(w)
+		if _, err := io.WriteString(qw, body); err != nil {
+			return err
+		}
+		return qw.Close()
+	}}
+}
+
+func attachmentPart(a Attachment, disposition string) mimePart {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", mime.FormatMediaType(contentType, map[string]string{"name": a.Filename}))
+	header.Set("Content-Disposition", mime.FormatMediaType(disposition, map[string]string{"filename": a.Filename}))
+	header.Set("Content-Transfer-Encoding", "base64")
+	if disposition == "inline" {
+		header.Set("Content-ID", "<"+a.Filename+">")
+	}
+	return mimePart{header: header, write: func(w io.Writer) error {
+		enc := base64.NewEncoder(base64.StdEncoding, &lineWriter{w: w})
+		if _, err := io.Copy(enc, a.Content); err != nil {
+			return fmt.Errorf("email: read attachment %s: %w", a.Filename, err)
+		}
+		return enc.Close()
+	}}
+}
+
+func randomBoundary() string {
+	b := make([]byte, 24)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// lineWriter wraps base64 output at 76 characters as required by RFC 2045
+type lineWriter struct {
+	w   io.Writer
+	col int
+}
+
+func (l *lineWriter) Write(p []byte) (int, error) {
+	written := 0
+	for len(p) > 0 {
+		n := base64LineLength - l.col
+		if n > len(p) {
+			n = len(p)
+		}
+		if _, err := l.w.Write(p[:n]); err != nil {
+			return written, err
+		}
+		written += n
+		l.col += n
+		p = p[n:]
+		if l.col == base64LineLength {
+			if _, err := l.w.Write([]byte("\r\n")); err != nil {
+				return written, err
+			}
+			l.col = 0
+		}
+	}
+	return written, nil
+}
@@ -1,12 +1,10 @@
 package mailgun
 
 import (
-	"fmt"
-	"log"
+	"context"
 	"strings"
 
 	"github.com/dghubble/sling"
-	"github.com/pkg/errors"
 )
 
 type Config struct {
@@ -14,6 +12,8 @@ type Config struct {
 	Domain    string
 	FromEmail string
 	BaseURL   string
+	// WebhookSigningKey verifies event webhooks, see WebhookHandler
+	WebhookSigningKey string `log:"-"`
 }
 
 type Client struct {
@@ -29,31 +29,43 @@ func NewClient(config Config) *Client {
 	base := sling.New().Base(strings.TrimRight(baseURL, "/") + "/")
 	slingClient := base.New().
 		Set("Accept", "application/json").
-		Set("Content-Type", "application/x-www-form-urlencoded").
 		SetBasicAuth("api", config.APIKey)
 
 	return &Client{config: config, sling: slingClient}
 }
 
+// SendEmail sends a plain text email, see SendMessage for html, attachments etc.
 func (c *Client) SendEmail(to, subject, message string) error {
-	req := sendMessageRequest{
-		From:    c.config.FromEmail,
-		To:      to,
+	_, err := c.SendMessage(context.Background(), Message{
+		To:      []string{to},
 		Subject: subject,
 		Text:    message,
-	}
-	respError := map[string]any{}
-	resp, err := c.sling.New().
-		Post(fmt.Sprintf("v3/%s/messages", c.config.Domain)).
-		BodyForm(&req).
-		Receive(nil, &respError)
+	})
+	return err
+}
+
+// SendHTMLEmail sends an email with both text and html parts
+func (c *Client) SendHTMLEmail(to, subject, text, html string) error {
+	_, err := c.SendMessage(context.Background(), Message{
+		To:      []string{to},
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+	})
+	return err
+}
+
+// SendTrackedEmail sends an email with the text and, if not empty, html parts and returns its
+// message id for correlating delivery events, see notifications.TrackedEmailProvider
+func (c *Client) SendTrackedEmail(ctx context.Context, to, subject, text, html string) (string, error) {
+	res, err := c.SendMessage(ctx, Message{
+		To:      []string{to},
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+	})
 	if err != nil {
-		log.Printf("mailgun: error sending email to=%s err=%v", to, err)
-		return errors.Wrap(err, "error sending email")
-	}
-	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-		return nil
+		return "", err
 	}
-	log.Printf("mailgun: failed to send email to=%s status=%d resp=%v", to, resp.StatusCode, respError)
-	return fmt.Errorf("failed to send email")
+	return res.ID, nil
 }
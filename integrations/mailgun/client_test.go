@@ -0,0 +1,113 @@
+package mailgun
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestClient_SendMessage(t *testing.T) {
+	var got *http.Request
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			t.Errorf("parse multipart: %v", err)
+		}
+		got = r
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"id":"<123@mg.example.com>","message":"Queued. Thank you."}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(Config{APIKey: "key", Domain: "mg.example.com", FromEmail: "noreply@example.com", BaseURL: srv.URL})
+	deliverAt := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	res, err := client.SendMessage(context.Background(), Message{
+		To:           []string{"a@example.com", "b@example.com"},
+		CC:           []string{"c@example.com"},
+		BCC:          []string{"d@example.com"},
+		ReplyTo:      "support@example.com",
+		Subject:      "Report",
+		Text:         "see attached",
+		HTML:         `<p>see attached <img src="cid:logo.png"></p>`,
+		Attachments:  []Attachment{{Filename: "report.csv", ContentType: "text/csv", Content: strings.NewReader("a,b\n1,2")}},
+		Inline:       []Attachment{{Filename: "logo.png", ContentType: "image/png", Content: strings.NewReader("png")}},
+		Headers:      map[string]string{"X-Request-ID": "req-1"},
+		Tags:         []string{"report", "weekly"},
+		Variables:    map[string]string{"user_id": "42"},
+		DeliveryTime: deliverAt,
+	})
+	if err != nil {
+		t.Fatalf("send message failed: %v", err)
+	}
+	if res.ID != "<123@mg.example.com>" {
+		t.Fatalf("unexpected message id: %s", res.ID)
+	}
+
+	if got.URL.Path != "/v3/mg.example.com/messages" {
+		t.Fatalf("unexpected path: %s", got.URL.Path)
+	}
+	if user, pass, _ := got.BasicAuth(); user != "api" || pass != "key" {
+		t.Fatalf("unexpected basic auth: %s:%s", user, pass)
+	}
+	form := got.MultipartForm.Value
+	wantFields := map[string]string{
+		"from":           "noreply@example.com",
+		"to":             "a@example.com,b@example.com",
+		"cc":             "c@example.com",
+		"bcc":            "d@example.com",
+		"h:Reply-To":     "support@example.com",
+		"h:X-Request-ID": "req-1",
+		"v:user_id":      "42",
+		"o:deliverytime": deliverAt.Format(time.RFC1123Z),
+	}
+	for key, want := range wantFields {
+		if len(form[key]) != 1 || form[key][0] != want {
+			t.Fatalf("unexpected %s: %v, want %s", key, form[key], want)
+		}
+	}
+	if len(form["o:tag"]) != 2 {
+		t.Fatalf("expected 2 tags, got %v", form["o:tag"])
+	}
+
+	files := got.MultipartForm.File
+	if len(files["attachment"]) != 1 || files["attachment"][0].Filename != "report.csv" {
+		t.Fatalf("unexpected attachments: %v", files["attachment"])
+	}
+	f, _ := files["attachment"][0].Open()
+	content, _ := io.ReadAll(f)
+	if string(content) != "a,b\n1,2" {
+		t.Fatalf("unexpected attachment content: %q", content)
+	}
+	if len(files["inline"]) != 1 || files["inline"][0].Header.Get("Content-Type") != "image/png" {
+		t.Fatalf("unexpected inline files: %v", files["inline"])
+	}
+}
+
+func TestClient_SendMessageErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message":"'to' parameter is not a valid address"}`))
+	}))
+	defer srv.Close()
+	client := NewClient(Config{Domain: "mg.example.com", FromEmail: "noreply@example.com", BaseURL: srv.URL})
+
+	_, err := client.SendMessage(context.Background(), Message{Subject: "hi", Text: "hi"})
+	if !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("expected ErrInvalidMessage, got %v", err)
+	}
+
+	if err := client.SendEmail("not-an-email", "hi", "hi"); !errors.Is(err, ErrInvalidMessage) {
+		t.Fatalf("expected ErrInvalidMessage for invalid address, got %v", err)
+	}
+
+	err = client.SendEmail("user@example.com", "hi", "hi")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest || !strings.Contains(apiErr.Message, "not a valid address") {
+		t.Fatalf("expected APIError with status 400, got %v", err)
+	}
+}
@@ -0,0 +1,26 @@
+package mailgun
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/krsoninikhil/go-rest-kit/integrations/email"
+)
+
+var (
+	ErrInvalidMessage = email.ErrInvalidMessage
+	ErrRequestFailed  = errors.New("mailgun: request failed")
+)
+
+// APIError is returned when mailgun responds with a non 2xx status
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("mailgun: status %d", e.StatusCode)
+	}
+	return fmt.Sprintf("mailgun: status %d: %s", e.StatusCode, e.Message)
+}
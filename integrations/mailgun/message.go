@@ -0,0 +1,134 @@
+package mailgun
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"mime/multipart"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/krsoninikhil/go-rest-kit/integrations/email"
+)
+
+// Message, Attachment and SendResponse are shared by all email integrations
+type (
+	Message      = email.Message
+	Attachment   = email.Attachment
+	SendResponse = email.SendResponse
+
+	errorResponse struct {
+		Message string `json:"message"`
+	}
+)
+
+// SendMessage sends msg using mailgun's multipart messages API
+func (c *Client) SendMessage(ctx context.Context, msg Message) (*SendResponse, error) {
+	if msg.From == "" {
+		msg.From = c.config.FromEmail
+	}
+	if err := msg.Validate(); err != nil {
+		return nil, err
+	}
+
+	body, contentType, err := multipartBody(msg)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := c.sling.New().
+		Post(fmt.Sprintf("v3/%s/messages", c.config.Domain)).
+		Body(body).
+		Set("Content-Type", contentType).
+		Request()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRequestFailed, err)
+	}
+
+	var (
+		res       SendResponse
+		respError errorResponse
+	)
+	resp, err := c.sling.Do(req.WithContext(ctx), &res, &respError)
+	if resp != nil && (resp.StatusCode < 200 || resp.StatusCode >= 300) {
+		log.Printf("mailgun: failed to send email to=%v status=%d resp=%v", msg.To, resp.StatusCode, respError.Message)
+		return nil, &APIError{StatusCode: resp.StatusCode, Message: respError.Message}
+	}
+	if err != nil {
+		log.Printf("mailgun: error sending email to=%v err=%v", msg.To, err)
+		return nil, fmt.Errorf("%w: %v", ErrRequestFailed, err)
+	}
+	return &res, nil
+}
+
+func multipartBody(m Message) (io.Reader, string, error) {
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+
+	fields := []struct{ key, value string }{
+		{"from", m.From},
+		{"to", strings.Join(m.To, ",")},
+		{"cc", strings.Join(m.CC, ",")},
+		{"bcc", strings.Join(m.BCC, ",")},
+		{"subject", m.Subject},
+		{"text", m.Text},
+		{"html", m.HTML},
+		{"h:Reply-To", m.ReplyTo},
+	}
+	if !m.DeliveryTime.IsZero() {
+		fields = append(fields, struct{ key, value string }{"o:deliverytime", m.DeliveryTime.Format(time.RFC1123Z)})
+	}
+	for _, tag := range m.Tags {
+		fields = append(fields, struct{ key, value string }{"o:tag", tag})
+	}
+	for key, value := range m.Headers {
+		fields = append(fields, struct{ key, value string }{"h:" + key, value})
+	}
+	for key, value := range m.Variables {
+		fields = append(fields, struct{ key, value string }{"v:" + key, value})
+	}
+	for _, f := range fields {
+		if f.value == "" {
+			continue
+		}
+		if err := w.WriteField(f.key, f.value); err != nil {
+			return nil, "", fmt.Errorf("%w: %v", ErrRequestFailed, err)
+		}
+	}
+
+	for _, files := range []struct {
+		field       string
+		attachments []Attachment
+	}{{"attachment", m.Attachments}, {"inline", m.Inline}} {
+		for _, a := range files.attachments {
+			if err := writeAttachment(w, files.field, a); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+	if err := w.Close(); err != nil {
+		return nil, "", fmt.Errorf("%w: %v", ErrRequestFailed, err)
+	}
+	return &buf, w.FormDataContentType(), nil
+}
+
+func writeAttachment(w *multipart.Writer, field string, a Attachment) error {
+	contentType := a.ContentType
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name=%q; filename=%q`, field, a.Filename))
+	header.Set("Content-Type", contentType)
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRequestFailed, err)
+	}
+	if _, err := io.Copy(part, a.Content); err != nil {
+		return fmt.Errorf("%w: read attachment %s: %v", ErrRequestFailed, a.Filename, err)
+	}
+	return nil
+}
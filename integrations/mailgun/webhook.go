@@ -0,0 +1,158 @@
+package mailgun
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krsoninikhil/go-rest-kit/integrations/delivery"
+)
+
+const (
+	providerName = "mailgun"
+	// maxSignatureAge rejects replayed webhooks
+	maxSignatureAge = 15 * time.Minute
+)
+
+type (
+	webhookSignature struct {
+		Timestamp string `json:"timestamp"`
+		Token     string `json:"token"`
+		Signature string `json:"signature"`
+	}
+
+	webhookEvent struct {
+		Signature webhookSignature `json:"signature"`
+		EventData struct {
+			Event     string  `json:"event"`
+			Severity  string  `json:"severity"`
+			Recipient string  `json:"recipient"`
+			Timestamp float64 `json:"timestamp"`
+			Reason    string  `json:"reason"`
+			Message   struct {
+				Headers struct {
+					MessageID string `json:"message-id"`
+				} `json:"headers"`
+			} `json:"message"`
+			DeliveryStatus struct {
+				Code        int    `json:"code"`
+				Message     string `json:"message"`
+				Description string `json:"description"`
+			} `json:"delivery-status"`
+			UserVariables map[string]any `json:"user-variables"`
+		} `json:"event-data"`
+	}
+)
+
+// WebhookHandler receives mailgun event webhooks, verifies their signature with
+// Config.WebhookSigningKey and publishes them to sink as delivery events.
+func (c *Client) WebhookHandler(sink delivery.Sink) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var payload webhookEvent
+		if err := ctx.ShouldBindJSON(&payload); err != nil {
+			ctx.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		sig := payload.Signature
+		if !ValidSignature(c.config.WebhookSigningKey, sig.Timestamp, sig.Token, sig.Signature) {
+			log.Printf("mailgun: invalid webhook signature timestamp=%s", sig.Timestamp)
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		event := payload.toEvent()
+		if err := sink.Publish(ctx, event); err != nil {
+			log.Printf("mailgun: error publishing delivery event id=%s err=%v", event.MessageID, err)
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		ctx.Status(http.StatusNoContent)
+	}
+}
+
+// ValidSignature verifies mailgun's webhook signature: hex HMAC-SHA256 of timestamp
+// followed by token, signed with the webhook signing key. Stale timestamps are rejected.
+func ValidSignature(signingKey, timestamp, token, signature string) bool {
+	if signingKey == "" || signature == "" {
+		return false
+	}
+	sec, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil || math.Abs(time.Since(time.Unix(sec, 0)).Seconds()) > maxSignatureAge.Seconds() {
+		return false
+	}
+	expected, err := hex.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	mac := hmac.New(sha256.New, []byte(signingKey))
+	mac.Write([]byte(timestamp + token))
+	return hmac.Equal(expected, mac.Sum(nil))
+}
+
+func (w webhookEvent) toEvent() delivery.Event {
+	data := w.EventData
+	sec, frac := math.Modf(data.Timestamp)
+	timestamp := time.Unix(int64(sec), int64(frac*float64(time.Second)))
+	if data.Timestamp == 0 {
+		timestamp = time.Now()
+	}
+
+	reason := data.Reason
+	if status := data.DeliveryStatus; status.Message != "" || status.Description != "" {
+		reason = strings.TrimSpace(fmt.Sprintf("%s %s %s", reason, status.Message, status.Description))
+	}
+
+	var metadata map[string]string
+	if len(data.UserVariables) > 0 {
+		metadata = make(map[string]string, len(data.UserVariables))
+		for key, value := range data.UserVariables {
+			metadata[key] = fmt.Sprint(value)
+		}
+	}
+
+	return delivery.Event{
+		Provider: providerName,
+		Channel:  delivery.ChannelEmail,
+		// SendResponse.ID wraps the id in angle brackets, events don't
+		MessageID: "<" + delivery.NormalizeMessageID(data.Message.Headers.MessageID) + ">",
+		Recipient: data.Recipient,
+		Status:    eventStatus(data.Event, data.Severity),
+		Reason:    reason,
+		Timestamp: timestamp,
+		Metadata:  metadata,
+	}
+}
+
+func eventStatus(event, severity string) delivery.Status {
+	switch event {
+	case "accepted":
+		return delivery.StatusQueued
+	case "delivered":
+		return delivery.StatusDelivered
+	case "failed":
+		if severity == "temporary" {
+			return delivery.StatusDeferred
+		}
+		return delivery.StatusFailed
+	case "rejected":
+		return delivery.StatusFailed
+	case "opened":
+		return delivery.StatusOpened
+	case "clicked":
+		return delivery.StatusClicked
+	case "complained":
+		return delivery.StatusComplained
+	case "unsubscribed":
+		return delivery.StatusUnsubscribed
+	default:
+		return delivery.StatusUnknown
+	}
+}
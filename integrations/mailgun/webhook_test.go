@@ -0,0 +1,72 @@
+package mailgun
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krsoninikhil/go-rest-kit/integrations/delivery"
+)
+
+func TestClient_WebhookHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const signingKey = "whsec"
+	client := NewClient(Config{WebhookSigningKey: signingKey})
+
+	var got []delivery.Event
+	router := gin.New()
+	router.POST("/webhooks/mailgun", client.WebhookHandler(delivery.SinkFunc(func(_ context.Context, e delivery.Event) error {
+		got = append(got, e)
+		return nil
+	})))
+
+	post := func(signature string) int {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		if signature == "" {
+			mac := hmac.New(sha256.New, []byte(signingKey))
+			mac.Write([]byte(timestamp + "tok"))
+			signature = hex.EncodeToString(mac.Sum(nil))
+		}
+		body := fmt.Sprintf(`{
+			"signature": {"timestamp": %q, "token": "tok", "signature": %q},
+			"event-data": {
+				"event": "failed", "severity": "permanent", "recipient": "a@example.com",
+				"timestamp": 1700000000.5, "reason": "bounce",
+				"message": {"headers": {"message-id": "123@mg.example.com"}},
+				"delivery-status": {"code": 550, "message": "mailbox unavailable"},
+				"user-variables": {"user_id": "42"}
+			}
+		}`, timestamp, signature)
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/mailgun", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := post("deadbeef"); code != http.StatusForbidden {
+		t.Fatalf("invalid signature: expected 403, got %d", code)
+	}
+	if code := post(""); code != http.StatusNoContent {
+		t.Fatalf("valid signature: expected 204, got %d", code)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(got))
+	}
+	e := got[0]
+	if e.MessageID != "<123@mg.example.com>" || e.Status != delivery.StatusFailed || e.Channel != delivery.ChannelEmail {
+		t.Errorf("unexpected event %+v", e)
+	}
+	if e.Metadata["user_id"] != "42" || !strings.Contains(e.Reason, "mailbox unavailable") {
+		t.Errorf("unexpected event details %+v", e)
+	}
+}
@@ -4,20 +4,26 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
-	"fmt"
 	"io"
 	"net/http"
+	"time"
 
 	"github.com/pkg/errors"
 )
 
-const defaultBaseURL = "https://openrouter.ai"
+const (
+	defaultBaseURL        = "https://openrouter.ai"
+	defaultTimeoutSeconds = 60
+)
 
 // Config holds OpenRouter client configuration.
 type Config struct {
 	APIKey  string `json:"-"` // sensitive; omit from logs
 	Model   string // e.g. "openai/gpt-4o-mini"
 	BaseURL string // optional; default https://openrouter.ai
+	// TimeoutSeconds is the max wait for response headers, optional; default 60.
+	// It doesn't limit reading a stream, use ctx for that.
+	TimeoutSeconds int
 }
 
 // Client calls the OpenRouter chat completions API.
@@ -28,73 +34,98 @@ type Client struct {
 
 // NewClient creates an OpenRouter client. If BaseURL is empty, default is used.
 func NewClient(config Config) *Client {
-	baseURL := config.BaseURL
-	if baseURL == "" {
-		baseURL = defaultBaseURL
+	if config.BaseURL == "" {
+		config.BaseURL = defaultBaseURL
 	}
-
-	return &Client{
-		config: Config{
-			APIKey:  config.APIKey,
-			Model:   config.Model,
-			BaseURL: baseURL,
-		},
-		http: &http.Client{},
+	if config.TimeoutSeconds == 0 {
+		config.TimeoutSeconds = defaultTimeoutSeconds
 	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.ResponseHeaderTimeout = time.Duration(config.TimeoutSeconds) * time.Second
+	return &Client{config: config, http: &http.Client{Transport: transport}}
 }
 
 // Chat sends system and user messages to OpenRouter and returns the assistant's content.
 // It uses a single non-streaming request. ctx is used for cancellation.
 func (c *Client) Chat(ctx context.Context, system, user string) (content string, err error) {
-	if c.config.APIKey == "" {
-		return "", errors.New("openrouter: API key is required")
+	out, err := c.Complete(ctx, ChatRequest{Messages: []Message{
+		{Role: "system", Content: system},
+		{Role: "user", Content: user},
+	}})
+	if err != nil {
+		return "", err
 	}
-	if c.config.Model == "" {
-		return "", errors.New("openrouter: model is required")
+	if len(out.Choices) == 0 {
+		return "", errors.New("openrouter: no choices in response")
+	}
+	content = out.Choices[0].Message.Content
+	if content == "" {
+		return "", errors.New("openrouter: empty content in first choice")
 	}
+	return content, nil
+}
 
-	messages := []Message{
-		{Role: "system", Content: system},
-		{Role: "user", Content: user},
+// Complete sends a chat completion request and returns the complete response.
+func (c *Client) Complete(ctx context.Context, body ChatRequest) (*ChatResponse, error) {
+	body.Stream = false
+	resp, err := c.do(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	var out ChatResponse
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, errors.Wrap(err, "openrouter: decode response")
+	}
+	return &out, nil
+}
+
+// Stream sends a streaming chat completion request, caller must Close the returned stream.
+func (c *Client) Stream(ctx context.Context, body ChatRequest) (*Stream, error) {
+	body.Stream = true
+	resp, err := c.do(ctx, body)
+	if err != nil {
+		return nil, err
+	}
+	return newStream(resp.Body), nil
+}
+
+func (c *Client) do(ctx context.Context, body ChatRequest) (*http.Response, error) {
+	if c.config.APIKey == "" {
+		return nil, errors.New("openrouter: API key is required")
 	}
-	body := ChatRequest{Model: c.config.Model, Messages: messages}
+	if body.Model == "" {
+		body.Model = c.config.Model
+	}
+	if body.Model == "" {
+		return nil, errors.New("openrouter: model is required")
+	}
+
 	var buf bytes.Buffer
 	if err := json.NewEncoder(&buf).Encode(body); err != nil {
-		return "", errors.Wrap(err, "openrouter: encode request")
+		return nil, errors.Wrap(err, "openrouter: encode request")
 	}
 
 	url := c.config.BaseURL + "/api/v1/chat/completions"
 	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &buf)
 	if err != nil {
-		return "", errors.Wrap(err, "openrouter: create request")
+		return nil, errors.Wrap(err, "openrouter: create request")
 	}
 	req.Header.Set("Authorization", "Bearer "+c.config.APIKey)
 	req.Header.Set("Content-Type", "application/json")
+	if body.Stream {
+		req.Header.Set("Accept", "text/event-stream")
+	}
 
 	resp, err := c.http.Do(req)
 	if err != nil {
-		return "", errors.Wrap(err, "openrouter: request failed")
+		return nil, errors.Wrap(err, "openrouter: request failed")
 	}
-	defer resp.Body.Close()
-
-	respBody, _ := io.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		return "", errors.Wrap(
-			fmt.Errorf("openrouter: HTTP %d: %s", resp.StatusCode, string(respBody)),
-			"openrouter",
-		)
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(resp.Body)
+		return nil, newAPIError(resp, respBody)
 	}
-
-	var out ChatResponse
-	if err := json.Unmarshal(respBody, &out); err != nil {
-		return "", errors.Wrap(err, "openrouter: decode response")
-	}
-	if len(out.Choices) == 0 {
-		return "", errors.New("openrouter: no choices in response")
-	}
-	content = out.Choices[0].Message.Content
-	if content == "" {
-		return "", errors.New("openrouter: empty content in first choice")
-	}
-	return content, nil
+	return resp, nil
 }
@@ -0,0 +1,120 @@
+package openrouter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Stream iterates over the chunks of a streaming response:
+//
+//	for stream.Next() {
+//		chunk := stream.Chunk()
+//	}
+//	if err := stream.Err(); err != nil {...}
+//
+// Response returns the response accumulated so far, complete once Next returns false.
+type Stream struct {
+	body     io.ReadCloser
+	scanner  *bufio.Scanner
+	chunk    ChatResponse
+	err      error
+	response ChatResponse
+	content  strings.Builder
+}
+
+type streamChunk struct {
+	ChatResponse
+	Error *errorDetail `json:"error,omitempty"`
+}
+
+func newStream(body io.ReadCloser) *Stream {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	return &Stream{body: body, scanner: scanner}
+}
+
+// Next advances to the next chunk, it returns false at the end of the stream or on error.
+func (s *Stream) Next() bool {
+	if s.err != nil {
+		return false
+	}
+	for s.scanner.Scan() {
+		data, ok := bytes.CutPrefix(s.scanner.Bytes(), []byte("data:"))
+		if !ok {
+			continue // blank lines and ": OPENROUTER PROCESSING" comments
+		}
+		data = bytes.TrimSpace(data)
+		if string(data) == "[DONE]" {
+			return false
+		}
+
+		var chunk streamChunk
+		if err := json.Unmarshal(data, &chunk); err != nil {
+			s.err = errors.Wrap(err, "openrouter: decode stream chunk")
+			return false
+		}
+		if chunk.Error != nil {
+			s.err = &APIError{StatusCode: chunk.Error.Code, Message: chunk.Error.Message}
+			return false
+		}
+		s.chunk = chunk.ChatResponse
+		s.accumulate(chunk.ChatResponse)
+		return true
+	}
+	if err := s.scanner.Err(); err != nil {
+		s.err = errors.Wrap(err, "openrouter: read stream")
+	}
+	return false
+}
+
+// Chunk returns the current chunk, content is in Choices[0].Delta
+func (s *Stream) Chunk() ChatResponse { return s.chunk }
+
+// Text returns the content delta of the current chunk
+func (s *Stream) Text() string {
+	if len(s.chunk.Choices) == 0 || s.chunk.Choices[0].Delta == nil {
+		return ""
+	}
+	return s.chunk.Choices[0].Delta.Content
+}
+
+func (s *Stream) Err() error { return s.err }
+
+// Response returns the response accumulated from the chunks so far
+func (s *Stream) Response() *ChatResponse {
+	res := s.response
+	if len(res.Choices) > 0 {
+		res.Choices = append([]Choice(nil), res.Choices...)
+		res.Choices[0].Message = Message{Role: "assistant", Content: s.content.String()}
+	}
+	return &res
+}
+
+func (s *Stream) Close() error { return s.body.Close() }
+
+func (s *Stream) accumulate(chunk ChatResponse) {
+	if s.response.ID == "" {
+		s.response.ID, s.response.Model = chunk.ID, chunk.Model
+	}
+	if chunk.Usage != nil {
+		s.response.Usage = chunk.Usage
+	}
+	if len(chunk.Choices) == 0 {
+		return
+	}
+	if len(s.response.Choices) == 0 {
+		s.response.Choices = []Choice{{}}
+	}
+	choice := chunk.Choices[0]
+	if choice.Delta != nil {
+		s.content.WriteString(choice.Delta.Content)
+	}
+	if choice.FinishReason != "" {
+		s.response.Choices[0].FinishReason = choice.FinishReason
+	}
+}
@@ -1,9 +1,29 @@
 package openrouter
 
 // ChatRequest is the request body for OpenRouter chat completions (OpenAI-compatible).
+// Model defaults to the client's config when empty.
 type ChatRequest struct {
-	Model    string    `json:"model"`
-	Messages []Message `json:"messages"`
+	Model       string    `json:"model"`
+	Messages    []Message `json:"messages"`
+	MaxTokens   int       `json:"max_tokens,omitempty"`
+	Temperature *float64  `json:"temperature,omitempty"`
+	Stop        []string  `json:"stop,omitempty"`
+	Stream      bool      `json:"stream,omitempty"`
+	// ResponseFormat requests JSON output, supported models only
+	ResponseFormat *ResponseFormat `json:"response_format,omitempty"`
+}
+
+// ResponseFormat is json_object or json_schema with JSONSchema set
+type ResponseFormat struct {
+	Type       string      `json:"type"`
+	JSONSchema *JSONSchema `json:"json_schema,omitempty"`
+}
+
+type JSONSchema struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Strict      bool   `json:"strict"`
+	Schema      any    `json:"schema"`
 }
 
 // Message represents a single message in the chat.
@@ -15,15 +35,17 @@ type Message struct {
 // ChatResponse is the response from OpenRouter chat completions.
 type ChatResponse struct {
 	ID      string   `json:"id,omitempty"`
+	Model   string   `json:"model,omitempty"`
 	Choices []Choice `json:"choices,omitempty"`
 	Usage   *Usage   `json:"usage,omitempty"`
 }
 
-// Choice holds the model's reply.
+// Choice holds the model's reply, Delta is set instead of Message for stream chunks.
 type Choice struct {
-	Message      Message `json:"message"`
-	FinishReason string  `json:"finish_reason,omitempty"`
-	Index        int     `json:"index,omitempty"`
+	Message      Message  `json:"message"`
+	Delta        *Message `json:"delta,omitempty"`
+	FinishReason string   `json:"finish_reason,omitempty"`
+	Index        int      `json:"index,omitempty"`
 }
 
 // Usage holds token usage stats.
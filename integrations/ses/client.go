@@ -0,0 +1,151 @@
+// Package ses sends email through the AWS SES v2 API using raw MIME messages.
+package ses
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awshttp "github.com/aws/aws-sdk-go-v2/aws/transport/http"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/smithy-go"
+	"github.com/krsoninikhil/go-rest-kit/integrations/email"
+)
+
+var ErrRequestFailed = errors.New("ses: request failed")
+
+// Config holds SES configuration. Credentials are loaded from the default credential
+// chain unless AccessKeyID and SecretAccessKey are set.
+type Config struct {
+	Region           string `mapstructure:"region"`
+	FromEmail        string `mapstructure:"from_email" validate:"required"`
+	ConfigurationSet string `mapstructure:"configuration_set"`
+	// Endpoint overrides the regional endpoint, e.g. for a local SES stand-in
+	Endpoint        string `mapstructure:"endpoint"`
+	AccessKeyID     string `mapstructure:"access_key_id"`
+	SecretAccessKey string `mapstructure:"secret_access_key" log:"-"`
+}
+
+// APIError is returned when SES rejects a request
+type APIError struct {
+	StatusCode int
+	Type       string
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("ses: status %d: %s: %s", e.StatusCode, e.Type, e.Message)
+}
+
+// Client sends emails through SES and implements email.Sender
+type Client struct {
+	config Config
+	client *sesv2.Client
+}
+
+var _ email.Sender = (*Client)(nil)
+
+func NewClient(ctx context.Context, cfg Config) (*Client, error) {
+	if cfg.Region == "" {
+		cfg.Region = "us-east-1"
+	}
+	opts := []func(*config.LoadOptions) error{config.WithRegion(cfg.Region)}
+	if cfg.AccessKeyID != "" {
+		opts = append(opts, config.WithCredentialsProvider(
+			credentials.NewStaticCredentialsProvider(cfg.AccessKeyID, cfg.SecretAccessKey, ""),
+		))
+	}
+	awsCfg, err := config.LoadDefaultConfig(ctx, opts...)
+	if err != nil {
+		return nil, fmt.Errorf("ses: load aws config: %w", err)
+	}
+	client := sesv2.NewFromConfig(awsCfg, func(o *sesv2.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+		}
+	})
+	return &Client{config: cfg, client: client}, nil
+}
+
+// SendEmail sends a plain text email, see SendMessage for html, attachments etc.
+func (c *Client) SendEmail(to, subject, message string) error {
+	_, err := c.SendMessage(context.Background(), email.Message{
+		To:      []string{to},
+		Subject: subject,
+		Text:    message,
+	})
+	return err
+}
+
+// SendHTMLEmail sends an email with both text and html parts
+func (c *Client) SendHTMLEmail(to, subject, text, html string) error {
+	_, err := c.SendMessage(context.Background(), email.Message{
+		To:      []string{to},
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+	})
+	return err
+}
+
+// SendTrackedEmail sends an email with the text and, if not empty, html parts and returns its
+// message id for correlating delivery events, see notifications.TrackedEmailProvider
+func (c *Client) SendTrackedEmail(ctx context.Context, to, subject, text, html string) (string, error) {
+	res, err := c.SendMessage(ctx, email.Message{
+		To:      []string{to},
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+	})
+	if err != nil {
+		return "", err
+	}
+	return res.ID, nil
+}
+
+// SendMessage sends msg as a raw email. Tags and variables are sent as SES message tags,
+// scheduled delivery isn't supported.
+func (c *Client) SendMessage(ctx context.Context, msg email.Message) (*email.SendResponse, error) {
+	if msg.From == "" {
+		msg.From = c.config.FromEmail
+	}
+	if err := msg.Validate(); err != nil {
+		return nil, err
+	}
+	if !msg.DeliveryTime.IsZero() {
+		return nil, fmt.Errorf("%w: scheduled delivery", email.ErrUnsupported)
+	}
+
+	var raw bytes.Buffer
+	if err := msg.WriteMIME(&raw, email.NewMessageID(msg.From)); err != nil {
+		return nil, err
+	}
+	out, err := c.client.SendEmail(ctx, newSendEmailInput(msg, raw.Bytes(), c.config.ConfigurationSet))
+	if err != nil {
+		err = mapError(err)
+		log.Printf("ses: error sending email to=%v err=%v", msg.To, err)
+		return nil, err
+	}
+	// SES sets its own Message-ID header, MessageId is used to correlate events
+	return &email.SendResponse{ID: aws.ToString(out.MessageId), Message: "Queued"}, nil
+}
+
+func mapError(err error) error {
+	var (
+		apiErr  smithy.APIError
+		respErr *awshttp.ResponseError
+	)
+	if !errors.As(err, &apiErr) {
+		return fmt.Errorf("%w: %v", ErrRequestFailed, err)
+	}
+	res := &APIError{Type: apiErr.ErrorCode(), Message: apiErr.ErrorMessage()}
+	if errors.As(err, &respErr) {
+		res.StatusCode = respErr.HTTPStatusCode()
+	}
+	return res
+}
@@ -0,0 +1,97 @@
+package ses
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/krsoninikhil/go-rest-kit/integrations/email"
+)
+
+func newTestClient(t *testing.T, handler http.HandlerFunc) *Client {
+	t.Helper()
+	srv := httptest.NewServer(handler)
+	t.Cleanup(srv.Close)
+	client, err := NewClient(context.Background(), Config{
+		Region:          "eu-west-1",
+		FromEmail:       "noreply@example.com",
+		Endpoint:        srv.URL,
+		AccessKeyID:     "AKIDEXAMPLE",
+		SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatalf("new client: %v", err)
+	}
+	return client
+}
+
+// sendEmailRequest is the SES v2 SendEmail request body
+type sendEmailRequest struct {
+	FromEmailAddress string
+	Destination      struct{ BccAddresses []string }
+	Content          struct{ Raw struct{ Data []byte } }
+	EmailTags        []struct{ Name, Value string }
+}
+
+func TestClient_SendMessage(t *testing.T) {
+	var got sendEmailRequest
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v2/email/outbound-emails" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+		}
+		auth := r.Header.Get("Authorization")
+		if !strings.Contains(auth, "Credential=AKIDEXAMPLE/") || !strings.Contains(auth, "/eu-west-1/ses/aws4_request") {
+			t.Errorf("unexpected authorization header: %s", auth)
+		}
+		body, _ := io.ReadAll(r.Body)
+		if err := json.Unmarshal(body, &got); err != nil {
+			t.Errorf("decode request: %v", err)
+		}
+		w.Write([]byte(`{"MessageId":"0100-abc"}`))
+	})
+
+	res, err := client.SendMessage(context.Background(), email.Message{
+		To:        []string{"a@example.com"},
+		BCC:       []string{"hidden@example.com"},
+		ReplyTo:   "support@example.com",
+		Subject:   "hello",
+		HTML:      "<p>hi</p>",
+		Tags:      []string{"otp"},
+		Variables: map[string]string{"user_id": "42"},
+	})
+	if err != nil {
+		t.Fatalf("send message failed: %v", err)
+	}
+	if res.ID != "0100-abc" {
+		t.Fatalf("unexpected message id: %s", res.ID)
+	}
+	if got.FromEmailAddress != "noreply@example.com" || got.Destination.BccAddresses[0] != "hidden@example.com" {
+		t.Fatalf("unexpected request: %+v", got)
+	}
+	if len(got.EmailTags) != 2 || got.EmailTags[1].Name != "user_id" || got.EmailTags[1].Value != "42" {
+		t.Fatalf("unexpected tags: %+v", got.EmailTags)
+	}
+	raw := string(got.Content.Raw.Data)
+	if !strings.Contains(raw, "Subject: hello") || !strings.Contains(raw, "text/html") || strings.Contains(raw, "hidden@example.com") {
+		t.Fatalf("unexpected raw message:\n%s", raw)
+	}
+}
+
+func TestClient_SendMessageAPIError(t *testing.T) {
+	client := newTestClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("x-amzn-ErrorType", "MessageRejected:http://internal.amazon.com/coral/com.amazonaws.sesv2/")
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message":"Email address is not verified."}`))
+	})
+
+	err := client.SendEmail("a@example.com", "hello", "hi")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Type != "MessageRejected" || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected MessageRejected APIError, got %v", err)
+	}
+}
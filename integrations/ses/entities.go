@@ -0,0 +1,40 @@
+package ses
+
+import (
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2"
+	"github.com/aws/aws-sdk-go-v2/service/sesv2/types"
+	"github.com/krsoninikhil/go-rest-kit/integrations/email"
+)
+
+func newSendEmailInput(msg email.Message, raw []byte, configurationSet string) *sesv2.SendEmailInput {
+	in := &sesv2.SendEmailInput{
+		FromEmailAddress: aws.String(msg.From),
+		Destination: &types.Destination{
+			ToAddresses:  msg.To,
+			CcAddresses:  msg.CC,
+			BccAddresses: msg.BCC,
+		},
+		Content: &types.EmailContent{Raw: &types.RawMessage{Data: raw}},
+	}
+	if configurationSet != "" {
+		in.ConfigurationSetName = aws.String(configurationSet)
+	}
+	if msg.ReplyTo != "" {
+		in.ReplyToAddresses = []string{msg.ReplyTo}
+	}
+	for _, tag := range msg.Tags {
+		in.EmailTags = append(in.EmailTags, types.MessageTag{Name: aws.String(tag), Value: aws.String("true")})
+	}
+	keys := make([]string, 0, len(msg.Variables))
+	for key := range msg.Variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		in.EmailTags = append(in.EmailTags, types.MessageTag{Name: aws.String(key), Value: aws.String(msg.Variables[key])})
+	}
+	return in
+}
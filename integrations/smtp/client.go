@@ -0,0 +1,297 @@
+// Package smtp sends email over SMTP with STARTTLS or implicit TLS, and reuses
+// connections across messages.
+package smtp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/smtp"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/krsoninikhil/go-rest-kit/integrations/email"
+)
+
+const (
+	TLSModeStartTLS = "starttls" // upgrade plain connection, required to be supported by server
+	TLSModeImplicit = "tls"      // connect over tls, usually port 465
+	TLSModeNone     = "none"     // plain text, only for local relays and tests
+
+	defaultPort         = 587
+	defaultMaxIdleConns = 2
+	defaultTimeout      = 30 * time.Second
+	defaultIdleTimeout  = 60 * time.Second
+)
+
+var ErrRequestFailed = errors.New("smtp: request failed")
+
+type Config struct {
+	Host      string `validate:"required"`
+	Port      int
+	Username  string
+	Password  string `log:"-"`
+	FromEmail string `validate:"required"`
+	// TLSMode is one of starttls (default), tls or none
+	TLSMode            string
+	InsecureSkipVerify bool
+	// LocalName is sent in EHLO, defaults to localhost
+	LocalName          string
+	MaxIdleConns       int
+	TimeoutSeconds     int
+	IdleTimeoutSeconds int
+}
+
+func (c Config) address() string {
+	port := c.Port
+	if port == 0 {
+		port = defaultPort
+	}
+	return net.JoinHostPort(c.Host, strconv.Itoa(port))
+}
+
+func (c Config) timeout() time.Duration {
+	if c.TimeoutSeconds <= 0 {
+		return defaultTimeout
+	}
+	return time.Duration(c.TimeoutSeconds) * time.Second
+}
+
+func (c Config) idleTimeout() time.Duration {
+	if c.IdleTimeoutSeconds <= 0 {
+		return defaultIdleTimeout
+	}
+	return time.Duration(c.IdleTimeoutSeconds) * time.Second
+}
+
+type conn struct {
+	client   *smtp.Client
+	netConn  net.Conn
+	lastUsed time.Time
+}
+
+// Client sends emails through an SMTP server and implements email.Sender
+type Client struct {
+	config Config
+	idle   chan *conn
+	closed chan struct{}
+	once   sync.Once
+}
+
+var _ email.Sender = (*Client)(nil)
+
+func NewClient(config Config) *Client {
+	if config.TLSMode == "" {
+		config.TLSMode = TLSModeStartTLS
+	}
+	if config.LocalName == "" {
+		config.LocalName = "localhost"
+	}
+	maxIdle := config.MaxIdleConns
+	if maxIdle <= 0 {
+		maxIdle = defaultMaxIdleConns
+	}
+	return &Client{
+		config: config,
+		idle:   make(chan *conn, maxIdle),
+		closed: make(chan struct{}),
+	}
+}
+
+// SendEmail sends a plain text email, see SendMessage for html, attachments etc.
+func (c *Client) SendEmail(to, subject, message string) error {
+	_, err := c.SendMessage(context.Background(), email.Message{
+		To:      []string{to},
+		Subject: subject,
+		Text:    message,
+	})
+	return err
+}
+
+// SendHTMLEmail sends an email with both text and html parts
+func (c *Client) SendHTMLEmail(to, subject, text, html string) error {
+	_, err := c.SendMessage(context.Background(), email.Message{
+		To:      []string{to},
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+	})
+	return err
+}
+
+// SendTrackedEmail sends an email with the text and, if not empty, html parts and returns its
+// message id for correlating delivery events, see notifications.TrackedEmailProvider
+func (c *Client) SendTrackedEmail(ctx context.Context, to, subject, text, html string) (string, error) {
+	res, err := c.SendMessage(ctx, email.Message{
+		To:      []string{to},
+		Subject: subject,
+		Text:    text,
+		HTML:    html,
+	})
+	if err != nil {
+		return "", err
+	}
+	return res.ID, nil
+}
+
+// SendMessage sends msg and returns its Message-ID. Tags are sent as X-Tags header,
+// scheduled delivery isn't supported.
+func (c *Client) SendMessage(ctx context.Context, msg email.Message) (*email.SendResponse, error) {
+	if msg.From == "" {
+		msg.From = c.config.FromEmail
+	}
+	if err := msg.Validate(); err != nil {
+		return nil, err
+	}
+	if !msg.DeliveryTime.IsZero() {
+		return nil, fmt.Errorf("%w: scheduled delivery", email.ErrUnsupported)
+	}
+	from, rcpts, err := msg.Envelope()
+	if err != nil {
+		return nil, err
+	}
+
+	cn, err := c.get(ctx)
+	if err != nil {
+		return nil, err
+	}
+	messageID := email.NewMessageID(msg.From)
+	if err := c.send(ctx, cn, msg, messageID, from, rcpts); err != nil {
+		cn.client.Close()
+		log.Printf("smtp: error sending email to=%v err=%v", msg.To, err)
+		return nil, fmt.Errorf("%w: %v", ErrRequestFailed, err)
+	}
+	c.put(cn)
+	return &email.SendResponse{ID: messageID, Message: "Queued"}, nil
+}
+
+func (c *Client) send(ctx context.Context, cn *conn, msg email.Message, messageID, from string, rcpts []string) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(c.config.timeout())
+	}
+	if err := cn.netConn.SetDeadline(deadline); err != nil {
+		return err
+	}
+
+	if err := cn.client.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range rcpts {
+		if err := cn.client.Rcpt(rcpt); err != nil {
+			return err
+		}
+	}
+	w, err := cn.client.Data()
+	if err != nil {
+		return err
+	}
+	if err := msg.WriteMIME(w, messageID); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+// Close closes idle connections, in-flight sends close their connection once done
+func (c *Client) Close() error {
+	c.once.Do(func() { close(c.closed) })
+	for {
+		select {
+		case cn := <-c.idle:
+			cn.client.Quit()
+		default:
+			return nil
+		}
+	}
+}
+
+// get returns an idle connection that is still alive or dials a new one
+func (c *Client) get(ctx context.Context) (*conn, error) {
+	for {
+		select {
+		case cn := <-c.idle:
+			if time.Since(cn.lastUsed) > c.config.idleTimeout() {
+				cn.client.Close()
+				continue
+			}
+			cn.netConn.SetDeadline(time.Now().Add(c.config.timeout()))
+			if err := cn.client.Reset(); err != nil {
+				cn.client.Close()
+				continue
+			}
+			return cn, nil
+		default:
+			return c.dial(ctx)
+		}
+	}
+}
+
+func (c *Client) put(cn *conn) {
+	cn.lastUsed = time.Now()
+	select {
+	case <-c.closed:
+		cn.client.Quit()
+	case c.idle <- cn:
+	default:
+		cn.client.Quit()
+	}
+}
+
+func (c *Client) dial(ctx context.Context) (*conn, error) {
+	tlsConfig := &tls.Config{
+		ServerName:         c.config.Host,
+		InsecureSkipVerify: c.config.InsecureSkipVerify,
+	}
+	dialer := &net.Dialer{Timeout: c.config.timeout()}
+
+	var (
+		netConn net.Conn
+		err     error
+	)
+	if c.config.TLSMode == TLSModeImplicit {
+		netConn, err = (&tls.Dialer{NetDialer: dialer, Config: tlsConfig}).DialContext(ctx, "tcp", c.config.address())
+	} else {
+		netConn, err = dialer.DialContext(ctx, "tcp", c.config.address())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: dial %s: %v", ErrRequestFailed, c.config.address(), err)
+	}
+	netConn.SetDeadline(time.Now().Add(c.config.timeout()))
+
+	client, err := smtp.NewClient(netConn, c.config.Host)
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("%w: %v", ErrRequestFailed, err)
+	}
+	if err := c.handshake(client, tlsConfig); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("%w: %v", ErrRequestFailed, err)
+	}
+	return &conn{client: client, netConn: netConn}, nil
+}
+
+func (c *Client) handshake(client *smtp.Client, tlsConfig *tls.Config) error {
+	if err := client.Hello(c.config.LocalName); err != nil {
+		return err
+	}
+	if c.config.TLSMode == TLSModeStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("server does not support STARTTLS")
+		}
+		if err := client.StartTLS(tlsConfig); err != nil {
+			return err
+		}
+	}
+	if c.config.Username != "" {
+		auth := smtp.PlainAuth("", c.config.Username, c.config.Password, c.config.Host)
+		if err := client.Auth(auth); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,219 @@
+package smtp
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/krsoninikhil/go-rest-kit/integrations/email"
+)
+
+type stubMessage struct {
+	from       string
+	recipients []string
+	data       string
+}
+
+// stubServer is a minimal SMTP server that records received messages
+type stubServer struct {
+	listener net.Listener
+	mu       sync.Mutex
+	conns    int
+	messages []stubMessage
+}
+
+func newStubServer(t *testing.T) *stubServer {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	s := &stubServer{listener: l}
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			s.mu.Lock()
+			s.conns++
+			s.mu.Unlock()
+			go s.serve(c)
+		}
+	}()
+	t.Cleanup(func() { l.Close() })
+	return s
+}
+
+func (s *stubServer) serve(c net.Conn) {
+	defer c.Close()
+	r := bufio.NewReader(c)
+	reply := func(line string) { io.WriteString(c, line+"\r\n") }
+	reply("220 stub ESMTP")
+
+	var msg stubMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		cmd := strings.TrimRight(line, "\r\n")
+		switch verb := strings.ToUpper(strings.SplitN(cmd, " ", 2)[0]); verb {
+		case "EHLO", "HELO":
+			reply("250-stub")
+			reply("250 8BITMIME")
+		case "MAIL":
+			msg = stubMessage{from: addressArg(cmd)}
+			reply("250 OK")
+		case "RCPT":
+			msg.recipients = append(msg.recipients, addressArg(cmd))
+			reply("250 OK")
+		case "DATA":
+			reply("354 go ahead")
+			var data strings.Builder
+			for {
+				l, err := r.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if l == ".\r\n" {
+					break
+				}
+				data.WriteString(strings.TrimPrefix(l, "."))
+			}
+			msg.data = data.String()
+			s.mu.Lock()
+			s.messages = append(s.messages, msg)
+			s.mu.Unlock()
+			reply("250 queued")
+		case "RSET", "NOOP":
+			reply("250 OK")
+		case "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 not implemented")
+		}
+	}
+}
+
+func addressArg(cmd string) string {
+	start, end := strings.Index(cmd, "<"), strings.Index(cmd, ">")
+	return cmd[start+1 : end]
+}
+
+func (s *stubServer) config() Config {
+	host, port, _ := net.SplitHostPort(s.listener.Addr().String())
+	p, _ := strconv.Atoi(port)
+	return Config{Host: host, Port: p, FromEmail: "noreply@example.com", TLSMode: TLSModeNone}
+}
+
+func TestClient_SendMessage(t *testing.T) {
+	srv := newStubServer(t)
+	client := NewClient(srv.config())
+	defer client.Close()
+
+	res, err := client.SendMessage(context.Background(), email.Message{
+		To:          []string{"a@example.com"},
+		CC:          []string{"b@example.com"},
+		BCC:         []string{"hidden@example.com"},
+		Subject:     "Weekly report",
+		Text:        "see attached",
+		HTML:        "<p>see attached</p>",
+		Attachments: []email.Attachment{{Filename: "report.csv", ContentType: "text/csv", Content: strings.NewReader("a,b\n1,2")}},
+	})
+	if err != nil {
+		t.Fatalf("send message failed: %v", err)
+	}
+	if err := client.SendEmail("c@example.com", "hello", "plain text"); err != nil {
+		t.Fatalf("send email failed: %v", err)
+	}
+	_, err = client.SendMessage(context.Background(), email.Message{
+		From:    "Brand <brand@example.com>",
+		To:      []string{"D <d@example.com>, e@example.com"},
+		Subject: "hello",
+		Text:    "plain text",
+	})
+	if err != nil {
+		t.Fatalf("send with display names failed: %v", err)
+	}
+
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	if srv.conns != 1 {
+		t.Fatalf("expected connection to be reused, got %d connections", srv.conns)
+	}
+	if len(srv.messages) != 3 {
+		t.Fatalf("expected 3 messages, got %d", len(srv.messages))
+	}
+	if got := srv.messages[2]; got.from != "brand@example.com" || strings.Join(got.recipients, ",") != "d@example.com,e@example.com" {
+		t.Fatalf("expected bare envelope addresses, got from=%s rcpt=%v", got.from, got.recipients)
+	}
+
+	got := srv.messages[0]
+	if got.from != "noreply@example.com" || strings.Join(got.recipients, ",") != "a@example.com,b@example.com,hidden@example.com" {
+		t.Fatalf("unexpected envelope: from=%s rcpt=%v", got.from, got.recipients)
+	}
+	parsed, err := mail.ReadMessage(strings.NewReader(got.data))
+	if err != nil {
+		t.Fatalf("parse message: %v", err)
+	}
+	if parsed.Header.Get("Message-Id") != res.ID {
+		t.Fatalf("expected Message-ID %s, got %s", res.ID, parsed.Header.Get("Message-Id"))
+	}
+	if parsed.Header.Get("Bcc") != "" || strings.Contains(got.data, "hidden@example.com") {
+		t.Fatalf("bcc recipient must not be in message data")
+	}
+
+	mediaType, params, _ := mime.ParseMediaType(parsed.Header.Get("Content-Type"))
+	if mediaType != "multipart/mixed" {
+		t.Fatalf("unexpected content type: %s", mediaType)
+	}
+	mr := multipart.NewReader(parsed.Body, params["boundary"])
+	var partTypes []string
+	for {
+		part, err := mr.NextPart()
+		if err != nil {
+			break
+		}
+		mt, _, _ := mime.ParseMediaType(part.Header.Get("Content-Type"))
+		partTypes = append(partTypes, mt)
+	}
+	if strings.Join(partTypes, ",") != "multipart/alternative,text/csv" {
+		t.Fatalf("unexpected parts: %v", partTypes)
+	}
+}
+
+func TestClient_StartTLSRequired(t *testing.T) {
+	srv := newStubServer(t)
+	conf := srv.config()
+	conf.TLSMode = TLSModeStartTLS
+	client := NewClient(conf)
+
+	err := client.SendEmail("a@example.com", "hello", "plain text")
+	if !errors.Is(err, ErrRequestFailed) || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Fatalf("expected STARTTLS error, got %v", err)
+	}
+}
+
+func TestClient_ScheduledDeliveryUnsupported(t *testing.T) {
+	client := NewClient(Config{Host: "127.0.0.1", FromEmail: "noreply@example.com"})
+	_, err := client.SendMessage(context.Background(), email.Message{
+		To:           []string{"a@example.com"},
+		Subject:      "hello",
+		Text:         "later",
+		DeliveryTime: time.Date(2030, 1, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if !errors.Is(err, email.ErrUnsupported) {
+		t.Fatalf("expected ErrUnsupported, got %v", err)
+	}
+}
@@ -1,17 +1,25 @@
 package twilio
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"strings"
 
 	"github.com/dghubble/sling"
 	"github.com/pkg/errors"
 )
 
+const defaultBaseURL = "https://api.twilio.com"
+
 type Config struct {
 	AccountSID string
 	AuthToken  string `log:"-"`
 	FromNumber string
+	// StatusCallbackURL is the public url of StatusCallbackHandler, when set twilio
+	// posts delivery updates for every message and signatures are verified against it
+	StatusCallbackURL string
+	BaseURL           string // optional; default https://api.twilio.com
 }
 
 type Client struct {
@@ -20,7 +28,11 @@ type Client struct {
 }
 
 func NewClient(config Config) *Client {
-	base := sling.New().Base("https://api.twilio.com/2010-04-01/Accounts/" + config.AccountSID + "/")
+	baseURL := strings.TrimRight(strings.TrimSpace(config.BaseURL), "/")
+	if baseURL == "" {
+		baseURL = defaultBaseURL
+	}
+	base := sling.New().Base(baseURL + "/2010-04-01/Accounts/" + config.AccountSID + "/")
 	slingClient := base.New().
 		Set("Accept", "application/json").
 		Set("Content-Type", "application/x-www-form-urlencoded").
@@ -29,23 +41,45 @@ func NewClient(config Config) *Client {
 }
 
 func (c *Client) SendSMS(toNumber, message string) error {
-	req := sendMessageRequest{
-		To:   toNumber,
-		From: c.config.FromNumber,
-		Body: message,
+	_, err := c.SendMessage(context.Background(), toNumber, message)
+	return err
+}
+
+// SendTrackedSMS sends an sms and returns its message SID, see notifications.TrackedSMSProvider
+func (c *Client) SendTrackedSMS(ctx context.Context, toNumber, message string) (string, error) {
+	res, err := c.SendMessage(ctx, toNumber, message)
+	if err != nil {
+		return "", err
 	}
+	return res.SID, nil
+}
+
+// SendMessage sends an sms and returns the message SID used in status callbacks
+func (c *Client) SendMessage(ctx context.Context, toNumber, message string) (*SendResponse, error) {
+	form := sendMessageRequest{
+		To:             toNumber,
+		From:           c.config.FromNumber,
+		Body:           message,
+		StatusCallback: c.config.StatusCallbackURL,
+	}
+	req, err := c.sling.New().Post("Messages.json").BodyForm(&form).Request()
+	if err != nil {
+		return nil, errors.Wrap(err, "error creating message request")
+	}
+
+	var res SendResponse
 	respError := map[string]any{}
-	resp, err := c.sling.New().Post("Messages.json").BodyForm(&req).Receive(nil, &respError)
+	resp, err := c.sling.Do(req.WithContext(ctx), &res, &respError)
 	if err != nil {
 		log.Printf("error sending message to=%s err=%v", toNumber, err)
-		return errors.Wrap(err, "error sending message")
+		return nil, errors.Wrap(err, "error sending message")
 	}
 
 	if resp.StatusCode == 201 {
-		return nil
+		return &res, nil
 	} else {
 		log.Printf("failed to send SMS, phone=%s twilio status=%d resp=%v",
 			toNumber, resp.StatusCode, respError)
-		return fmt.Errorf("failed to send SMS")
+		return nil, fmt.Errorf("failed to send SMS")
 	}
 }
@@ -2,8 +2,15 @@ package twilio
 
 type (
 	sendMessageRequest struct {
-		To   string `json:"To" url:"To"`
-		From string `json:"From" url:"From"`
-		Body string `json:"Body" url:"Body"`
+		To             string `json:"To" url:"To"`
+		From           string `json:"From" url:"From"`
+		Body           string `json:"Body" url:"Body"`
+		StatusCallback string `json:"StatusCallback,omitempty" url:"StatusCallback,omitempty"`
+	}
+
+	// SendResponse holds the fields of twilio's message resource used for correlation
+	SendResponse struct {
+		SID    string `json:"sid"`
+		Status string `json:"status"`
 	}
 )
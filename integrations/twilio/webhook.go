@@ -0,0 +1,126 @@
+package twilio
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"encoding/base64"
+	"log"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krsoninikhil/go-rest-kit/integrations/delivery"
+)
+
+const providerName = "twilio"
+
+// StatusCallbackHandler receives message status callbacks, verifies X-Twilio-Signature
+// and publishes them to sink as delivery events.
+func (c *Client) StatusCallbackHandler(sink delivery.Sink) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		if err := ctx.Request.ParseForm(); err != nil {
+			ctx.AbortWithStatus(http.StatusBadRequest)
+			return
+		}
+		callbackURL := c.config.StatusCallbackURL
+		if callbackURL == "" {
+			callbackURL = requestURL(ctx.Request)
+		}
+		signature := ctx.GetHeader("X-Twilio-Signature")
+		if !ValidSignature(c.config.AuthToken, callbackURL, ctx.Request.PostForm, signature) {
+			log.Printf("twilio: invalid status callback signature url=%s", callbackURL)
+			ctx.AbortWithStatus(http.StatusForbidden)
+			return
+		}
+
+		event := statusEvent(ctx.Request.PostForm)
+		if err := sink.Publish(ctx, event); err != nil {
+			log.Printf("twilio: error publishing delivery event id=%s err=%v", event.MessageID, err)
+			ctx.AbortWithStatus(http.StatusInternalServerError)
+			return
+		}
+		ctx.Status(http.StatusNoContent)
+	}
+}
+
+// ValidSignature verifies twilio's request signature: base64 HMAC-SHA1 of the url
+// followed by the sorted post params concatenated as key+value. It's false without an
+// authToken, so a client without one rejects every callback.
+func ValidSignature(authToken, callbackURL string, params url.Values, signature string) bool {
+	if authToken == "" {
+		return false
+	}
+	expected, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil || signature == "" {
+		return false
+	}
+	return hmac.Equal(expected, sign(authToken, callbackURL, params))
+}
+
+func sign(authToken, callbackURL string, params url.Values) []byte {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(callbackURL)
+	for _, key := range keys {
+		for _, value := range params[key] {
+			b.WriteString(key)
+			b.WriteString(value)
+		}
+	}
+	mac := hmac.New(sha1.New, []byte(authToken))
+	mac.Write([]byte(b.String()))
+	return mac.Sum(nil)
+}
+
+func requestURL(r *http.Request) string {
+	scheme := "https"
+	if r.TLS == nil {
+		scheme = "http"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + r.Host + r.URL.RequestURI()
+}
+
+func statusEvent(form url.Values) delivery.Event {
+	reason := form.Get("ErrorCode")
+	if msg := form.Get("ErrorMessage"); msg != "" {
+		reason = strings.TrimSpace(reason + " " + msg)
+	}
+	return delivery.Event{
+		Provider:  providerName,
+		Channel:   delivery.ChannelSMS,
+		MessageID: form.Get("MessageSid"),
+		Recipient: form.Get("To"),
+		Status:    messageStatus(form.Get("MessageStatus")),
+		Reason:    reason,
+		// callbacks carry no event time, sinks order them by status, see delivery.CacheSink
+		Timestamp: time.Now(),
+	}
+}
+
+func messageStatus(status string) delivery.Status {
+	switch status {
+	case "accepted", "scheduled", "queued":
+		return delivery.StatusQueued
+	case "sending", "sent":
+		return delivery.StatusSent
+	case "delivered":
+		return delivery.StatusDelivered
+	case "read":
+		return delivery.StatusOpened
+	case "undelivered", "failed", "canceled":
+		return delivery.StatusFailed
+	default:
+		return delivery.StatusUnknown
+	}
+}
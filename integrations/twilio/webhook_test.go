@@ -0,0 +1,100 @@
+package twilio
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krsoninikhil/go-rest-kit/cache"
+	"github.com/krsoninikhil/go-rest-kit/integrations/delivery"
+)
+
+func TestClient_StatusCallbackHandler(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	const callbackURL = "https://api.example.com/webhooks/twilio"
+	client := NewClient(Config{AccountSID: "AC1", AuthToken: "token", StatusCallbackURL: callbackURL})
+
+	var got []delivery.Event
+	router := gin.New()
+	router.POST("/webhooks/twilio", client.StatusCallbackHandler(delivery.SinkFunc(func(_ context.Context, e delivery.Event) error {
+		got = append(got, e)
+		return nil
+	})))
+
+	form := url.Values{
+		"MessageSid":    {"SM123"},
+		"MessageStatus": {"undelivered"},
+		"To":            {"+15550001111"},
+		"ErrorCode":     {"30003"},
+	}
+	post := func(signature string) int {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodPost, "/webhooks/twilio", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("X-Twilio-Signature", signature)
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	if code := post("bad"); code != http.StatusForbidden {
+		t.Fatalf("invalid signature: expected 403, got %d", code)
+	}
+	signature := base64.StdEncoding.EncodeToString(sign("token", callbackURL, form))
+	if code := post(signature); code != http.StatusNoContent {
+		t.Fatalf("valid signature: expected 204, got %d", code)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected 1 event, got %d", len(got))
+	}
+	if e := got[0]; e.MessageID != "SM123" || e.Status != delivery.StatusFailed || e.Reason != "30003" {
+		t.Errorf("unexpected event %+v", e)
+	}
+}
+
+func TestStatusEvent_Ordering(t *testing.T) {
+	if ValidSignature("", "https://api.example.com", url.Values{}, base64.StdEncoding.EncodeToString(sign("", "https://api.example.com", url.Values{}))) {
+		t.Error("expected signatures to be invalid without an auth token")
+	}
+
+	sink := delivery.NewCacheSink(cache.NewInMemory(), time.Hour)
+	for _, status := range []string{"delivered", "sent"} {
+		event := statusEvent(url.Values{"MessageSid": {"SM123"}, "MessageStatus": {status}})
+		if err := sink.Publish(context.Background(), event); err != nil {
+			t.Fatalf("publish %s: %v", status, err)
+		}
+	}
+	if e, err := sink.Latest("SM123"); err != nil || e.Status != delivery.StatusDelivered {
+		t.Errorf("expected late sent callback to be ignored, got %+v, %v", e, err)
+	}
+}
+
+func TestClient_SendMessage(t *testing.T) {
+	var got url.Values
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		got = r.PostForm
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{"sid":"SM123","status":"queued"}`))
+	}))
+	defer srv.Close()
+
+	client := NewClient(Config{AccountSID: "AC1", AuthToken: "token", FromNumber: "+15550000000",
+		StatusCallbackURL: "https://api.example.com/webhooks/twilio", BaseURL: srv.URL})
+	res, err := client.SendMessage(context.Background(), "+15550001111", "hi")
+	if err != nil {
+		t.Fatalf("send message: %v", err)
+	}
+	if res.SID != "SM123" {
+		t.Errorf("expected sid SM123, got %s", res.SID)
+	}
+	if got.Get("StatusCallback") != "https://api.example.com/webhooks/twilio" || got.Get("To") != "+15550001111" {
+		t.Errorf("unexpected form %v", got)
+	}
+}
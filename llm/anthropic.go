@@ -0,0 +1,177 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"strings"
+
+	"github.com/krsoninikhil/go-rest-kit/integrations/anthropic"
+)
+
+const ProviderAnthropic = "anthropic"
+
+type anthropicClient struct {
+	client *anthropic.Client
+}
+
+// NewAnthropic adapts the anthropic client to Client
+func NewAnthropic(client *anthropic.Client) Client {
+	return &anthropicClient{client: client}
+}
+
+func (a *anthropicClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	res, err := a.client.Messages(ctx, toAnthropicRequest(req))
+	if err != nil {
+		return nil, fromAnthropicError(err)
+	}
+	return fromAnthropicResponse(res, req.Schema), nil
+}
+
+func (a *anthropicClient) Stream(ctx context.Context, req Request) (Stream, error) {
+	stream, err := a.client.Stream(ctx, toAnthropicRequest(req))
+	if err != nil {
+		return nil, fromAnthropicError(err)
+	}
+	return &anthropicStream{stream: stream, schema: req.Schema}, nil
+}
+
+type anthropicStream struct {
+	stream *anthropic.Stream
+	schema *Schema
+	text   string
+}
+
+func (s *anthropicStream) Next() bool {
+	for s.stream.Next() {
+		event := s.stream.Event()
+		if event.Type != anthropic.EventContentBlockDelta || event.Delta == nil {
+			continue
+		}
+		switch {
+		case event.Delta.Type == anthropic.DeltaText && s.schema == nil:
+			s.text = event.Delta.Text
+			return true
+		case event.Delta.Type == anthropic.DeltaInputJSON && s.schema != nil:
+			s.text = event.Delta.PartialJSON
+			return true
+		}
+	}
+	s.text = ""
+	return false
+}
+
+func (s *anthropicStream) Text() string { return s.text }
+
+func (s *anthropicStream) Err() error {
+	if err := s.stream.Err(); err != nil {
+		return fromAnthropicError(err)
+	}
+	return nil
+}
+
+func (s *anthropicStream) Response() *Response {
+	return fromAnthropicResponse(s.stream.Message(), s.schema)
+}
+
+func (s *anthropicStream) Close() error { return s.stream.Close() }
+
+func toAnthropicRequest(req Request) anthropic.MessagesRequest {
+	system := []string{}
+	if req.System != "" {
+		system = append(system, req.System)
+	}
+	messages := make([]anthropic.Message, 0, len(req.Messages))
+	for _, m := range req.Messages {
+		if m.Role == RoleSystem {
+			system = append(system, m.Content)
+			continue
+		}
+		messages = append(messages, anthropic.Message{
+			Role:    m.Role,
+			Content: []anthropic.ContentBlock{anthropic.TextBlock(m.Content)},
+		})
+	}
+	out := anthropic.MessagesRequest{
+		Model:         req.Model,
+		MaxTokens:     req.MaxTokens,
+		System:        strings.Join(system, "\n\n"),
+		Messages:      messages,
+		Temperature:   req.Temperature,
+		StopSequences: req.StopSequences,
+	}
+	if req.Schema != nil {
+		// anthropic has no json mode, forcing a tool call returns input matching the schema
+		out.Tools = []anthropic.Tool{{
+			Name:        req.Schema.Name,
+			Description: schemaDescription(req.Schema),
+			InputSchema: req.Schema.Schema,
+		}}
+		out.ToolChoice = &anthropic.ToolChoice{Type: "tool", Name: req.Schema.Name}
+	}
+	return out
+}
+
+func fromAnthropicResponse(res *anthropic.MessagesResponse, schema *Schema) *Response {
+	out := &Response{
+		Provider:     ProviderAnthropic,
+		Model:        res.Model,
+		Content:      res.Text(),
+		FinishReason: anthropicFinishReason(res.StopReason),
+	}
+	if schema != nil {
+		for _, use := range res.ToolUses() {
+			if use.Name == schema.Name {
+				out.Content = string(use.Input)
+				// forced tool call is the answer, not a request to run a tool
+				out.FinishReason = FinishStop
+			}
+		}
+		if res.StopReason == anthropic.StopMaxTokens {
+			out.FinishReason = FinishLength
+		}
+	}
+	if res.Usage != nil {
+		out.Usage = Usage{InputTokens: res.Usage.InputTokens, OutputTokens: res.Usage.OutputTokens}
+	}
+	return out
+}
+
+func anthropicFinishReason(reason string) FinishReason {
+	switch reason {
+	case anthropic.StopEndTurn, anthropic.StopSequence:
+		return FinishStop
+	case anthropic.StopMaxTokens:
+		return FinishLength
+	case anthropic.StopToolUse:
+		return FinishToolUse
+	case anthropic.StopRefusal:
+		return FinishContentFilter
+	default:
+		return FinishUnknown
+	}
+}
+
+func fromAnthropicError(err error) error {
+	var apiErr *anthropic.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	statusCode := apiErr.StatusCode
+	if statusCode == 0 && apiErr.Temporary() {
+		statusCode = 529 // overloaded error event in a stream
+	}
+	return &Error{
+		Provider:   ProviderAnthropic,
+		StatusCode: statusCode,
+		Message:    apiErr.Message,
+		RetryAfter: apiErr.RetryAfter,
+		Err:        err,
+	}
+}
+
+func schemaDescription(schema *Schema) string {
+	if schema.Description != "" {
+		return schema.Description
+	}
+	return "Respond with the result matching this schema."
+}
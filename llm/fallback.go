@@ -0,0 +1,50 @@
+package llm
+
+import (
+	"context"
+	"errors"
+)
+
+type fallbackClient struct {
+	clients []Client
+}
+
+// Fallback tries clients in order until one succeeds, e.g. anthropic then openrouter.
+// Streams fall back only until a stream is established. The returned error joins
+// the errors of all clients.
+func Fallback(clients ...Client) Client {
+	return &fallbackClient{clients: clients}
+}
+
+func (f *fallbackClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	return fallback(ctx, f.clients, func(client Client) (*Response, error) {
+		return client.Complete(ctx, req)
+	})
+}
+
+func (f *fallbackClient) Stream(ctx context.Context, req Request) (Stream, error) {
+	return fallback(ctx, f.clients, func(client Client) (Stream, error) {
+		return client.Stream(ctx, req)
+	})
+}
+
+func fallback[T any](ctx context.Context, clients []Client, call func(Client) (T, error)) (T, error) {
+	var (
+		zero T
+		errs []error
+	)
+	for _, client := range clients {
+		res, err := call(client)
+		if err == nil {
+			return res, nil
+		}
+		errs = append(errs, err)
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	if len(errs) == 0 {
+		return zero, errors.New("llm: no clients configured")
+	}
+	return zero, errors.Join(errs...)
+}
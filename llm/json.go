@@ -0,0 +1,143 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+const defaultMaxRepairs = 2
+
+// SchemaError is returned by ChatJSON when the model's output didn't match the schema
+// after all repair attempts. Any other error is a provider failure.
+type SchemaError struct {
+	Violations []string
+	Raw        string // last output of the model
+	Attempts   int
+}
+
+func (e *SchemaError) Error() string {
+	return fmt.Sprintf("llm: response doesn't match schema after %d attempts: %s",
+		e.Attempts, strings.Join(e.Violations, "; "))
+}
+
+type jsonOptions struct {
+	maxRepairs int
+	request    Request
+}
+
+type JSONOption func(*jsonOptions)
+
+// MaxRepairs sets how many times the model is re-prompted with the validation errors, default 2
+func MaxRepairs(n int) JSONOption {
+	return func(o *jsonOptions) { o.maxRepairs = n }
+}
+
+// WithRequest sets the base request, e.g. model, max tokens or previous messages.
+// System and user passed to ChatJSON are added to it.
+func WithRequest(req Request) JSONOption {
+	return func(o *jsonOptions) { o.request = req }
+}
+
+// ChatJSON asks the model to respond with JSON matching the schema derived from T,
+// see SchemaFor. The response is validated and unmarshalled into T, invalid responses
+// are sent back to the model with the violations up to MaxRepairs times.
+func ChatJSON[T any](ctx context.Context, client Client, system, user string, opts ...JSONOption) (T, error) {
+	var out T
+	options := jsonOptions{maxRepairs: defaultMaxRepairs}
+	for _, opt := range opts {
+		opt(&options)
+	}
+
+	schema, err := SchemaFor[T]()
+	if err != nil {
+		return out, err
+	}
+	if schema["type"] != "object" {
+		return out, fmt.Errorf("llm: ChatJSON requires a struct or map type, got %T", out)
+	}
+	req := options.request
+	req.Messages = append(append([]Message(nil), req.Messages...), UserMessage(user))
+	if system != "" {
+		req.System = strings.TrimSpace(req.System + "\n\n" + system)
+	}
+	req.Schema = &Schema{Name: schemaName(reflect.TypeOf((*T)(nil)).Elem()), Schema: schema}
+
+	schemaErr := &SchemaError{}
+	for attempt := 0; attempt <= options.maxRepairs; attempt++ {
+		res, err := client.Complete(ctx, req)
+		if err != nil {
+			return out, err
+		}
+
+		schemaErr.Attempts++
+		schemaErr.Raw = res.Content
+		schemaErr.Violations = decodeJSON(res, schema, &out)
+		if len(schemaErr.Violations) == 0 {
+			return out, nil
+		}
+		req.Messages = append(req.Messages,
+			AssistantMessage(res.Content),
+			UserMessage(repairPrompt(schemaErr.Violations)),
+		)
+	}
+	return out, schemaErr
+}
+
+func decodeJSON(res *Response, schema map[string]any, out any) []string {
+	if res.FinishReason == FinishLength {
+		return []string{"response was truncated, max tokens reached"}
+	}
+	raw := extractJSON(res.Content)
+	var value any
+	if err := json.Unmarshal([]byte(raw), &value); err != nil {
+		return []string{"invalid JSON: " + err.Error()}
+	}
+	if violations := validateSchema(value, schema, ""); len(violations) > 0 {
+		return violations
+	}
+	if err := json.Unmarshal([]byte(raw), out); err != nil {
+		return []string{err.Error()}
+	}
+	return nil
+}
+
+// extractJSON strips markdown code fences some models wrap JSON in
+func extractJSON(content string) string {
+	content = strings.TrimSpace(content)
+	if !strings.HasPrefix(content, "```") {
+		return content
+	}
+	content = strings.TrimPrefix(content, "```")
+	if i := strings.IndexByte(content, '\n'); i >= 0 {
+		content = content[i+1:]
+	}
+	return strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(content), "```"))
+}
+
+func repairPrompt(violations []string) string {
+	var b bytes.Buffer
+	b.WriteString("Your response did not match the required JSON schema:\n")
+	for _, v := range violations {
+		b.WriteString("- " + v + "\n")
+	}
+	b.WriteString("Respond again with only the corrected JSON.")
+	return b.String()
+}
+
+// schemaName returns the type name usable as a tool name, i.e. [a-zA-Z0-9_-]
+func schemaName(t reflect.Type) string {
+	name := strings.Map(func(r rune) rune {
+		if r == '_' || r == '-' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (r >= '0' && r <= '9') {
+			return r
+		}
+		return '_'
+	}, t.Name())
+	if name == "" {
+		return "response"
+	}
+	return strings.ToLower(name[:1]) + name[1:]
+}
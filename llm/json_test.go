@@ -0,0 +1,128 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/krsoninikhil/go-rest-kit/integrations/anthropic"
+)
+
+type invoice struct {
+	Number   string        `json:"number" description:"invoice number"`
+	Currency string        `json:"currency" enum:"INR,USD"`
+	Total    float64       `json:"total"`
+	Items    []invoiceItem `json:"items"`
+	Note     *string       `json:"note"`
+	PONumber string        `json:"po_number,omitempty"`
+}
+
+type invoiceItem struct {
+	Name     string `json:"name"`
+	Quantity int    `json:"quantity"`
+}
+
+func TestSchemaFor(t *testing.T) {
+	schema, err := SchemaFor[invoice]()
+	if err != nil {
+		t.Fatalf("schema: %v", err)
+	}
+	if got, want := schema["required"], []string{"currency", "items", "number", "total"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("expected required %v, got %v", want, got)
+	}
+	props := schema["properties"].(map[string]any)
+	if note := props["note"].(map[string]any); !reflect.DeepEqual(note["type"], []any{"string", "null"}) {
+		t.Errorf("expected nullable note, got %v", note)
+	}
+	items := props["items"].(map[string]any)["items"].(map[string]any)
+	if items["type"] != "object" || items["additionalProperties"] != false {
+		t.Errorf("unexpected items schema %v", items)
+	}
+
+	if _, err := SchemaFor[chan int](); err == nil {
+		t.Errorf("expected error for unsupported type")
+	}
+}
+
+type scriptedClient struct {
+	responses []string
+	requests  []Request
+}
+
+func (s *scriptedClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	s.requests = append(s.requests, req)
+	content := s.responses[0]
+	s.responses = s.responses[1:]
+	return &Response{Content: content, FinishReason: FinishStop}, nil
+}
+
+func (s *scriptedClient) Stream(ctx context.Context, req Request) (Stream, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestChatJSON_Repair(t *testing.T) {
+	client := &scriptedClient{responses: []string{
+		`{"number": "A-1", "currency": "EUR", "total": "10", "items": []}`,
+		"```json\n" + `{"number": "A-1", "currency": "INR", "total": 10, "items": [{"name": "pen", "quantity": 2}], "note": null}` + "\n```",
+	}}
+	got, err := ChatJSON[invoice](context.Background(), client, "extract the invoice", "invoice A-1 ...")
+	if err != nil {
+		t.Fatalf("chat json: %v", err)
+	}
+	if got.Number != "A-1" || got.Total != 10 || len(got.Items) != 1 || got.Items[0].Quantity != 2 {
+		t.Errorf("unexpected result %+v", got)
+	}
+	if len(client.requests) != 2 || client.requests[0].Schema == nil || client.requests[0].Schema.Name != "invoice" {
+		t.Fatalf("unexpected requests %+v", client.requests)
+	}
+	repair := client.requests[1].Messages[2].Content
+	for _, want := range []string{"currency: must be one of", "total: expected number"} {
+		if !strings.Contains(repair, want) {
+			t.Errorf("expected %q in repair prompt: %s", want, repair)
+		}
+	}
+	if strings.Contains(repair, "note") {
+		t.Errorf("note is nullable, repair prompt: %s", repair)
+	}
+}
+
+func TestChatJSON_SchemaError(t *testing.T) {
+	client := &scriptedClient{responses: []string{"not json", `{"number": 1}`}}
+	_, err := ChatJSON[invoice](context.Background(), client, "", "...", MaxRepairs(1))
+	var schemaErr *SchemaError
+	if !errors.As(err, &schemaErr) || schemaErr.Attempts != 2 || schemaErr.Raw != `{"number": 1}` {
+		t.Fatalf("expected schema error after 2 attempts, got %v", err)
+	}
+
+	var llmErr *Error
+	if errors.As(err, &llmErr) {
+		t.Errorf("schema error must not be a provider error")
+	}
+}
+
+func TestChatJSON_AnthropicForcedTool(t *testing.T) {
+	var got anthropic.MessagesRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&got)
+		w.Write([]byte(`{"stop_reason": "tool_use", "content": [{"type": "tool_use", "id": "tu_1", "name": "invoiceItem",
+			"input": {"name": "pen", "quantity": 3}}]}`))
+	}))
+	defer srv.Close()
+
+	client := NewAnthropic(anthropic.NewClient(anthropic.Config{APIKey: "key", Model: "m", BaseURL: srv.URL}))
+	item, err := ChatJSON[invoiceItem](context.Background(), client, "", "3 pens")
+	if err != nil {
+		t.Fatalf("chat json: %v", err)
+	}
+	if item.Name != "pen" || item.Quantity != 3 {
+		t.Errorf("unexpected item %+v", item)
+	}
+	if got.ToolChoice == nil || got.ToolChoice.Name != "invoiceItem" || len(got.Tools) != 1 {
+		t.Errorf("expected forced tool, got %+v", got)
+	}
+}
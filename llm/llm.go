@@ -0,0 +1,104 @@
+// Package llm provides a provider agnostic interface over the anthropic and openrouter
+// clients with retries, timeouts and fallback.
+package llm
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	RoleSystem    = "system"
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+type FinishReason string
+
+const (
+	FinishStop          FinishReason = "stop"
+	FinishLength        FinishReason = "length"
+	FinishToolUse       FinishReason = "tool_use"
+	FinishContentFilter FinishReason = "content_filter"
+	FinishUnknown       FinishReason = "unknown"
+)
+
+type (
+	// Client is implemented by the provider adapters and the wrappers in this package
+	Client interface {
+		Complete(ctx context.Context, req Request) (*Response, error)
+		Stream(ctx context.Context, req Request) (Stream, error)
+	}
+
+	// Stream iterates over the text deltas of a streaming response, Response is
+	// complete once Next returns false. Caller must Close the stream.
+	Stream interface {
+		Next() bool
+		Text() string
+		Err() error
+		Response() *Response
+		Close() error
+	}
+
+	Message struct {
+		Role    string
+		Content string
+	}
+
+	// Request is a provider agnostic completion request, zero values use the provider's defaults
+	Request struct {
+		Model         string
+		System        string
+		Messages      []Message
+		MaxTokens     int
+		Temperature   *float64
+		StopSequences []string
+		// Schema requests JSON output matching it, see ChatJSON
+		Schema *Schema
+	}
+
+	Response struct {
+		Provider     string
+		Model        string
+		Content      string
+		FinishReason FinishReason
+		Usage        Usage
+	}
+
+	Usage struct {
+		InputTokens  int
+		OutputTokens int
+	}
+)
+
+// Error is a normalized provider error, use errors.As to get it from any client error.
+type Error struct {
+	Provider   string
+	StatusCode int
+	Message    string
+	RetryAfter time.Duration
+	Err        error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("llm: %s: HTTP %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+func (e *Error) Unwrap() error { return e.Err }
+
+// Retryable returns true for rate limits and provider side errors
+func (e *Error) Retryable() bool {
+	return e.StatusCode == http.StatusTooManyRequests || e.StatusCode >= http.StatusInternalServerError
+}
+
+// UserMessage is a shorthand for a user Message
+func UserMessage(content string) Message {
+	return Message{Role: RoleUser, Content: content}
+}
+
+// AssistantMessage is a shorthand for an assistant Message
+func AssistantMessage(content string) Message {
+	return Message{Role: RoleAssistant, Content: content}
+}
@@ -0,0 +1,117 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/krsoninikhil/go-rest-kit/integrations/openrouter"
+)
+
+type fakeClient struct {
+	errs  []error
+	calls int
+}
+
+func (f *fakeClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	f.calls++
+	if len(f.errs) > 0 {
+		err := f.errs[0]
+		f.errs = f.errs[1:]
+		return nil, err
+	}
+	return &Response{Provider: "fake", Content: "ok", FinishReason: FinishStop}, nil
+}
+
+func (f *fakeClient) Stream(ctx context.Context, req Request) (Stream, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestWithRetry(t *testing.T) {
+	fake := &fakeClient{errs: []error{
+		&Error{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Millisecond},
+		&Error{StatusCode: http.StatusBadGateway},
+	}}
+	client := WithRetry(fake, RetryConfig{MaxAttempts: 3, BaseDelay: time.Millisecond})
+	res, err := client.Complete(context.Background(), Request{})
+	if err != nil || res.Content != "ok" || fake.calls != 3 {
+		t.Fatalf("expected success on 3rd attempt, got res=%v err=%v calls=%d", res, err, fake.calls)
+	}
+
+	fake = &fakeClient{errs: []error{&Error{StatusCode: http.StatusBadRequest}}}
+	if _, err := WithRetry(fake, RetryConfig{}).Complete(context.Background(), Request{}); err == nil || fake.calls != 1 {
+		t.Errorf("expected no retry on 400, got err=%v calls=%d", err, fake.calls)
+	}
+
+	fake = &fakeClient{errs: []error{&Error{StatusCode: http.StatusTooManyRequests, RetryAfter: time.Hour}}}
+	if _, err := WithRetry(fake, RetryConfig{}).Complete(context.Background(), Request{}); err == nil || fake.calls != 1 {
+		t.Errorf("expected no retry when Retry-After exceeds max delay, got err=%v calls=%d", err, fake.calls)
+	}
+}
+
+func TestFallback(t *testing.T) {
+	primary := &fakeClient{errs: []error{&Error{Provider: "primary", StatusCode: http.StatusServiceUnavailable}}}
+	secondary := &fakeClient{}
+	res, err := Fallback(primary, secondary).Complete(context.Background(), Request{})
+	if err != nil || res.Provider != "fake" || primary.calls != 1 || secondary.calls != 1 {
+		t.Fatalf("expected fallback to secondary, got res=%v err=%v", res, err)
+	}
+
+	primary = &fakeClient{errs: []error{errors.New("primary down")}}
+	secondary = &fakeClient{errs: []error{&Error{StatusCode: http.StatusInternalServerError}}}
+	_, err = Fallback(primary, secondary).Complete(context.Background(), Request{})
+	var llmErr *Error
+	if err == nil || !errors.As(err, &llmErr) {
+		t.Errorf("expected joined errors, got %v", err)
+	}
+}
+
+func TestOpenRouter_Stream(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Write([]byte(": OPENROUTER PROCESSING\n\n" +
+			`data: {"id":"gen-1","model":"m","choices":[{"delta":{"role":"assistant","content":"Hel"}}]}` + "\n\n" +
+			`data: {"id":"gen-1","model":"m","choices":[{"delta":{"content":"lo"},"finish_reason":"stop"}]}` + "\n\n" +
+			`data: {"id":"gen-1","model":"m","choices":[],"usage":{"prompt_tokens":3,"completion_tokens":2}}` + "\n\n" +
+			"data: [DONE]\n\n"))
+	}))
+	defer srv.Close()
+
+	client := NewOpenRouter(openrouter.NewClient(openrouter.Config{APIKey: "key", Model: "m", BaseURL: srv.URL}))
+	stream, err := WithTimeout(client, time.Second).Stream(context.Background(), Request{Messages: []Message{UserMessage("hi")}})
+	if err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	defer stream.Close()
+
+	var text string
+	for stream.Next() {
+		text += stream.Text()
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("stream err: %v", err)
+	}
+	res := stream.Response()
+	if text != "Hello" || res.Content != "Hello" || res.FinishReason != FinishStop || res.Usage.OutputTokens != 2 {
+		t.Errorf("unexpected stream result text=%q res=%+v", text, res)
+	}
+}
+
+func TestOpenRouter_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Retry-After", "2")
+		w.WriteHeader(http.StatusTooManyRequests)
+		w.Write([]byte(`{"error":{"code":429,"message":"rate limited"}}`))
+	}))
+	defer srv.Close()
+
+	client := NewOpenRouter(openrouter.NewClient(openrouter.Config{APIKey: "key", Model: "m", BaseURL: srv.URL}))
+	_, err := client.Complete(context.Background(), Request{Messages: []Message{UserMessage("hi")}})
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || !llmErr.Retryable() || llmErr.RetryAfter != 2*time.Second || llmErr.Provider != ProviderOpenRouter {
+		t.Errorf("unexpected error %v", err)
+	}
+}
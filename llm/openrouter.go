@@ -0,0 +1,132 @@
+package llm
+
+import (
+	"context"
+	"errors"
+
+	"github.com/krsoninikhil/go-rest-kit/integrations/openrouter"
+)
+
+const ProviderOpenRouter = "openrouter"
+
+type openRouterClient struct {
+	client *openrouter.Client
+}
+
+// NewOpenRouter adapts the openrouter client to Client
+func NewOpenRouter(client *openrouter.Client) Client {
+	return &openRouterClient{client: client}
+}
+
+func (o *openRouterClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	res, err := o.client.Complete(ctx, toOpenRouterRequest(req))
+	if err != nil {
+		return nil, fromOpenRouterError(err)
+	}
+	return fromOpenRouterResponse(res), nil
+}
+
+func (o *openRouterClient) Stream(ctx context.Context, req Request) (Stream, error) {
+	stream, err := o.client.Stream(ctx, toOpenRouterRequest(req))
+	if err != nil {
+		return nil, fromOpenRouterError(err)
+	}
+	return &openRouterStream{stream: stream}, nil
+}
+
+type openRouterStream struct {
+	stream *openrouter.Stream
+}
+
+func (s *openRouterStream) Next() bool {
+	for s.stream.Next() {
+		if s.stream.Text() != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *openRouterStream) Text() string { return s.stream.Text() }
+
+func (s *openRouterStream) Err() error {
+	if err := s.stream.Err(); err != nil {
+		return fromOpenRouterError(err)
+	}
+	return nil
+}
+
+func (s *openRouterStream) Response() *Response { return fromOpenRouterResponse(s.stream.Response()) }
+
+func (s *openRouterStream) Close() error { return s.stream.Close() }
+
+func toOpenRouterRequest(req Request) openrouter.ChatRequest {
+	messages := make([]openrouter.Message, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, openrouter.Message{Role: RoleSystem, Content: req.System})
+	}
+	for _, m := range req.Messages {
+		messages = append(messages, openrouter.Message{Role: m.Role, Content: m.Content})
+	}
+	out := openrouter.ChatRequest{
+		Model:       req.Model,
+		Messages:    messages,
+		MaxTokens:   req.MaxTokens,
+		Temperature: req.Temperature,
+		Stop:        req.StopSequences,
+	}
+	if req.Schema != nil {
+		out.ResponseFormat = &openrouter.ResponseFormat{
+			Type: "json_schema",
+			JSONSchema: &openrouter.JSONSchema{
+				Name:        req.Schema.Name,
+				Description: req.Schema.Description,
+				// strict mode requires every field to be required, output is validated by ChatJSON anyway
+				Strict: false,
+				Schema: req.Schema.Schema,
+			},
+		}
+	}
+	return out
+}
+
+func fromOpenRouterResponse(res *openrouter.ChatResponse) *Response {
+	out := &Response{Provider: ProviderOpenRouter, Model: res.Model, FinishReason: FinishUnknown}
+	if len(res.Choices) > 0 {
+		out.Content = res.Choices[0].Message.Content
+		out.FinishReason = openRouterFinishReason(res.Choices[0].FinishReason)
+	}
+	if res.Usage != nil {
+		out.Usage = Usage{InputTokens: res.Usage.PromptTokens, OutputTokens: res.Usage.CompletionTokens}
+	}
+	return out
+}
+
+func openRouterFinishReason(reason string) FinishReason {
+	switch reason {
+	case "stop":
+		return FinishStop
+	case "length":
+		return FinishLength
+	case "tool_calls", "function_call":
+		return FinishToolUse
+	case "content_filter":
+		return FinishContentFilter
+	default:
+		return FinishUnknown
+	}
+}
+
+func fromOpenRouterError(err error) error {
+	var apiErr *openrouter.APIError
+	if !errors.As(err, &apiErr) {
+		return err
+	}
+	return &Error{
+		Provider:   ProviderOpenRouter,
+		StatusCode: apiErr.StatusCode,
+		Message:    apiErr.Message,
+		RetryAfter: apiErr.RetryAfter,
+		Err:        err,
+	}
+}
@@ -0,0 +1,90 @@
+package llm
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultMaxAttempts = 3
+	defaultBaseDelay   = 500 * time.Millisecond
+	defaultMaxDelay    = 30 * time.Second
+)
+
+// RetryConfig configures WithRetry, zero values use defaults of 3 attempts,
+// 500ms base delay and 30s max delay.
+type RetryConfig struct {
+	MaxAttempts int
+	BaseDelay   time.Duration
+	MaxDelay    time.Duration
+}
+
+type retryClient struct {
+	client Client
+	config RetryConfig
+}
+
+// WithRetry retries retryable errors, i.e. 429 and 5xx, with exponential backoff and jitter.
+// A provider's Retry-After is honored, if it's longer than MaxDelay the error is returned
+// instead. Streams are only retried until the stream is established.
+func WithRetry(client Client, config RetryConfig) Client {
+	if config.MaxAttempts == 0 {
+		config.MaxAttempts = defaultMaxAttempts
+	}
+	if config.BaseDelay == 0 {
+		config.BaseDelay = defaultBaseDelay
+	}
+	if config.MaxDelay == 0 {
+		config.MaxDelay = defaultMaxDelay
+	}
+	return &retryClient{client: client, config: config}
+}
+
+func (r *retryClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	return retry(ctx, r.config, func() (*Response, error) {
+		return r.client.Complete(ctx, req)
+	})
+}
+
+func (r *retryClient) Stream(ctx context.Context, req Request) (Stream, error) {
+	return retry(ctx, r.config, func() (Stream, error) {
+		return r.client.Stream(ctx, req)
+	})
+}
+
+func retry[T any](ctx context.Context, config RetryConfig, call func() (T, error)) (T, error) {
+	for attempt := 1; ; attempt++ {
+		res, err := call()
+		if err == nil || attempt >= config.MaxAttempts {
+			return res, err
+		}
+		delay, ok := retryDelay(err, attempt, config)
+		if !ok {
+			return res, err
+		}
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return res, err
+		case <-timer.C:
+		}
+	}
+}
+
+func retryDelay(err error, attempt int, config RetryConfig) (time.Duration, bool) {
+	var llmErr *Error
+	if !errors.As(err, &llmErr) || !llmErr.Retryable() {
+		return 0, false
+	}
+	if llmErr.RetryAfter > config.MaxDelay {
+		return 0, false
+	}
+
+	delay := min(config.BaseDelay<<(attempt-1), config.MaxDelay)
+	delay = delay/2 + rand.N(delay/2+1)
+	return max(delay, llmErr.RetryAfter), true
+}
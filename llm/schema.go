@@ -0,0 +1,270 @@
+package llm
+
+import (
+	"encoding"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Schema is a JSON schema the response must match, see ChatJSON.
+// Name identifies it to the provider, e.g. as the forced tool name.
+type Schema struct {
+	Name        string
+	Description string
+	Schema      map[string]any
+}
+
+var (
+	timeType          = reflect.TypeOf(time.Time{})
+	rawMessageType    = reflect.TypeOf(json.RawMessage{})
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// SchemaFor derives a JSON schema from T using its json tags. Fields without omitempty
+// are required and pointer fields are nullable. Field descriptions and allowed values can
+// be set by `description:"..."` and `enum:"a,b"` tags.
+func SchemaFor[T any]() (map[string]any, error) {
+	return schemaOf(reflect.TypeOf((*T)(nil)).Elem(), map[reflect.Type]bool{})
+}
+
+func schemaOf(t reflect.Type, seen map[reflect.Type]bool) (map[string]any, error) {
+	switch {
+	case t == timeType:
+		return map[string]any{"type": "string", "format": "date-time"}, nil
+	case t == rawMessageType || t.Kind() == reflect.Interface:
+		return map[string]any{}, nil
+	case t.Kind() != reflect.Pointer && t.Kind() != reflect.Struct && t.Implements(textMarshalerType):
+		return map[string]any{"type": "string"}, nil
+	}
+
+	switch t.Kind() {
+	case reflect.Pointer:
+		schema, err := schemaOf(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		if typ, ok := schema["type"].(string); ok {
+			schema["type"] = []any{typ, "null"}
+		}
+		return schema, nil
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return map[string]any{"type": "string", "contentEncoding": "base64"}, nil
+		}
+		items, err := schemaOf(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Map:
+		if t.Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("llm: unsupported map key type %s", t.Key())
+		}
+		values, err := schemaOf(t.Elem(), seen)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "object", "additionalProperties": values}, nil
+	case reflect.Struct:
+		if seen[t] {
+			return nil, fmt.Errorf("llm: recursive type %s is not supported", t)
+		}
+		seen[t] = true
+		defer delete(seen, t)
+
+		properties := map[string]any{}
+		required := []string{}
+		if err := structProperties(t, seen, properties, &required); err != nil {
+			return nil, err
+		}
+		sort.Strings(required)
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}, nil
+	default:
+		return nil, fmt.Errorf("llm: unsupported type %s", t)
+	}
+}
+
+func structProperties(t reflect.Type, seen map[reflect.Type]bool, properties map[string]any, required *[]string) error {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		tag := field.Tag.Get("json")
+		if !field.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := structProperties(embedded, seen, properties, required); err != nil {
+					return err
+				}
+				continue
+			}
+		}
+		if name == "" {
+			name = field.Name
+		}
+
+		schema, err := schemaOf(field.Type, seen)
+		if err != nil {
+			return fmt.Errorf("%s.%s: %w", t.Name(), field.Name, err)
+		}
+		if description := field.Tag.Get("description"); description != "" {
+			schema["description"] = description
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			values := []any{}
+			for _, v := range strings.Split(enum, ",") {
+				values = append(values, strings.TrimSpace(v))
+			}
+			schema["enum"] = values
+		}
+		properties[name] = schema
+		if !strings.Contains(opts, "omitempty") && field.Type.Kind() != reflect.Pointer {
+			*required = append(*required, name)
+		}
+	}
+	return nil
+}
+
+// validateSchema checks value decoded from JSON against schema and returns the violations
+// as "path: problem". Only the keywords generated by SchemaFor are supported.
+func validateSchema(value any, schema map[string]any, path string) []string {
+	if !matchesType(value, schema["type"]) {
+		label := path
+		if label == "" {
+			label = "response"
+		}
+		return []string{fmt.Sprintf("%s: expected %v, got %s", label, schema["type"], jsonType(value))}
+	}
+	var violations []string
+	if enum, ok := schema["enum"].([]any); ok && value != nil {
+		found := false
+		for _, allowed := range enum {
+			if fmt.Sprint(allowed) == fmt.Sprint(value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			violations = append(violations, fmt.Sprintf("%s: must be one of %v", path, enum))
+		}
+	}
+
+	switch v := value.(type) {
+	case []any:
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range v {
+				violations = append(violations, validateSchema(item, items, fmt.Sprintf("%s[%d]", path, i))...)
+			}
+		}
+	case map[string]any:
+		properties, _ := schema["properties"].(map[string]any)
+		for _, name := range requiredFields(schema["required"]) {
+			if _, ok := v[name]; !ok {
+				violations = append(violations, fmt.Sprintf("%s: missing required field", joinPath(path, name)))
+			}
+		}
+		keys := make([]string, 0, len(v))
+		for key := range v {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			if propSchema, ok := properties[key].(map[string]any); ok {
+				violations = append(violations, validateSchema(v[key], propSchema, joinPath(path, key))...)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					violations = append(violations, fmt.Sprintf("%s: unknown field", joinPath(path, key)))
+				}
+			case map[string]any:
+				violations = append(violations, validateSchema(v[key], additional, joinPath(path, key))...)
+			}
+		}
+	}
+	return violations
+}
+
+func matchesType(value any, schemaType any) bool {
+	switch t := schemaType.(type) {
+	case nil:
+		return true
+	case string:
+		actual := jsonType(value)
+		return actual == t || (t == "number" && actual == "integer")
+	case []any:
+		for _, option := range t {
+			if matchesType(value, option) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func jsonType(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case float64:
+		if v == float64(int64(v)) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	default:
+		return fmt.Sprintf("%T", value)
+	}
+}
+
+func requiredFields(required any) []string {
+	switch r := required.(type) {
+	case []string:
+		return r
+	case []any:
+		fields := make([]string, 0, len(r))
+		for _, f := range r {
+			fields = append(fields, fmt.Sprint(f))
+		}
+		return fields
+	}
+	return nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
@@ -0,0 +1,43 @@
+package llm
+
+import (
+	"context"
+	"time"
+)
+
+type timeoutClient struct {
+	client  Client
+	timeout time.Duration
+}
+
+// WithTimeout limits every call to timeout, for streams it includes reading the stream.
+// Wrap it with WithRetry to have the timeout apply per attempt.
+func WithTimeout(client Client, timeout time.Duration) Client {
+	return &timeoutClient{client: client, timeout: timeout}
+}
+
+func (t *timeoutClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	defer cancel()
+	return t.client.Complete(ctx, req)
+}
+
+func (t *timeoutClient) Stream(ctx context.Context, req Request) (Stream, error) {
+	ctx, cancel := context.WithTimeout(ctx, t.timeout)
+	stream, err := t.client.Stream(ctx, req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	return &cancelStream{Stream: stream, cancel: cancel}, nil
+}
+
+type cancelStream struct {
+	Stream
+	cancel context.CancelFunc
+}
+
+func (s *cancelStream) Close() error {
+	defer s.cancel()
+	return s.Stream.Close()
+}
@@ -0,0 +1,161 @@
+// Package usage meters llm calls per user into a ledger and enforces per user budgets.
+package usage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+	"github.com/krsoninikhil/go-rest-kit/llm"
+)
+
+// Config sets per user budgets in USD, zero means no limit. FallbackPrice, if set, is used
+// for models missing from Pricing, otherwise calls to such models fail while budgets are set.
+type Config struct {
+	DailyBudgetUSD   float64
+	MonthlyBudgetUSD float64
+	Pricing          Pricing
+	FallbackPrice    *Price
+}
+
+type meteredClient struct {
+	client llm.Client
+	ledger Ledger
+	config Config
+	now    func() time.Time
+}
+
+// NewClient wraps client to record usage and cost of every call to ledger, attributed to
+// the user from ctx, see UserIDFrom. Calls by a user over the daily budget fail with
+// apperrors.TooManyRequestsError and over the monthly budget with PaymentRequiredError.
+// Budgets are checked before the call, so concurrent calls can overshoot them. Calls for a
+// model without a price fail with apperrors.ServerError instead of bypassing the budgets.
+func NewClient(client llm.Client, ledger Ledger, config Config) llm.Client {
+	return &meteredClient{client: client, ledger: ledger, config: config, now: time.Now}
+}
+
+func (m *meteredClient) Complete(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	userID := UserIDFrom(ctx)
+	if err := m.checkBudget(ctx, userID, req.Model); err != nil {
+		return nil, err
+	}
+	res, err := m.client.Complete(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	m.record(ctx, userID, req, res)
+	return res, nil
+}
+
+func (m *meteredClient) Stream(ctx context.Context, req llm.Request) (llm.Stream, error) {
+	userID := UserIDFrom(ctx)
+	if err := m.checkBudget(ctx, userID, req.Model); err != nil {
+		return nil, err
+	}
+	stream, err := m.client.Stream(ctx, req)
+	if err != nil {
+		return nil, err
+	}
+	return &meteredStream{Stream: stream, record: func() {
+		// ctx may be cancelled by the time the stream is closed
+		m.record(context.WithoutCancel(ctx), userID, req, stream.Response())
+	}}, nil
+}
+
+func (m *meteredClient) checkBudget(ctx context.Context, userID int, model string) error {
+	if userID == 0 || (m.config.DailyBudgetUSD <= 0 && m.config.MonthlyBudgetUSD <= 0) {
+		return nil
+	}
+	// an empty model is the provider's default, it's priced once the response names it
+	if model != "" {
+		if _, err := m.costMicros(model, llm.Usage{}); err != nil {
+			return apperrors.NewServerError(err)
+		}
+	}
+	now := m.now().UTC()
+	if m.config.DailyBudgetUSD > 0 {
+		startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		spend, err := m.ledger.Spend(ctx, userID, startOfDay)
+		if err != nil {
+			return err
+		}
+		if spend >= toMicros(m.config.DailyBudgetUSD) {
+			return apperrors.NewTooManyRequestsError("llm_usage", fmt.Errorf("daily budget exhausted"))
+		}
+	}
+	if m.config.MonthlyBudgetUSD > 0 {
+		startOfMonth := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+		spend, err := m.ledger.Spend(ctx, userID, startOfMonth)
+		if err != nil {
+			return err
+		}
+		if spend >= toMicros(m.config.MonthlyBudgetUSD) {
+			return apperrors.NewPaymentRequiredError("llm_usage", fmt.Errorf("monthly budget exhausted"))
+		}
+	}
+	return nil
+}
+
+// record doesn't fail the call, the response is already paid for
+func (m *meteredClient) record(ctx context.Context, userID int, req llm.Request, res *llm.Response) {
+	model := res.Model
+	if model == "" {
+		model = req.Model
+	}
+	cost, err := m.costMicros(model, res.Usage)
+	if err != nil && model != req.Model && req.Model != "" {
+		// providers may respond with a dated version of the requested model
+		cost, err = m.costMicros(req.Model, res.Usage)
+	}
+	if err != nil {
+		log.Printf("llm usage: error pricing usage user=%d err=%v", userID, err)
+	}
+	r := Record{
+		UserID:       userID,
+		Provider:     res.Provider,
+		Model:        model,
+		InputTokens:  res.Usage.InputTokens,
+		OutputTokens: res.Usage.OutputTokens,
+		CostMicros:   cost,
+	}
+	r.CreatedAt = m.now()
+	if err := m.ledger.Add(ctx, r); err != nil {
+		log.Printf("llm usage: error recording usage user=%d model=%s err=%v", userID, model, err)
+	}
+}
+
+func (m *meteredClient) costMicros(model string, u llm.Usage) (int64, error) {
+	cost, err := m.config.Pricing.CostMicros(model, u)
+	if errors.Is(err, ErrUnknownModel) && m.config.FallbackPrice != nil {
+		return m.config.FallbackPrice.costMicros(u), nil
+	}
+	return cost, err
+}
+
+// meteredStream records usage once the stream is consumed or closed
+type meteredStream struct {
+	llm.Stream
+	record func()
+	once   sync.Once
+}
+
+func (s *meteredStream) Next() bool {
+	if s.Stream.Next() {
+		return true
+	}
+	s.once.Do(s.record)
+	return false
+}
+
+func (s *meteredStream) Close() error {
+	s.once.Do(s.record)
+	return s.Stream.Close()
+}
+
+func toMicros(usd float64) int64 {
+	return int64(usd * 1e6)
+}
@@ -0,0 +1,117 @@
+package usage
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+	"github.com/krsoninikhil/go-rest-kit/auth"
+	"github.com/krsoninikhil/go-rest-kit/llm"
+)
+
+type memLedger struct {
+	records []Record
+}
+
+func (l *memLedger) Add(ctx context.Context, r Record) error {
+	l.records = append(l.records, r)
+	return nil
+}
+
+func (l *memLedger) Spend(ctx context.Context, userID int, since time.Time) (int64, error) {
+	var spend int64
+	for _, r := range l.records {
+		if r.UserID == userID && !r.CreatedAt.Before(since) {
+			spend += r.CostMicros
+		}
+	}
+	return spend, nil
+}
+
+type fakeLLM struct{}
+
+func (fakeLLM) Complete(ctx context.Context, req llm.Request) (*llm.Response, error) {
+	return &llm.Response{Provider: "fake", Model: "m1", Content: "ok",
+		Usage: llm.Usage{InputTokens: 1000, OutputTokens: 500}}, nil
+}
+
+func (fakeLLM) Stream(ctx context.Context, req llm.Request) (llm.Stream, error) {
+	return nil, errors.New("not implemented")
+}
+
+func TestClient_Budgets(t *testing.T) {
+	ledger := &memLedger{}
+	client := NewClient(fakeLLM{}, ledger, Config{
+		DailyBudgetUSD:   0.02,
+		MonthlyBudgetUSD: 0.03,
+		Pricing:          Pricing{"m1": {InputPerMTok: 3, OutputPerMTok: 15}},
+	}).(*meteredClient)
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	client.now = func() time.Time { return now }
+
+	gin.SetMode(gin.TestMode)
+	c, _ := gin.CreateTestContext(nil)
+	c.Set(auth.CtxKeyUserID, 7)
+
+	// each call costs 1000*3 + 500*15 = 10500 micro USD
+	for i := 0; i < 2; i++ {
+		if _, err := client.Complete(c, llm.Request{}); err != nil {
+			t.Fatalf("call %d: %v", i, err)
+		}
+	}
+	if len(ledger.records) != 2 || ledger.records[0].UserID != 7 || ledger.records[0].CostMicros != 10500 {
+		t.Fatalf("unexpected records %+v", ledger.records)
+	}
+
+	_, err := client.Complete(c, llm.Request{})
+	var appErr apperrors.AppError
+	if !errors.As(err, &appErr) || appErr.HTTPCode() != http.StatusTooManyRequests {
+		t.Fatalf("expected daily budget error, got %v", err)
+	}
+
+	now = now.Add(24 * time.Hour)
+	if _, err := client.Complete(c, llm.Request{}); err != nil {
+		t.Fatalf("expected daily budget reset, got %v", err)
+	}
+	now = now.Add(24 * time.Hour)
+	// contexts derived from the gin context are attributed to the user too
+	ctx, cancel := context.WithTimeout(c, time.Minute)
+	defer cancel()
+	_, err = client.Complete(ctx, llm.Request{})
+	if !errors.As(err, &appErr) || appErr.HTTPCode() != http.StatusPaymentRequired {
+		t.Fatalf("expected monthly budget error, got %v", err)
+	}
+
+	// other users and anonymous calls are not affected
+	if _, err := client.Complete(WithUserID(context.Background(), 8), llm.Request{}); err != nil {
+		t.Errorf("user 8: %v", err)
+	}
+	if _, err := client.Complete(context.Background(), llm.Request{}); err != nil {
+		t.Errorf("anonymous: %v", err)
+	}
+}
+
+func TestClient_UnknownModel(t *testing.T) {
+	ledger := &memLedger{}
+	config := Config{DailyBudgetUSD: 1, Pricing: Pricing{"m1": {InputPerMTok: 3, OutputPerMTok: 15}}}
+	ctx := WithUserID(context.Background(), 7)
+
+	_, err := NewClient(fakeLLM{}, ledger, config).Complete(ctx, llm.Request{Model: "m2"})
+	var appErr apperrors.AppError
+	if !errors.As(err, &appErr) || appErr.HTTPCode() != http.StatusInternalServerError {
+		t.Fatalf("expected unpriced model to fail, got %v", err)
+	}
+
+	config.FallbackPrice = &Price{InputPerMTok: 10, OutputPerMTok: 10}
+	config.Pricing = nil
+	if _, err := NewClient(fakeLLM{}, ledger, config).Complete(ctx, llm.Request{Model: "m2"}); err != nil {
+		t.Fatalf("expected fallback price to be used, got %v", err)
+	}
+	if len(ledger.records) != 1 || ledger.records[0].CostMicros != 15000 {
+		t.Fatalf("unexpected records %+v", ledger.records)
+	}
+}
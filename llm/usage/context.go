@@ -0,0 +1,35 @@
+package usage
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/krsoninikhil/go-rest-kit/auth"
+)
+
+type userIDKey struct{}
+
+// WithUserID attributes the llm calls made with ctx to userID
+func WithUserID(ctx context.Context, userID int) context.Context {
+	return context.WithValue(ctx, userIDKey{}, userID)
+}
+
+// UserIDFrom returns the user set by WithUserID or the authenticated user set by auth
+// middleware, which a gin context or any context derived from it resolves. It returns 0 if
+// there is none.
+func UserIDFrom(ctx context.Context) int {
+	if userID, ok := ctx.Value(userIDKey{}).(int); ok {
+		return userID
+	}
+	switch v := ctx.Value(auth.CtxKeyUserID).(type) {
+	case string:
+		id, _ := strconv.Atoi(v)
+		return id
+	case int:
+		return v
+	case int64:
+		return int(v)
+	default:
+		return 0
+	}
+}
@@ -0,0 +1,87 @@
+package usage
+
+import (
+	"context"
+	"time"
+
+	"github.com/krsoninikhil/go-rest-kit/apperrors"
+	"github.com/krsoninikhil/go-rest-kit/sqldb"
+)
+
+// Record is a ledger entry for a single llm call. Cost is in micro USD to avoid
+// rounding errors when summing.
+type Record struct {
+	sqldb.BaseModel
+	UserID       int `gorm:"index"`
+	Provider     string
+	Model        string `gorm:"index"`
+	InputTokens  int
+	OutputTokens int
+	CostMicros   int64
+}
+
+func (Record) TableName() string    { return "llm_usage_records" }
+func (Record) ResourceName() string { return "llm_usage" }
+
+// Cost returns the cost in USD
+func (r Record) Cost() float64 { return float64(r.CostMicros) / 1e6 }
+
+// ModelUsage is the aggregated usage of a model
+type ModelUsage struct {
+	Provider     string
+	Model        string
+	Calls        int
+	InputTokens  int
+	OutputTokens int
+	CostMicros   int64
+}
+
+type Ledger interface {
+	Add(ctx context.Context, r Record) error
+	// Spend returns the total cost in micro USD of the user's calls since the given time
+	Spend(ctx context.Context, userID int, since time.Time) (int64, error)
+}
+
+// Dao is the GORM backed Ledger, migrate Record to create its table
+type Dao struct {
+	*sqldb.PGDB
+}
+
+func NewDao(db *sqldb.PGDB) *Dao {
+	return &Dao{db}
+}
+
+func (d *Dao) Add(ctx context.Context, r Record) error {
+	if err := d.DB(ctx).Create(&r).Error; err != nil {
+		return apperrors.NewServerError(err)
+	}
+	return nil
+}
+
+func (d *Dao) Spend(ctx context.Context, userID int, since time.Time) (int64, error) {
+	var spend int64
+	err := d.DB(ctx).Model(&Record{}).
+		Select("COALESCE(SUM(cost_micros), 0)").
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Scan(&spend).Error
+	if err != nil {
+		return 0, apperrors.NewServerError(err)
+	}
+	return spend, nil
+}
+
+// ByModel returns the user's usage since the given time grouped by model
+func (d *Dao) ByModel(ctx context.Context, userID int, since time.Time) ([]ModelUsage, error) {
+	var res []ModelUsage
+	err := d.DB(ctx).Model(&Record{}).
+		Select("provider, model, COUNT(*) AS calls, SUM(input_tokens) AS input_tokens, "+
+			"SUM(output_tokens) AS output_tokens, SUM(cost_micros) AS cost_micros").
+		Where("user_id = ? AND created_at >= ?", userID, since).
+		Group("provider, model").
+		Order("cost_micros DESC").
+		Scan(&res).Error
+	if err != nil {
+		return nil, apperrors.NewServerError(err)
+	}
+	return res, nil
+}
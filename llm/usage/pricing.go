@@ -0,0 +1,36 @@
+package usage
+
+import (
+	"errors"
+	"fmt"
+	"math"
+
+	"github.com/krsoninikhil/go-rest-kit/llm"
+)
+
+var ErrUnknownModel = errors.New("no price for model")
+
+// Price is the USD cost per million tokens
+type Price struct {
+	InputPerMTok  float64
+	OutputPerMTok float64
+}
+
+// Pricing maps model names, as returned by the provider, to their price
+type Pricing map[string]Price
+
+// CostMicros returns the estimated cost in micro USD, it fails with ErrUnknownModel for
+// models without a price.
+func (p Pricing) CostMicros(model string, u llm.Usage) (int64, error) {
+	price, ok := p[model]
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrUnknownModel, model)
+	}
+	return price.costMicros(u), nil
+}
+
+func (p Price) costMicros(u llm.Usage) int64 {
+	// per million tokens price in USD equals per token price in micro USD
+	cost := float64(u.InputTokens)*p.InputPerMTok + float64(u.OutputTokens)*p.OutputPerMTok
+	return int64(math.Ceil(cost))
+}
@@ -0,0 +1,114 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// providers, satisfied by integrations/twilio, integrations/fast2sms and the email integrations
+type (
+	SMSProvider interface {
+		SendSMS(to, message string) error
+	}
+	EmailProvider interface {
+		SendEmail(to, subject, message string) error
+	}
+	// HTMLEmailProvider is used instead of EmailProvider when the template has an html part
+	HTMLEmailProvider interface {
+		SendHTMLEmail(to, subject, text, html string) error
+	}
+	// TrackedSMSProvider is used instead of SMSProvider when implemented, the returned message
+	// id correlates delivery events, e.g. twilio's message SID
+	TrackedSMSProvider interface {
+		SendTrackedSMS(ctx context.Context, to, message string) (string, error)
+	}
+	// TrackedEmailProvider is used instead of EmailProvider when implemented, html is empty
+	// if the template has no html part
+	TrackedEmailProvider interface {
+		SendTrackedEmail(ctx context.Context, to, subject, text, html string) (string, error)
+	}
+)
+
+var (
+	ErrProviderNotConfigured = errors.New("notifications: provider not configured")
+	ErrEmptyText             = errors.New("notifications: template has no text body")
+)
+
+type ctxKeyLocale struct{}
+
+// WithLocale returns a context carrying the locale to render notifications in
+func WithLocale(ctx context.Context, locale string) context.Context {
+	return context.WithValue(ctx, ctxKeyLocale{}, locale)
+}
+
+// LocaleFrom returns the locale set by WithLocale or empty string
+func LocaleFrom(ctx context.Context) string {
+	locale, _ := ctx.Value(ctxKeyLocale{}).(string)
+	return locale
+}
+
+// Notifier renders templates and delivers them through the configured providers
+type Notifier struct {
+	renderer *Renderer
+	sms      SMSProvider
+	email    EmailProvider
+}
+
+func NewNotifier(renderer *Renderer) *Notifier {
+	if renderer == nil {
+		renderer = DefaultRenderer()
+	}
+	return &Notifier{renderer: renderer}
+}
+
+func (n *Notifier) WithSMSProvider(provider SMSProvider) *Notifier {
+	n.sms = provider
+	return n
+}
+
+func (n *Notifier) WithEmailProvider(provider EmailProvider) *Notifier {
+	n.email = provider
+	return n
+}
+
+// SendSMS renders the text part of template name in the context locale and sends it to phone.
+// It returns the provider's message id, empty if the provider isn't a TrackedSMSProvider, and
+// ErrEmptyText if the rendered text is empty, e.g. for a template with only an html part.
+func (n *Notifier) SendSMS(ctx context.Context, phone, name string, data any) (string, error) {
+	if n.sms == nil {
+		return "", fmt.Errorf("%w: sms", ErrProviderNotConfigured)
+	}
+	msg, err := n.renderer.Render(name, LocaleFrom(ctx), data)
+	if err != nil {
+		return "", err
+	}
+	if strings.TrimSpace(msg.Text) == "" {
+		return "", fmt.Errorf("%w: %s", ErrEmptyText, name)
+	}
+	if tracked, ok := n.sms.(TrackedSMSProvider); ok {
+		return tracked.SendTrackedSMS(ctx, phone, msg.Text)
+	}
+	return "", n.sms.SendSMS(phone, msg.Text)
+}
+
+// SendEmail renders template name in the context locale and emails it to the address.
+// The html part is only sent if the provider supports it. It returns the provider's message
+// id, empty if the provider isn't a TrackedEmailProvider.
+func (n *Notifier) SendEmail(ctx context.Context, to, name string, data any) (string, error) {
+	if n.email == nil {
+		return "", fmt.Errorf("%w: email", ErrProviderNotConfigured)
+	}
+	msg, err := n.renderer.Render(name, LocaleFrom(ctx), data)
+	if err != nil {
+		return "", err
+	}
+	if tracked, ok := n.email.(TrackedEmailProvider); ok {
+		return tracked.SendTrackedEmail(ctx, to, msg.Subject, msg.Text, msg.HTML)
+	}
+	if htmlProvider, ok := n.email.(HTMLEmailProvider); ok && msg.HTML != "" {
+		return "", htmlProvider.SendHTMLEmail(to, msg.Subject, msg.Text, msg.HTML)
+	}
+	return "", n.email.SendEmail(to, msg.Subject, msg.Text)
+}
@@ -0,0 +1,180 @@
+// Package notifications renders named, localized message templates and delivers
+// them through sms and email providers.
+//
+// Templates are looked up in an fs.FS as <locale>/<name>.subject.tmpl,
+// <locale>/<name>.txt.tmpl and <locale>/<name>.html.tmpl. Subject and text use
+// text/template while html uses html/template. At least one of text or html must exist.
+package notifications
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/fs"
+	"strings"
+	"sync"
+	"text/template"
+)
+
+const (
+	TemplateOTP       = "otp"
+	TemplateMagicLink = "magic_link"
+
+	DefaultLocale = "en"
+)
+
+//go:embed templates
+var embedded embed.FS
+
+// Templates holds the default templates shipped with the package
+var Templates, _ = fs.Sub(embedded, "templates")
+
+var ErrTemplateNotFound = errors.New("notifications: template not found")
+
+type (
+	// Message is a rendered template
+	Message struct {
+		Subject string
+		Text    string
+		HTML    string
+	}
+
+	// OTPData is the data for TemplateOTP
+	OTPData struct {
+		Code             string
+		Brand            string
+		ExpiresInMinutes int
+	}
+	// MagicLinkData is the data for TemplateMagicLink
+	MagicLinkData struct {
+		Link             string
+		Brand            string
+		ExpiresInMinutes int
+	}
+)
+
+// Renderer renders templates from one or more file systems, earlier file systems
+// take precedence so apps can override only the templates they want to customize.
+type Renderer struct {
+	defaultLocale string
+	sources       []fs.FS
+	parsed        sync.Map // path -> parsed template
+}
+
+// NewRenderer creates a renderer looking up templates in sources in order.
+func NewRenderer(defaultLocale string, sources ...fs.FS) *Renderer {
+	if defaultLocale == "" {
+		defaultLocale = DefaultLocale
+	}
+	return &Renderer{defaultLocale: normalizeLocale(defaultLocale), sources: sources}
+}
+
+// DefaultRenderer renders the templates shipped with the package
+func DefaultRenderer() *Renderer {
+	return NewRenderer(DefaultLocale, Templates)
+}
+
+// Render renders the template name for locale, falling back to the language
+// (e.g. "en" for "en-IN") and then to the default locale.
+func (r *Renderer) Render(name, locale string, data any) (*Message, error) {
+	for _, l := range r.candidateLocales(locale) {
+		msg, found, err := r.render(l, name, data)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			return msg, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %s", ErrTemplateNotFound, name)
+}
+
+func (r *Renderer) render(locale, name string, data any) (*Message, bool, error) {
+	base := locale + "/" + name
+	var (
+		msg   Message
+		found bool
+	)
+	for _, part := range []struct {
+		ext  string
+		html bool
+		out  *string
+	}{
+		{".subject.tmpl", false, &msg.Subject},
+		{".txt.tmpl", false, &msg.Text},
+		{".html.tmpl", true, &msg.HTML},
+	} {
+		out, ok, err := r.execute(base+part.ext, part.html, data)
+		if err != nil {
+			return nil, false, err
+		}
+		if ok {
+			*part.out = out
+			found = found || part.ext != ".subject.tmpl"
+		}
+	}
+	return &msg, found, nil
+}
+
+// executor is satisfied by both text and html templates
+type executor interface {
+	Execute(w io.Writer, data any) error
+}
+
+func (r *Renderer) execute(path string, html bool, data any) (string, bool, error) {
+	tmpl, ok, err := r.lookup(path, html)
+	if err != nil || !ok {
+		return "", ok, err
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return "", false, fmt.Errorf("notifications: render %s: %w", path, err)
+	}
+	return strings.TrimSpace(buf.String()), true, nil
+}
+
+func (r *Renderer) lookup(path string, html bool) (executor, bool, error) {
+	if tmpl, ok := r.parsed.Load(path); ok {
+		return tmpl.(executor), true, nil
+	}
+	for _, source := range r.sources {
+		content, err := fs.ReadFile(source, path)
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("notifications: read %s: %w", path, err)
+		}
+
+		var tmpl executor
+		if html {
+			tmpl, err = htmltemplate.New(path).Parse(string(content))
+		} else {
+			tmpl, err = template.New(path).Parse(string(content))
+		}
+		if err != nil {
+			return nil, false, fmt.Errorf("notifications: parse %s: %w", path, err)
+		}
+		r.parsed.Store(path, tmpl)
+		return tmpl, true, nil
+	}
+	return nil, false, nil
+}
+
+func (r *Renderer) candidateLocales(locale string) []string {
+	var res []string
+	if locale = normalizeLocale(locale); locale != "" {
+		res = append(res, locale)
+		if lang, _, found := strings.Cut(locale, "-"); found {
+			res = append(res, lang)
+		}
+	}
+	return append(res, r.defaultLocale)
+}
+
+func normalizeLocale(locale string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+}
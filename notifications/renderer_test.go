@@ -0,0 +1,129 @@
+package notifications
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRenderer_DefaultOTPTemplate(t *testing.T) {
+	msg, err := DefaultRenderer().Render(TemplateOTP, "en-IN", OTPData{Code: "123456", Brand: "FaithLabs", ExpiresInMinutes: 10})
+	if err != nil {
+		t.Fatalf("render failed: %v", err)
+	}
+	if msg.Subject != "Your FaithLabs verification code" {
+		t.Fatalf("unexpected subject: %q", msg.Subject)
+	}
+	if msg.Text != "Your FaithLabs OTP code is 123456. It expires shortly." {
+		t.Fatalf("unexpected text: %q", msg.Text)
+	}
+	if msg.HTML == "" {
+		t.Fatalf("expected html part")
+	}
+}
+
+func TestRenderer_LocaleFallbackAndOverride(t *testing.T) {
+	custom := fstest.MapFS{
+		"hi/otp.txt.tmpl":     {Data: []byte("{{.Code}} aapka OTP hai")},
+		"en/otp.subject.tmpl": {Data: []byte("Login code for {{.Brand}}")},
+	}
+	r := NewRenderer("en", custom, Templates)
+	data := OTPData{Code: "123456", Brand: "FaithLabs"}
+
+	tests := []struct {
+		name        string
+		locale      string
+		wantSubject string
+		wantText    string
+	}{
+		{"region falls back to language", "hi_IN", "", "123456 aapka OTP hai"},
+		{"override takes precedence", "en", "Login code for FaithLabs", "Your FaithLabs OTP code is 123456. It expires shortly."},
+		{"unknown locale uses default", "fr", "Login code for FaithLabs", "Your FaithLabs OTP code is 123456. It expires shortly."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg, err := r.Render(TemplateOTP, tt.locale, data)
+			if err != nil {
+				t.Fatalf("render failed: %v", err)
+			}
+			if msg.Subject != tt.wantSubject || msg.Text != tt.wantText {
+				t.Fatalf("unexpected message: %+v", msg)
+			}
+		})
+	}
+}
+
+func TestRenderer_TemplateNotFound(t *testing.T) {
+	_, err := DefaultRenderer().Render("missing", "en", nil)
+	if !errors.Is(err, ErrTemplateNotFound) {
+		t.Fatalf("expected ErrTemplateNotFound, got %v", err)
+	}
+}
+
+type fakeHTMLEmailProvider struct {
+	subject, text, html string
+}
+
+func (f *fakeHTMLEmailProvider) SendEmail(to, subject, message string) error {
+	f.subject, f.text = subject, message
+	return nil
+}
+
+func (f *fakeHTMLEmailProvider) SendHTMLEmail(to, subject, text, html string) error {
+	f.subject, f.text, f.html = subject, text, html
+	return nil
+}
+
+func TestNotifier_SendEmailUsesHTMLWhenSupported(t *testing.T) {
+	email := &fakeHTMLEmailProvider{}
+	n := NewNotifier(nil).WithEmailProvider(email)
+	_, err := n.SendEmail(WithLocale(context.Background(), "en"), "reader@example.com", TemplateMagicLink,
+		MagicLinkData{Link: "https://example.com/?token=a&b", Brand: "FaithLabs", ExpiresInMinutes: 15})
+	if err != nil {
+		t.Fatalf("send failed: %v", err)
+	}
+	if email.html == "" || email.text == "" {
+		t.Fatalf("expected both text and html parts: %+v", email)
+	}
+	if want := `<a href="https://example.com/?token=a&amp;b">`; !strings.Contains(email.html, want) {
+		t.Fatalf("expected escaped link %q in html: %s", want, email.html)
+	}
+}
+
+func TestNotifier_ProviderNotConfigured(t *testing.T) {
+	_, err := NewNotifier(nil).SendSMS(context.Background(), "+12345678901", TemplateOTP, OTPData{})
+	if !errors.Is(err, ErrProviderNotConfigured) {
+		t.Fatalf("expected ErrProviderNotConfigured, got %v", err)
+	}
+}
+
+type fakeTrackedSMSProvider struct{ text string }
+
+func (f *fakeTrackedSMSProvider) SendSMS(to, message string) error {
+	return errors.New("untracked send")
+}
+
+func (f *fakeTrackedSMSProvider) SendTrackedSMS(ctx context.Context, to, message string) (string, error) {
+	f.text = message
+	return "SM123", nil
+}
+
+func TestNotifier_SendSMSReturnsMessageID(t *testing.T) {
+	sms := &fakeTrackedSMSProvider{}
+	id, err := NewNotifier(nil).WithSMSProvider(sms).SendSMS(context.Background(), "+12345678901", TemplateOTP,
+		OTPData{Code: "123456", ExpiresInMinutes: 10})
+	if err != nil || id != "SM123" || !strings.Contains(sms.text, "123456") {
+		t.Fatalf("expected tracked send with message id, got id=%q err=%v text=%q", id, err, sms.text)
+	}
+}
+
+func TestNotifier_SendSMSRejectsEmptyText(t *testing.T) {
+	r := NewRenderer("en", fstest.MapFS{"en/welcome.html.tmpl": {Data: []byte("<p>Welcome</p>")}})
+	sms := &fakeTrackedSMSProvider{}
+	_, err := NewNotifier(r).WithSMSProvider(sms).SendSMS(context.Background(), "+12345678901", "welcome", nil)
+	if !errors.Is(err, ErrEmptyText) || sms.text != "" {
+		t.Fatalf("expected ErrEmptyText without sending, got %v", err)
+	}
+}